@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+)
+
+// Custom action types served in addition to the Flight SQL ones
+const (
+	ActionListActiveStatements = "ListActiveStatements"
+	ActionExportDDL            = "ExportDDL"
+	ActionCheckpoint           = "Checkpoint"
+	ActionMaintain             = "Maintain"
+	ActionRefreshMetadata      = "RefreshMetadata"
+	ActionReloadConfig         = "ReloadConfig"
+	ActionGetTableRowCounts    = "GetTableRowCounts"
+	ActionExportParquet        = "ExportParquet"
+	ActionKillStatement        = "KillStatement"
+	ActionGetCurrentNamespace  = "GetCurrentNamespace"
+	ActionDescribePreparedPlan = "DescribePreparedPlan"
+	ActionEstimateQuery        = "EstimateQuery"
+	ActionPoolStats            = "PoolStats"
+	ActionPreload              = "Preload"
+	ActionSetBackendOption     = "SetBackendOption"
+	ActionValidateQuery        = "ValidateQuery"
+)
+
+// customAction describes a DoAction handler that is not part of Flight SQL
+type customAction struct {
+	description string
+	// admin actions require the caller to pass requireAdmin
+	admin   bool
+	handler func(s *DummyFlightSQLServer, ctx context.Context, body []byte) ([][]byte, error)
+}
+
+var customActions = map[string]customAction{
+	ActionListActiveStatements: {
+		description: "List live statement handles with their age and query (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).listActiveStatements,
+	},
+	ActionExportDDL: {
+		description: "Export CREATE TABLE statements for every table in the backend",
+		handler:     (*DummyFlightSQLServer).exportDDL,
+	},
+	ActionCheckpoint: {
+		description: "Flush the backend write-ahead log to the database file (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).checkpoint,
+	},
+	ActionMaintain: {
+		description: "Run a backend maintenance operation named in the body: vacuum or analyze (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).maintain,
+	},
+	ActionRefreshMetadata: {
+		description: "Drop cached query results so the next queries see current data",
+		handler:     (*DummyFlightSQLServer).refreshMetadata,
+	},
+	ActionReloadConfig: {
+		description: "Re-read the configuration file and apply the log level, admin tokens and rate limit (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).reloadConfig,
+	},
+	ActionGetTableRowCounts: {
+		description: "Return the row count of every table, or of the table named in the body (requires TableRowCounts)",
+		handler:     (*DummyFlightSQLServer).tableRowCounts,
+	},
+	ActionExportParquet: {
+		description: "Write the results of a query to Parquet files below the export directory and return their URIs (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).exportParquet,
+	},
+	ActionKillStatement: {
+		description: "Stop the streams reading the statement handle in the body and drop the handle (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).killStatement,
+	},
+	ActionGetCurrentNamespace: {
+		description: "Return the catalog and schema unqualified names resolve against for the session",
+		handler:     (*DummyFlightSQLServer).currentNamespace,
+	},
+	ActionDescribePreparedPlan: {
+		description: "Return the backend's query plan for the prepared statement handle in the body",
+		handler:     (*DummyFlightSQLServer).describePreparedPlan,
+	},
+	ActionEstimateQuery: {
+		description: "Return the backend's row and cost estimate for the query in the body without running it",
+		handler:     (*DummyFlightSQLServer).estimateQuery,
+	},
+	ActionPoolStats: {
+		description: "Return the total, idle and in-use connections of the pool, its waits and average acquire latency (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).poolStats,
+	},
+	ActionPreload: {
+		description: "Run the query in the body and keep its result for fetching with the returned token (admin only)",
+		admin:       true,
+		handler:     (*DummyFlightSQLServer).preload,
+	},
+	ActionSetBackendOption: {
+		description: `Apply a backend setting from AllowedBackendOptions, given as {"key": ..., "value": ...}, to the session's connections`,
+		handler:     (*DummyFlightSQLServer).setBackendOption,
+	},
+	ActionValidateQuery: {
+		description: "Check that the query in the body prepares without running it and return its result schema or the backend's error",
+		handler:     (*DummyFlightSQLServer).validateQuery,
+	},
+}
+
+// flightService wraps the Flight SQL routing so that the server can answer
+// custom actions and table flights, which flightsql.NewFlightServer rejects
+type flightService struct {
+	flight.FlightServer
+	srv *DummyFlightSQLServer
+}
+
+// newFlightService returns the Flight service to register for s
+func newFlightService(s *DummyFlightSQLServer) flight.FlightServer {
+	return &flightService{FlightServer: flightsql.NewFlightServerWithAllocator(s, s.Alloc), srv: s}
+}
+
+func (f *flightService) DoAction(action *flight.Action, stream flight.FlightService_DoActionServer) error {
+	custom, ok := customActions[action.Type]
+	if !ok {
+		return f.FlightServer.DoAction(action, stream)
+	}
+
+	ctx := stream.Context()
+	if custom.admin {
+		if err := f.srv.requireAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
+	results, err := custom.handler(f.srv, ctx, action.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, body := range results {
+		if err := stream.Send(&flight.Result{Body: body}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *flightService) ListActions(in *flight.Empty, stream flight.FlightService_ListActionsServer) error {
+	if err := f.FlightServer.ListActions(in, stream); err != nil {
+		return err
+	}
+
+	types := make([]string, 0, len(customActions))
+	for actionType := range customActions {
+		types = append(types, actionType)
+	}
+	sort.Strings(types)
+
+	for _, actionType := range types {
+		if err := stream.Send(&flight.ActionType{
+			Type:        actionType,
+			Description: customActions[actionType].description,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeStatementsSchema is the result schema of ListActiveStatements
+var activeStatementsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "kind", Type: arrow.BinaryTypes.String},
+	{Name: "handle", Type: arrow.BinaryTypes.String},
+	{Name: "query", Type: arrow.BinaryTypes.String},
+	{Name: "created_at", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+	{Name: "age_ms", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "owner", Type: arrow.BinaryTypes.String, Nullable: true},
+}, nil)
+
+// listActiveStatements returns the live statement handles as a single Arrow
+// IPC stream. Entries are of kind "statement", "prepared" or "transaction";
+// transactions have no query but name the client that began them.
+func (s *DummyFlightSQLServer) listActiveStatements(ctx context.Context, body []byte) ([][]byte, error) {
+	bldr := array.NewRecordBuilder(s.Alloc, activeStatementsSchema)
+	defer bldr.Release()
+
+	now := time.Now()
+	add := func(kind, handle, query string, created time.Time, owner string) {
+		bldr.Field(0).(*array.StringBuilder).Append(kind)
+		bldr.Field(1).(*array.StringBuilder).Append(handle)
+		bldr.Field(2).(*array.StringBuilder).Append(query)
+		bldr.Field(3).(*array.TimestampBuilder).Append(arrow.Timestamp(created.UnixMilli()))
+		bldr.Field(4).(*array.Int64Builder).Append(now.Sub(created).Milliseconds())
+		if owner == "" {
+			bldr.Field(5).AppendNull()
+		} else {
+			bldr.Field(5).(*array.StringBuilder).Append(owner)
+		}
+	}
+	for _, stmt := range s.activeStatements() {
+		add("statement", stmt.handle, stmt.query, stmt.created, "")
+	}
+	for _, prepared := range s.activePrepared() {
+		add("prepared", prepared.handle, prepared.query, prepared.created, "")
+	}
+	for _, txn := range s.activeTransactions() {
+		add("transaction", txn.id, "", txn.created, txn.owner)
+	}
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
+
+// serializeRecord encodes a record batch as an Arrow IPC stream
+func serializeRecord(rec arrow.RecordBatch) ([]byte, error) {
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(rec.Schema()))
+	if err := w.Write(rec); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
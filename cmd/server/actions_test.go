@@ -0,0 +1,947 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// openFlightClient connects a plain Flight client to the given address
+func openFlightClient(t *testing.T, addr string) flight.Client {
+	client, err := flight.NewClientWithMiddleware(addr, nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create flight client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// doAction runs an action and returns the bodies of all results
+func doAction(ctx context.Context, client flight.Client, actionType string, body []byte) ([][]byte, error) {
+	stream, err := client.DoAction(ctx, &flight.Action{Type: actionType, Body: body})
+	if err != nil {
+		return nil, err
+	}
+
+	var results [][]byte
+	for {
+		result, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return results, nil
+			}
+			return results, err
+		}
+		results = append(results, result.Body)
+	}
+}
+
+// decodeActionResult reads the record batches of an Arrow IPC action result
+func decodeActionResult(t *testing.T, body []byte) []arrow.RecordBatch {
+	reader, err := ipc.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to decode action result: %v", err)
+	}
+	defer reader.Release()
+
+	var records []arrow.RecordBatch
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		t.Cleanup(rec.Release)
+		records = append(records, rec)
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatalf("Failed to read action result: %v", err)
+	}
+	return records
+}
+
+func TestListActiveStatements(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.AdminToken = "secret"
+
+			setupTestData(t, server)
+
+			ctx := context.Background()
+
+			// Open a couple of statement handles
+			queries := []string{
+				"SELECT id FROM test_table",
+				"SELECT name FROM test_table WHERE id = 2",
+			}
+			for _, query := range queries {
+				desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+				if _, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc); err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+			}
+
+			// And an open transaction
+			txnID, err := server.BeginTransaction(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTransaction failed for %s: %v", driver.name, err)
+			}
+
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			// Unprivileged clients are rejected
+			_, err = doAction(ctx, client, ActionListActiveStatements, nil)
+			if status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("Expected PermissionDenied without admin token for %s, got %v", driver.name, err)
+			}
+
+			adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+			results, err := doAction(adminCtx, client, ActionListActiveStatements, nil)
+			if err != nil {
+				t.Fatalf("ListActiveStatements failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result for %s, got %d", driver.name, len(results))
+			}
+
+			var listed, transactions []string
+			for _, rec := range decodeActionResult(t, results[0]) {
+				kindCol := rec.Column(0).(*array.String)
+				handleCol := rec.Column(1).(*array.String)
+				queryCol := rec.Column(2).(*array.String)
+				ageCol := rec.Column(4).(*array.Int64)
+				for i := 0; i < int(rec.NumRows()); i++ {
+					if ageCol.Value(i) < 0 {
+						t.Errorf("Expected non-negative age for %s, got %d", driver.name, ageCol.Value(i))
+					}
+					switch kindCol.Value(i) {
+					case "statement":
+						listed = append(listed, queryCol.Value(i))
+					case "transaction":
+						transactions = append(transactions, handleCol.Value(i))
+					default:
+						t.Errorf("Unexpected kind '%s' for %s", kindCol.Value(i), driver.name)
+					}
+				}
+			}
+
+			if len(transactions) != 1 || transactions[0] != string(txnID) {
+				t.Errorf("Expected transaction %q for %s, got %v", txnID, driver.name, transactions)
+			}
+
+			if len(listed) != len(queries) {
+				t.Fatalf("Expected %d statements for %s, got %v", len(queries), driver.name, listed)
+			}
+			for i, query := range queries {
+				if listed[i] != query {
+					t.Errorf("Expected statement %d to be %q for %s, got %q", i, query, driver.name, listed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExportDDL(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			execTestSQL(t, server, `CREATE TABLE other_table (code TEXT NOT NULL, amount REAL)`)
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			results, err := doAction(ctx, client, ActionExportDDL, nil)
+			if err != nil {
+				t.Fatalf("ExportDDL failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result for %s, got %d", driver.name, len(results))
+			}
+
+			ddl := make(map[string]string)
+			for _, rec := range decodeActionResult(t, results[0]) {
+				nameCol := rec.Column(0).(*array.String)
+				ddlCol := rec.Column(1).(*array.String)
+				for i := 0; i < int(rec.NumRows()); i++ {
+					ddl[nameCol.Value(i)] = ddlCol.Value(i)
+				}
+			}
+
+			for _, table := range []string{"test_table", "other_table"} {
+				statement, ok := ddl[table]
+				if !ok {
+					t.Errorf("Expected DDL for %s in %s, got %v", table, driver.name, ddl)
+					continue
+				}
+				if !strings.Contains(strings.ToUpper(statement), "CREATE TABLE") || !strings.Contains(statement, table) {
+					t.Errorf("Expected a CREATE TABLE statement for %s in %s, got %q", table, driver.name, statement)
+				}
+				t.Logf("DDL for %s in %s:\n%s", table, driver.name, statement)
+			}
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.AdminToken = "secret"
+
+			setupTestData(t, server)
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			results, err := doAction(ctx, client, ActionCheckpoint, nil)
+			if err != nil {
+				t.Fatalf("Checkpoint failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result for %s, got %d", driver.name, len(results))
+			}
+
+			server.cfg.ReadOnly = true
+			_, err = doAction(ctx, client, ActionCheckpoint, nil)
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition in read-only mode for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestMaintain(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.AdminToken = "secret"
+
+			setupTestData(t, server)
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			results, err := doAction(ctx, client, ActionMaintain, []byte("analyze"))
+			if err != nil {
+				t.Fatalf("Maintain analyze failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 || string(results[0]) != "analyze completed" {
+				t.Errorf("Unexpected results for %s: %q", driver.name, results)
+			}
+
+			_, err = doAction(ctx, client, ActionMaintain, []byte("defragment"))
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for an unknown operation for %s, got %v", driver.name, err)
+			}
+
+			server.cfg.ReadOnly = true
+			_, err = doAction(ctx, client, ActionMaintain, []byte("analyze"))
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition in read-only mode for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+	setupTestData(t, server)
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	var logs lockedBuffer
+	server.cfg.AdminToken = "secret"
+	server.cfg.ConfigFile = configFile
+	server.logger = newLogger(&logs, &server.logLevel)
+
+	writeConfig(`{"log_level": "info"}`)
+	if err := server.loadConfig(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	query := func() {
+		for chunk := range runStatement(t, server, "SELECT id FROM test_table") {
+			if chunk.Err != nil {
+				t.Fatalf("Stream error: %v", chunk.Err)
+			}
+			chunk.Data.Release()
+		}
+	}
+
+	query()
+	if strings.Contains(logs.String(), "executing statement") {
+		t.Fatalf("Expected no debug logs at info level, got:\n%s", logs.String())
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	client := openFlightClient(t, startTestFlightServer(t, server))
+
+	writeConfig(`{"log_level": "debug", "admin_tokens": ["rotated"]}`)
+	if _, err := doAction(ctx, client, ActionReloadConfig, nil); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	query()
+	if !strings.Contains(logs.String(), "executing statement") {
+		t.Errorf("Expected debug logs after reloading with log_level debug, got:\n%s", logs.String())
+	}
+
+	// The token list was replaced, so the old token no longer works
+	_, err := doAction(ctx, client, ActionReloadConfig, nil)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied with the old admin token, got %v", err)
+	}
+
+	// An invalid file leaves the current settings in place
+	writeConfig(`{"log_level": "loud"}`)
+	rotated := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer rotated")
+	_, err = doAction(rotated, client, ActionReloadConfig, nil)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid log level, got %v", err)
+	}
+	if server.logLevel.Level() != slog.LevelDebug {
+		t.Errorf("Expected the log level to stay at debug, got %s", server.logLevel.Level())
+	}
+}
+
+func TestGetTableRowCounts(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			_, err := doAction(ctx, client, ActionGetTableRowCounts, nil)
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition while row counts are disabled for %s, got %v", driver.name, err)
+			}
+
+			server.cfg.TableRowCounts = true
+			results, err := doAction(ctx, client, ActionGetTableRowCounts, []byte("test_table"))
+			if err != nil {
+				t.Fatalf("GetTableRowCounts failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result for %s, got %d", driver.name, len(results))
+			}
+
+			var found bool
+			for _, rec := range decodeActionResult(t, results[0]) {
+				tableCol := rec.Column(2).(*array.String)
+				countCol := rec.Column(3).(*array.Int64)
+				approximateCol := rec.Column(4).(*array.Boolean)
+				for i := 0; i < int(rec.NumRows()); i++ {
+					if tableCol.Value(i) != "test_table" {
+						t.Errorf("Expected only test_table for %s, got %s", driver.name, tableCol.Value(i))
+						continue
+					}
+					found = true
+
+					// Statistics may lag behind, exact counts may not
+					count := countCol.Value(i)
+					if approximateCol.Value(i) {
+						if count < 2 || count > 4 {
+							t.Errorf("Expected an approximate count near 3 for %s, got %d", driver.name, count)
+						}
+					} else if count != 3 {
+						t.Errorf("Expected 3 rows for %s, got %d", driver.name, count)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("Expected a row count for test_table in %s", driver.name)
+			}
+		})
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			server.cfg.ExportDir = t.TempDir()
+			server.cfg.AdminToken = "secret"
+
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			// Unprivileged clients can't write files on the server
+			body := []byte(`{"query": "SELECT id, name FROM test_table ORDER BY id", "path": "export", "rows_per_file": 2}`)
+			_, err := doAction(context.Background(), client, ActionExportParquet, body)
+			if status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("Expected PermissionDenied without admin token for %s, got %v", driver.name, err)
+			}
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+			results, err := doAction(ctx, client, ActionExportParquet, body)
+			if err != nil {
+				t.Fatalf("ExportParquet failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("Expected 2 files of at most 2 rows for %s, got %d", driver.name, len(results))
+			}
+
+			var names []string
+			for _, result := range results {
+				uri, err := url.Parse(string(result))
+				if err != nil || uri.Scheme != "file" {
+					t.Fatalf("Expected a file URI for %s, got %q", driver.name, result)
+				}
+				if !strings.HasPrefix(uri.Path, filepath.ToSlash(filepath.Join(server.cfg.ExportDir, "export"))) {
+					t.Errorf("Expected the file below the export directory for %s, got %s", driver.name, uri.Path)
+				}
+
+				f, err := os.Open(filepath.FromSlash(uri.Path))
+				if err != nil {
+					t.Fatalf("Failed to open exported file for %s: %v", driver.name, err)
+				}
+				table, err := pqarrow.ReadTable(ctx, f, nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+				f.Close()
+				if err != nil {
+					t.Fatalf("Failed to read exported file for %s: %v", driver.name, err)
+				}
+
+				reader := array.NewTableReader(table, -1)
+				for reader.Next() {
+					rec := reader.RecordBatch()
+					for i := 0; i < int(rec.NumRows()); i++ {
+						names = append(names, rec.Column(1).ValueStr(i))
+					}
+				}
+				reader.Release()
+				table.Release()
+			}
+
+			if strings.Join(names, ",") != "test1,test2,test3" {
+				t.Errorf("Expected the exported rows in order for %s, got %v", driver.name, names)
+			}
+
+			escape := []byte(`{"query": "SELECT 1", "path": "../outside"}`)
+			if _, err := doAction(ctx, client, ActionExportParquet, escape); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for a path outside the export directory for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestKillStatement(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.AdminToken = "secret"
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: multiBatchQuery}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+			handle := ticket.GetStatementHandle()
+
+			_, streamCh, err := server.DoGetStatement(ctx, ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			// Take one batch, the stream then waits for the slow reader
+			first := <-streamCh
+			if first.Err != nil {
+				t.Fatalf("Stream error for %s: %v", driver.name, first.Err)
+			}
+			first.Data.Release()
+
+			if _, err := doAction(ctx, client, ActionKillStatement, handle); status.Code(err) != codes.PermissionDenied {
+				t.Errorf("Expected PermissionDenied without the admin token for %s, got %v", driver.name, err)
+			}
+
+			adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+			if _, err := doAction(adminCtx, client, ActionKillStatement, handle); err != nil {
+				t.Fatalf("KillStatement failed for %s: %v", driver.name, err)
+			}
+
+			var streamErr error
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				chunk.Data.Release()
+			}
+			if status.Code(streamErr) != codes.Canceled {
+				t.Errorf("Expected the stream to end with Canceled for %s, got %v", driver.name, streamErr)
+			}
+
+			if _, _, err := server.DoGetStatement(ctx, ticket); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected the killed handle to be gone for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestGetCurrentNamespace(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.EnableSessions = true
+
+			// SQLite has no schemas, only the attached database to switch to
+			option, want := sessionOptionSchema, "analytics"
+			if driver.name == "SQLite" {
+				option, want = sessionOptionCatalog, "main"
+			} else {
+				execTestSQL(t, server, "CREATE SCHEMA analytics")
+			}
+
+			ctx := context.Background()
+			addr := startTestFlightServer(t, server)
+			client, err := flight.NewClientWithMiddleware(addr, nil,
+				[]flight.ClientMiddleware{flight.NewClientCookieMiddleware()},
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("Failed to create flight client: %v", err)
+			}
+			defer client.Close()
+
+			options, err := flight.NewSessionOptionValues(map[string]any{option: want})
+			if err != nil {
+				t.Fatalf("Failed to build session options: %v", err)
+			}
+			result, err := client.SetSessionOptions(ctx, &flight.SetSessionOptionsRequest{SessionOptions: options})
+			if err != nil {
+				t.Fatalf("SetSessionOptions failed for %s: %v", driver.name, err)
+			}
+			if len(result.GetErrors()) > 0 {
+				t.Fatalf("Expected %s to be accepted for %s, got %v", option, driver.name, result.GetErrors())
+			}
+
+			results, err := doAction(ctx, client, ActionGetCurrentNamespace, nil)
+			if err != nil {
+				t.Fatalf("GetCurrentNamespace failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected one result for %s, got %d", driver.name, len(results))
+			}
+			records := decodeActionResult(t, results[0])
+			if len(records) != 1 || records[0].NumRows() != 1 {
+				t.Fatalf("Expected a single namespace row for %s", driver.name)
+			}
+
+			column := 1
+			if option == sessionOptionCatalog {
+				column = 0
+			}
+			if got := records[0].Column(column).ValueStr(0); got != want {
+				t.Errorf("Expected the session's %s %q for %s, got %q", option, want, driver.name, got)
+			}
+		})
+	}
+}
+
+func TestDescribePreparedPlan(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			execTestSQL(t, server, "CREATE TABLE orders (id INTEGER, test_id INTEGER)")
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			query := "SELECT test_table.name, orders.id FROM test_table JOIN orders ON orders.test_id = test_table.id"
+			prepared, err := server.CreatePreparedStatement(ctx, &pb.ActionCreatePreparedStatementRequest{Query: query})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+
+			results, err := doAction(ctx, client, ActionDescribePreparedPlan, prepared.Handle)
+			if err != nil {
+				t.Fatalf("DescribePreparedPlan failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 || len(results[0]) == 0 {
+				t.Fatalf("Expected a non-empty plan for %s, got %q", driver.name, results)
+			}
+			plan := strings.ToLower(string(results[0]))
+			for _, table := range []string{"test_table", "orders"} {
+				if !strings.Contains(plan, table) {
+					t.Errorf("Expected the plan to reference %s for %s, got:\n%s", table, driver.name, results[0])
+				}
+			}
+
+			if _, err := doAction(ctx, client, ActionDescribePreparedPlan, []byte("unknown")); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound for an unknown handle for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestEstimateQuery(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			values := make([]string, 0, 97)
+			for i := 4; i <= 100; i++ {
+				values = append(values, fmt.Sprintf("(%d, 'test%d', %d.5)", i, i, i))
+			}
+			execTestSQL(t, server, "INSERT INTO test_table VALUES "+strings.Join(values, ", "))
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			results, err := doAction(ctx, client, ActionEstimateQuery, []byte("SELECT * FROM test_table"))
+			if err != nil {
+				t.Fatalf("EstimateQuery failed for %s: %v", driver.name, err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected one result for %s, got %d", driver.name, len(results))
+			}
+			records := decodeActionResult(t, results[0])
+			if len(records) != 1 || records[0].NumRows() != 1 {
+				t.Fatalf("Expected a single estimate row for %s, got %v", driver.name, records)
+			}
+			rows := records[0].Column(0).(*array.Int64).Value(0)
+			cost := records[0].Column(1).(*array.Float64).Value(0)
+
+			if driver.driverName == "duckdb" {
+				// The optimizer's estimate is not exact, but of the table's order
+				if rows < 10 || rows > 1000 {
+					t.Errorf("Expected an estimate of about 100 rows for %s, got %d", driver.name, rows)
+				}
+				if cost < float64(rows) {
+					t.Errorf("Expected the cost to cover the estimated rows for %s, got %v", driver.name, cost)
+				}
+			} else if rows != -1 || cost != -1 {
+				t.Errorf("Expected unknown estimates for %s, got %d rows and cost %v", driver.name, rows, cost)
+			}
+
+			if _, err := doAction(ctx, client, ActionEstimateQuery, []byte("SELECT * FROM missing_table")); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for an unknown table for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			validate := func(query string) arrow.RecordBatch {
+				t.Helper()
+				results, err := doAction(ctx, client, ActionValidateQuery, []byte(query))
+				if err != nil {
+					t.Fatalf("ValidateQuery failed for %s: %v", driver.name, err)
+				}
+				if len(results) != 1 {
+					t.Fatalf("Expected one result for %s, got %d", driver.name, len(results))
+				}
+				records := decodeActionResult(t, results[0])
+				if len(records) != 1 || records[0].NumRows() != 1 {
+					t.Fatalf("Expected a single validation row for %s, got %v", driver.name, records)
+				}
+				return records[0]
+			}
+
+			good := validate("SELECT id, name FROM test_table")
+			if !good.Column(0).(*array.Boolean).Value(0) {
+				t.Fatalf("Expected the query to be valid for %s, got error %q", driver.name, good.Column(2).ValueStr(0))
+			}
+			schema, err := flight.DeserializeSchema(good.Column(1).(*array.Binary).Value(0), memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to decode the result schema for %s: %v", driver.name, err)
+			}
+			if schema.NumFields() != 2 || schema.Field(0).Name != "id" || schema.Field(1).Name != "name" {
+				t.Errorf("Expected the id and name columns for %s, got %v", driver.name, schema)
+			}
+
+			bad := validate("SELECT missing_column FROM test_table")
+			if bad.Column(0).(*array.Boolean).Value(0) {
+				t.Fatalf("Expected an unknown column to be invalid for %s", driver.name)
+			}
+			if !bad.Column(1).IsNull(0) {
+				t.Errorf("Expected no schema for an invalid query for %s", driver.name)
+			}
+			if message := bad.Column(2).ValueStr(0); !strings.Contains(message, "missing_column") {
+				t.Errorf("Expected the backend diagnostic to name the column for %s, got %q", driver.name, message)
+			}
+
+			// Validating a write doesn't run it
+			if deleted := validate("DELETE FROM test_table"); !deleted.Column(0).(*array.Boolean).Value(0) {
+				t.Errorf("Expected the delete to be valid for %s, got error %q", driver.name, deleted.Column(2).ValueStr(0))
+			}
+			for chunk := range runStatement(t, server, "SELECT COUNT(*) FROM test_table") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if count := chunk.Data.Column(0).ValueStr(0); count != "3" {
+					t.Errorf("Expected the rows to be untouched for %s, got %s", driver.name, count)
+				}
+				chunk.Data.Release()
+			}
+		})
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.AdminToken = "secret"
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			if _, err := doAction(ctx, client, ActionPoolStats, nil); status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition without a pool for %s, got %v", driver.name, err)
+			}
+
+			server.pool = newConnPool(server.newConnection, 4, 0, 0)
+			defer server.pool.close()
+
+			// stats returns total, idle, in_use and waits from the action
+			stats := func() (total, idle, inUse, waits int64) {
+				results, err := doAction(ctx, client, ActionPoolStats, nil)
+				if err != nil {
+					t.Fatalf("PoolStats failed for %s: %v", driver.name, err)
+				}
+				records := decodeActionResult(t, results[0])
+				if len(records) != 1 || records[0].NumRows() != 1 {
+					t.Fatalf("Expected a single stats row for %s, got %v", driver.name, records)
+				}
+				col := func(i int) int64 { return records[0].Column(i).(*array.Int64).Value(0) }
+				return col(0), col(1), col(2), col(4)
+			}
+
+			var conns []adbc.Connection
+			for range 2 {
+				conn, err := server.openConnection(context.Background())
+				if err != nil {
+					t.Fatalf("Failed to acquire a connection for %s: %v", driver.name, err)
+				}
+				conns = append(conns, conn)
+			}
+
+			if total, idle, inUse, waits := stats(); total != 2 || idle != 0 || inUse != 2 || waits != 2 {
+				t.Errorf("Expected 2 connections in use for %s, got total %d, idle %d, in use %d, waits %d",
+					driver.name, total, idle, inUse, waits)
+			}
+
+			for _, conn := range conns {
+				conn.Close()
+			}
+			if total, idle, inUse, _ := stats(); total != 2 || idle != 2 || inUse != 0 {
+				t.Errorf("Expected 2 idle connections for %s, got total %d, idle %d, in use %d", driver.name, total, idle, inUse)
+			}
+		})
+	}
+}
+
+func TestPreload(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+			server.cfg.AdminToken = "secret"
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			body := []byte(`{"query": "SELECT id, name FROM test_table ORDER BY id"}`)
+			if _, err := doAction(ctx, client, ActionPreload, body); status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition without PreloadTTL for %s, got %v", driver.name, err)
+			}
+
+			server.cfg.PreloadTTL = time.Minute
+			server.preloads = newResultCache(server.cfg.PreloadTTL, 0)
+
+			results, err := doAction(ctx, client, ActionPreload, body)
+			if err != nil {
+				t.Fatalf("Preload failed for %s: %v", driver.name, err)
+			}
+			records := decodeActionResult(t, results[0])
+			if len(records) != 1 || records[0].NumRows() != 1 {
+				t.Fatalf("Expected a single preload row for %s, got %v", driver.name, records)
+			}
+			token := records[0].Column(0).(*array.String).Value(0)
+			if rows := records[0].Column(2).(*array.Int64).Value(0); rows != 3 {
+				t.Errorf("Expected 3 preloaded rows for %s, got %d", driver.name, rows)
+			}
+
+			// A re-executed query would see the new row
+			execTestSQL(t, server, "INSERT INTO test_table (id, name, value) VALUES (4, 'late', 4.0)")
+
+			// fetch streams the result for token, twice to show it is kept
+			for range 2 {
+				ticket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: mustStatementTicket(t, token)})
+				if err != nil {
+					t.Fatalf("Failed to parse ticket for %s: %v", driver.name, err)
+				}
+				_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+				}
+				var rows int64
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					rows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				}
+				if rows != 3 {
+					t.Errorf("Expected the 3 preloaded rows for %s, got %d", driver.name, rows)
+				}
+			}
+
+			ticket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: mustStatementTicket(t, preloadTokenPrefix+"unknown")})
+			if err != nil {
+				t.Fatalf("Failed to parse ticket for %s: %v", driver.name, err)
+			}
+			if _, _, err := server.DoGetStatement(context.Background(), ticket); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound for an unknown token for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+// mustStatementTicket returns a statement query ticket for handle
+func mustStatementTicket(t *testing.T, handle string) []byte {
+	ticket, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+	if err != nil {
+		t.Fatalf("Failed to create ticket: %v", err)
+	}
+	return ticket
+}
+
+func TestSetBackendOption(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.EnableSessions = true
+
+			allowed, denied := "threads", "memory_limit"
+			if driver.name == "SQLite" {
+				allowed, denied = "cache_size", "journal_mode"
+			}
+			server.cfg.AllowedBackendOptions = []string{allowed}
+
+			ctx := context.Background()
+			addr := startTestFlightServer(t, server)
+			client, err := flight.NewClientWithMiddleware(addr, nil,
+				[]flight.ClientMiddleware{flight.NewClientCookieMiddleware()},
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("Failed to create flight client: %v", err)
+			}
+			defer client.Close()
+
+			body := []byte(fmt.Sprintf(`{"key": %q, "value": "2"}`, allowed))
+			if _, err := doAction(ctx, client, ActionSetBackendOption, body); err != nil {
+				t.Fatalf("SetBackendOption %s failed for %s: %v", allowed, driver.name, err)
+			}
+			options, err := client.GetSessionOptions(ctx, &flight.GetSessionOptionsRequest{})
+			if err != nil {
+				t.Fatalf("GetSessionOptions failed for %s: %v", driver.name, err)
+			}
+			if got := options.GetSessionOptions()[sessionBackendOptionPrefix+allowed].GetStringValue(); got != "2" {
+				t.Errorf("Expected the session to hold %s = 2 for %s, got %q", allowed, driver.name, got)
+			}
+
+			body = []byte(fmt.Sprintf(`{"key": %q, "value": "1"}`, denied))
+			if _, err := doAction(ctx, client, ActionSetBackendOption, body); status.Code(err) != codes.PermissionDenied {
+				t.Errorf("Expected PermissionDenied for %s on %s, got %v", denied, driver.name, err)
+			}
+		})
+	}
+}
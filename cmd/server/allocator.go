@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/arrow/memory/mallocator"
+)
+
+// Allocator kinds accepted by ServerConfig.Allocator
+const (
+	AllocatorDefault = "default"
+	AllocatorGo      = "go"
+	AllocatorMalloc  = "malloc"
+)
+
+// newAllocator returns the Arrow allocator for the given kind. The malloc
+// allocator keeps buffers off the Go heap, which avoids GC pressure when
+// streaming large results.
+func newAllocator(kind string) (memory.Allocator, error) {
+	switch kind {
+	case "", AllocatorDefault:
+		return memory.DefaultAllocator, nil
+	case AllocatorGo:
+		return memory.NewGoAllocator(), nil
+	case AllocatorMalloc:
+		return mallocator.NewMallocator(), nil
+	default:
+		return nil, fmt.Errorf("unknown allocator %q, supported values are: %s, %s, %s", kind, AllocatorDefault, AllocatorGo, AllocatorMalloc)
+	}
+}
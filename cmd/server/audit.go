@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+)
+
+// AuditRecord describes one executed query
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Client is the client certificate identity, or the peer address
+	// without mutual TLS
+	Client string `json:"client"`
+	Method string `json:"method"`
+	// Query is the query text, or its SHA-256 with AuditHashQueries
+	Query    string        `json:"query"`
+	Duration time.Duration `json:"duration_ns"`
+	// Rows is the number of rows returned or changed, -1 when unknown
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives a record of every query the server executes,
+// successful or not
+type AuditSink interface {
+	Audit(record AuditRecord) error
+}
+
+// fileAuditSink appends records to a file as JSON lines
+type fileAuditSink struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// newFileAuditSink opens path for appending, creating it when missing
+func newFileAuditSink(path string) (*fileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuditSink{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (a *fileAuditSink) Audit(record AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enc.Encode(record)
+}
+
+func (a *fileAuditSink) Close() error {
+	return a.f.Close()
+}
+
+// audit sends a record for query to the audit sink, if one is configured,
+// and reports it to the metrics. Sink failures are logged, they don't fail
+// the query.
+func (s *DummyFlightSQLServer) audit(ctx context.Context, method, query string, start time.Time, rows int64, err error) {
+	s.recordQuery(method, start, err)
+
+	sink := s.cfg.AuditSink
+	if sink == nil {
+		return
+	}
+
+	record := AuditRecord{
+		Time:     start,
+		Client:   callerIdentity(ctx),
+		Method:   method,
+		Query:    query,
+		Duration: time.Since(start),
+		Rows:     rows,
+	}
+	if s.cfg.AuditHashQueries {
+		sum := sha256.Sum256([]byte(query))
+		record.Query = hex.EncodeToString(sum[:])
+	}
+	if err != nil {
+		record.Error = err.Error()
+		record.Rows = unknownRowCount
+	}
+
+	if err := sink.Audit(record); err != nil {
+		s.logFor(ctx).Error("failed to write audit record", "method", method, "error", err)
+	}
+}
+
+// auditStream audits a query whose results are streamed on ch. Failures to
+// start are audited right away, otherwise the returned stream relays ch and
+// audits the outcome once it ends.
+func (s *DummyFlightSQLServer) auditStream(ctx context.Context, method, query string, start time.Time, ch <-chan flight.StreamChunk, err error) <-chan flight.StreamChunk {
+	if s.cfg.AuditSink == nil && s.cfg.Metrics == nil {
+		return ch
+	}
+	if err != nil {
+		s.audit(ctx, method, query, start, 0, err)
+		return ch
+	}
+
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+
+		var (
+			rows      int64
+			streamErr error
+		)
+		for chunk := range ch {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			} else if chunk.Data != nil {
+				rows += chunk.Data.NumRows()
+			}
+			out <- chunk
+		}
+		s.audit(ctx, method, query, start, rows, streamErr)
+	}()
+	return out
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requireAdmin fails unless the caller sent "authorization: Bearer <token>"
+// with one of the admin tokens. Admin-only features are disabled entirely
+// when no admin token is configured.
+func (s *DummyFlightSQLServer) requireAdmin(ctx context.Context) error {
+	tokens := s.runtimeSettings().adminTokens
+	if len(tokens) == 0 {
+		return status.Error(codes.PermissionDenied, "admin actions are disabled")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		for _, token := range tokens {
+			if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+token)) == 1 {
+				return nil
+			}
+		}
+	}
+
+	return status.Error(codes.PermissionDenied, "admin privileges required")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// Backend vendors with dialect specific handling
+const (
+	vendorDuckDB = "duckdb"
+	vendorSQLite = "sqlite"
+)
+
+// backendVendor returns the lower-cased vendor name reported by the driver
+func backendVendor(ctx context.Context, conn adbc.Connection) (string, error) {
+	reader, err := conn.GetInfo(ctx, []adbc.InfoCode{adbc.InfoVendorName})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		codes := rec.Column(0).(*array.Uint32)
+		values := rec.Column(1).(*array.DenseUnion)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			if adbc.InfoCode(codes.Value(i)) != adbc.InfoVendorName {
+				continue
+			}
+			if name, ok := values.Field(values.ChildID(i)).(*array.String); ok {
+				return strings.ToLower(name.Value(int(values.ValueOffset(i)))), nil
+			}
+		}
+	}
+
+	return "", reader.Err()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sessionBackendOptionPrefix prefixes the session options holding backend
+// settings made with SetBackendOption
+const sessionBackendOptionPrefix = "backend."
+
+// backendOptionName matches the setting names allowed in
+// AllowedBackendOptions, which end up unquoted in SET and PRAGMA statements
+var backendOptionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkBackendOptions validates AllowedBackendOptions
+func checkBackendOptions(names []string) error {
+	for _, name := range names {
+		if !backendOptionName.MatchString(name) {
+			return fmt.Errorf("invalid allowed backend option %q", name)
+		}
+	}
+	return nil
+}
+
+// setBackendOptionRequest is the JSON body of the SetBackendOption action
+type setBackendOptionRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// backendOptionStatement returns the statement applying a setting on the
+// vendor's backend. Numbers are passed as is, anything else as a string
+// literal.
+func backendOptionStatement(vendor, key, value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	if vendor == vendorSQLite {
+		return fmt.Sprintf("PRAGMA %s = %s", key, value)
+	}
+	return fmt.Sprintf("SET %s = %s", key, value)
+}
+
+// setBackendOption applies a backend setting from AllowedBackendOptions to
+// the connections of the caller's session. The setting is tried on a fresh
+// connection first, so invalid values are rejected right away.
+func (s *DummyFlightSQLServer) setBackendOption(ctx context.Context, body []byte) ([][]byte, error) {
+	sess, err := sessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var req setBackendOptionRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, `expected a JSON body with "key" and "value" fields`)
+	}
+	idx := slices.IndexFunc(s.cfg.AllowedBackendOptions, func(name string) bool { return strings.EqualFold(name, req.Key) })
+	if idx < 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "backend option %q is not allowed", req.Key)
+	}
+	key := strings.ToLower(s.cfg.AllowedBackendOptions[idx])
+
+	conn, err := s.newConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := applyBackendOption(ctx, conn, key, req.Value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to set backend option %s: %v", key, err)
+	}
+
+	sess.SetSessionOption(sessionBackendOptionPrefix+key, &pb.SessionOptionValue{
+		OptionValue: &pb.SessionOptionValue_StringValue{StringValue: req.Value},
+	})
+	return [][]byte{[]byte(fmt.Sprintf("backend option %s set to %s", key, req.Value))}, nil
+}
+
+// sessionBackendOptions returns the backend settings of the caller's
+// session by name
+func sessionBackendOptions(ctx context.Context) map[string]string {
+	sess, err := sessionFromContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var options map[string]string
+	for name, value := range sess.GetSessionOptions() {
+		if key, ok := strings.CutPrefix(name, sessionBackendOptionPrefix); ok {
+			if options == nil {
+				options = make(map[string]string)
+			}
+			options[key] = value.GetStringValue()
+		}
+	}
+	return options
+}
+
+// applySessionBackendOptions applies the backend settings of the caller's
+// session to a new connection
+func (s *DummyFlightSQLServer) applySessionBackendOptions(ctx context.Context, conn adbc.Connection) error {
+	options := sessionBackendOptions(ctx)
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := applyBackendOption(ctx, conn, key, options[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// applyBackendOption runs the statement setting key on conn
+func applyBackendOption(ctx context.Context, conn adbc.Connection, key, value string) error {
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(backendOptionStatement(vendor, key, value)); err != nil {
+		return err
+	}
+	// PRAGMA reports the new value as a result row, which is of no interest
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return err
+	}
+	reader.Release()
+	return nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchRowsHeader is the request header a client sets on GetFlightInfo or
+// DoGet to receive the results in batches of this many rows
+const batchRowsHeader = "x-batch-rows"
+
+// coalesceHeader is the request header a client sets to "true" on
+// GetFlightInfo or DoGet to receive the results as a single batch
+const coalesceHeader = "x-coalesce"
+
+// batching is how the batches of a result are reshaped before sending
+type batching struct {
+	rows     int64 // 0 keeps the backend's batch sizes
+	coalesce bool  // one batch for the whole result, overrides rows
+
+	// coalesceSet tells an explicit coalesce=false apart from no header
+	coalesceSet bool
+}
+
+// batchingFromContext returns the batching requested by the client's
+// headers
+func batchingFromContext(ctx context.Context) (batching, error) {
+	rows, err := positiveHeader(ctx, batchRowsHeader)
+	if err != nil {
+		return batching{}, err
+	}
+	coalesce, coalesceSet, err := boolHeader(ctx, coalesceHeader)
+	if err != nil {
+		return batching{}, err
+	}
+	return batching{rows: rows, coalesce: coalesce, coalesceSet: coalesceSet}, nil
+}
+
+// batchingFor returns the batching of a fetch: the DoGet headers win over
+// the ones sent with GetFlightInfo, which win over BatchRows
+func (s *DummyFlightSQLServer) batchingFor(ctx context.Context, ticketed batching) (batching, error) {
+	requested, err := batchingFromContext(ctx)
+	if err != nil {
+		return batching{}, err
+	}
+	if requested.rows == 0 {
+		requested.rows = cmp.Or(ticketed.rows, s.cfg.BatchRows)
+	}
+	if !requested.coalesceSet {
+		requested.coalesce = ticketed.coalesce
+	}
+	return requested, nil
+}
+
+// reshape applies b to the data batches of a stream
+func (s *DummyFlightSQLServer) reshape(ch <-chan flight.StreamChunk, b batching) <-chan flight.StreamChunk {
+	if b.coalesce {
+		return s.coalesce(ch, s.cfg.CoalesceMaxRows)
+	}
+	return s.rebatch(ch, b.rows)
+}
+
+// rebatch re-slices the data batches of a stream into batches of rows rows,
+// splitting larger batches and concatenating smaller ones. Empty batches and
+// errors pass through after the rows before them, and app metadata stays on
+// the batch that ends with the rows it was sent with.
+func (s *DummyFlightSQLServer) rebatch(in <-chan flight.StreamChunk, rows int64) <-chan flight.StreamChunk {
+	if rows <= 0 {
+		return in
+	}
+
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+		defer s.recoverStream(out)
+
+		var (
+			pending     []arrow.RecordBatch
+			pendingRows int64
+		)
+		discard := func() {
+			for _, rec := range pending {
+				rec.Release()
+			}
+			pending, pendingRows = nil, 0
+		}
+		defer discard()
+
+		flush := func(metadata []byte) error {
+			if len(pending) == 0 {
+				return nil
+			}
+			rec := pending[0]
+			if len(pending) > 1 {
+				var err error
+				if rec, err = concatBatches(s.Alloc, pending); err != nil {
+					return err
+				}
+				discard()
+			}
+			pending, pendingRows = nil, 0
+			out <- flight.StreamChunk{Data: rec, AppMetadata: metadata}
+			return nil
+		}
+
+		for chunk := range in {
+			if chunk.Err != nil {
+				if err := flush(nil); err != nil {
+					out <- flight.StreamChunk{Err: err}
+					return
+				}
+				out <- chunk
+				continue
+			}
+			if chunk.Data == nil || chunk.Data.NumRows() == 0 {
+				if err := flush(nil); err != nil {
+					out <- flight.StreamChunk{Err: err}
+					return
+				}
+				out <- chunk
+				continue
+			}
+
+			rec := chunk.Data
+			for offset := int64(0); offset < rec.NumRows(); {
+				n := min(rows-pendingRows, rec.NumRows()-offset)
+				pending = append(pending, rec.NewSlice(offset, offset+n))
+				pendingRows += n
+				offset += n
+				if pendingRows == rows {
+					var metadata []byte
+					if offset == rec.NumRows() {
+						metadata, chunk.AppMetadata = chunk.AppMetadata, nil
+					}
+					if err := flush(metadata); err != nil {
+						rec.Release()
+						out <- flight.StreamChunk{Err: err}
+						return
+					}
+				}
+			}
+			rec.Release()
+
+			// Metadata describes the rows sent so far, so it can't wait for
+			// the batch to fill up
+			if chunk.AppMetadata != nil {
+				if err := flush(chunk.AppMetadata); err != nil {
+					out <- flight.StreamChunk{Err: err}
+					return
+				}
+			}
+		}
+		if err := flush(nil); err != nil {
+			out <- flight.StreamChunk{Err: err}
+		}
+	}()
+	return out
+}
+
+// coalesce concatenates the data batches of a stream into one batch, which
+// is sent once the stream ends. A stream of more than maxRows rows fails
+// with ResourceExhausted, unless maxRows is 0. Empty batches seen before any
+// rows pass through right away, so heartbeats keep working, later ones
+// follow the coalesced batch. The app metadata of the last data batch is
+// kept, as progress reported with it covers all the rows before.
+func (s *DummyFlightSQLServer) coalesce(in <-chan flight.StreamChunk, maxRows int64) <-chan flight.StreamChunk {
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+		defer s.recoverStream(out)
+
+		var (
+			batches  []arrow.RecordBatch
+			trailing []flight.StreamChunk
+			metadata []byte
+			rows     int64
+			failed   bool
+		)
+		defer func() {
+			for _, rec := range batches {
+				rec.Release()
+			}
+			for _, chunk := range trailing {
+				if chunk.Data != nil {
+					chunk.Data.Release()
+				}
+			}
+		}()
+
+		for chunk := range in {
+			switch {
+			case failed:
+				// The upstream stream is drained so it can finish
+				if chunk.Data != nil {
+					chunk.Data.Release()
+				}
+			case chunk.Err != nil:
+				out <- chunk
+				failed = true
+			case chunk.Data == nil || chunk.Data.NumRows() == 0:
+				if len(batches) == 0 {
+					out <- chunk
+				} else {
+					trailing = append(trailing, chunk)
+				}
+			default:
+				rows += chunk.Data.NumRows()
+				batches = append(batches, chunk.Data)
+				if chunk.AppMetadata != nil {
+					metadata = chunk.AppMetadata
+				}
+				if maxRows > 0 && rows > maxRows {
+					out <- flight.StreamChunk{Err: status.Errorf(codes.ResourceExhausted,
+						"result exceeds the maximum of %d rows for a coalesced batch", maxRows)}
+					failed = true
+				}
+			}
+		}
+		if failed || len(batches) == 0 {
+			return
+		}
+
+		rec := batches[0]
+		if len(batches) > 1 {
+			var err error
+			if rec, err = concatBatches(s.Alloc, batches); err != nil {
+				out <- flight.StreamChunk{Err: err}
+				return
+			}
+			for _, b := range batches {
+				b.Release()
+			}
+		}
+		batches = nil
+		out <- flight.StreamChunk{Data: rec, AppMetadata: metadata}
+
+		for _, chunk := range trailing {
+			out <- chunk
+		}
+		trailing = nil
+	}()
+	return out
+}
+
+// concatBatches concatenates batches of the same schema into one
+func concatBatches(mem memory.Allocator, batches []arrow.RecordBatch) (arrow.RecordBatch, error) {
+	schema := batches[0].Schema()
+	cols := make([]arrow.Array, schema.NumFields())
+	var rows int64
+	for _, rec := range batches {
+		rows += rec.NumRows()
+	}
+	for i := range cols {
+		parts := make([]arrow.Array, len(batches))
+		for j, rec := range batches {
+			parts[j] = rec.Column(i)
+		}
+		col, err := array.Concatenate(parts, mem)
+		if err != nil {
+			for _, c := range cols[:i] {
+				c.Release()
+			}
+			return nil, err
+		}
+		cols[i] = col
+	}
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+	return array.NewRecordBatch(schema, cols, rows), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+)
+
+const benchRows = 100000
+
+// setupBenchData creates bench_table holding benchRows rows
+func setupBenchData(b *testing.B, server *DummyFlightSQLServer) {
+	execTestSQL(b, server, fmt.Sprintf(`
+		CREATE TABLE bench_table AS
+		WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < %d)
+		SELECT x AS id, 'name_' || x AS name, x * 1.5 AS value FROM seq
+	`, benchRows))
+}
+
+func BenchmarkDoGetStatement(b *testing.B) {
+	drivers := getTestDrivers(b)
+
+	for _, driver := range drivers {
+		b.Run(driver.name, func(b *testing.B) {
+			server, cleanup := setupTestServer(b, driver)
+			defer cleanup()
+
+			setupBenchData(b, server)
+			benchStatement(b, server, driver)
+		})
+	}
+}
+
+func BenchmarkDoGetStatement_Allocators(b *testing.B) {
+	drivers := getTestDrivers(b)
+
+	for _, driver := range drivers {
+		for _, kind := range []string{AllocatorDefault, AllocatorGo, AllocatorMalloc} {
+			b.Run(driver.name+"_"+kind, func(b *testing.B) {
+				server, cleanup := setupTestServer(b, driver)
+				defer cleanup()
+
+				alloc, err := newAllocator(kind)
+				if err != nil {
+					b.Fatalf("Failed to create %s allocator: %v", kind, err)
+				}
+				server.Alloc = alloc
+
+				setupBenchData(b, server)
+				benchStatement(b, server, driver)
+			})
+		}
+	}
+}
+
+// benchStatement repeatedly plans and streams a full scan of bench_table
+func benchStatement(b *testing.B, server *DummyFlightSQLServer, driver testDriver) {
+	ctx := context.Background()
+	cmd := &mockStatementQuery{query: "SELECT id, name, value FROM bench_table"}
+	desc := &flight.FlightDescriptor{
+		Type: 0,
+		Cmd:  []byte("bench-command"),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var rows int64
+	for i := 0; i < b.N; i++ {
+		flightInfo, err := server.GetFlightInfoStatement(ctx, cmd, desc)
+		if err != nil {
+			b.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+		}
+
+		statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+		if err != nil {
+			b.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+		}
+
+		_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+		if err != nil {
+			b.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+		}
+
+		for chunk := range streamCh {
+			if chunk.Data != nil {
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+		}
+	}
+
+	if rows != int64(b.N)*benchRows {
+		b.Fatalf("Expected %d rows for %s, got %d", int64(b.N)*benchRows, driver.name, rows)
+	}
+	b.ReportMetric(float64(rows)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkDoGetTables(b *testing.B) {
+	drivers := getTestDrivers(b)
+
+	for _, driver := range drivers {
+		b.Run(driver.name, func(b *testing.B) {
+			server, cleanup := setupTestServer(b, driver)
+			defer cleanup()
+
+			const numTables = 200
+			statements := make([]string, 0, numTables)
+			for i := 0; i < numTables; i++ {
+				statements = append(statements, fmt.Sprintf("CREATE TABLE bench_table_%d (id INTEGER, name TEXT)", i))
+			}
+			execTestSQL(b, server, statements...)
+
+			ctx := context.Background()
+			cmd := &mockGetTables{}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, streamCh, err := server.DoGetTables(ctx, cmd)
+				if err != nil {
+					b.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+				}
+
+				var tables int64
+				for chunk := range streamCh {
+					if chunk.Data != nil {
+						tables += chunk.Data.NumRows()
+						chunk.Data.Release()
+					}
+				}
+
+				if tables < numTables {
+					b.Fatalf("Expected at least %d tables for %s, got %d", numTables, driver.name, tables)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/util"
+)
+
+// resultCache keeps the batches of completed statement results so identical
+// queries can be answered without going to the backend. The server doesn't
+// support bound parameters, so the normalized query alone identifies a result.
+type resultCache struct {
+	ttl      time.Duration
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*cachedResult
+	size    int64
+}
+
+// cachedResult is a materialized statement result owned by the cache
+type cachedResult struct {
+	schema  *arrow.Schema
+	batches []arrow.RecordBatch
+	bytes   int64
+	expires time.Time
+}
+
+// newResultCache returns a cache holding results for ttl, up to maxBytes in
+// total. A maxBytes of zero leaves the size unbounded.
+func newResultCache(ttl time.Duration, maxBytes int64) *resultCache {
+	return &resultCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*cachedResult),
+	}
+}
+
+// get returns the cached result for key with every batch retained for the
+// caller, or false if there is no live entry
+func (c *resultCache) get(key string) (*arrow.Schema, []arrow.RecordBatch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	if time.Now().After(entry.expires) {
+		c.remove(key)
+		return nil, nil, false
+	}
+
+	batches := make([]arrow.RecordBatch, len(entry.batches))
+	for i, rec := range entry.batches {
+		rec.Retain()
+		batches[i] = rec
+	}
+	return entry.schema, batches, true
+}
+
+// put takes ownership of batches and stores them under key, evicting the
+// entries closest to expiry until the result fits
+func (c *resultCache) put(key string, schema *arrow.Schema, batches []arrow.RecordBatch, bytes int64) {
+	c.putFor(key, schema, batches, bytes, c.ttl)
+}
+
+// putFor is put with an entry specific time to live
+func (c *resultCache) putFor(key string, schema *arrow.Schema, batches []arrow.RecordBatch, bytes int64, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBytes > 0 && bytes > c.maxBytes {
+		releaseBatches(batches)
+		return
+	}
+
+	c.remove(key)
+	for c.maxBytes > 0 && c.size+bytes > c.maxBytes {
+		var oldest string
+		for k, entry := range c.entries {
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		c.remove(oldest)
+	}
+
+	c.entries[key] = &cachedResult{
+		schema:  schema,
+		batches: batches,
+		bytes:   bytes,
+		expires: time.Now().Add(ttl),
+	}
+	c.size += bytes
+}
+
+// invalidate drops every cached result
+func (c *resultCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		c.remove(key)
+	}
+}
+
+// remove drops the entry for key. c.mu must be held.
+func (c *resultCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	releaseBatches(entry.batches)
+	c.size -= entry.bytes
+	delete(c.entries, key)
+}
+
+func releaseBatches(batches []arrow.RecordBatch) {
+	for _, rec := range batches {
+		rec.Release()
+	}
+}
+
+// refreshMetadata drops cached results, e.g. after the backend was changed
+// outside of the server
+func (s *DummyFlightSQLServer) refreshMetadata(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.cache != nil {
+		s.cache.invalidate()
+	}
+	return [][]byte{[]byte("metadata refreshed")}, nil
+}
+
+// resultCollector accumulates the batches of a result while it is streamed,
+// giving up once the result can't fit in the cache
+type resultCollector struct {
+	cache   *resultCache
+	key     string
+	schema  *arrow.Schema
+	batches []arrow.RecordBatch
+	bytes   int64
+	active  bool
+}
+
+// newResultCollector returns a collector for the result of query, or nil if
+// caching is disabled
+func newResultCollector(cache *resultCache, key string, schema *arrow.Schema) *resultCollector {
+	if cache == nil {
+		return nil
+	}
+	return &resultCollector{cache: cache, key: key, schema: schema, active: true}
+}
+
+// add retains rec for the cache
+func (c *resultCollector) add(rec arrow.RecordBatch) {
+	if c == nil || !c.active {
+		return
+	}
+
+	size := util.TotalRecordSize(rec)
+	if c.cache.maxBytes > 0 && c.bytes+size > c.cache.maxBytes {
+		c.discard()
+		return
+	}
+
+	rec.Retain()
+	c.batches = append(c.batches, rec)
+	c.bytes += size
+}
+
+// store hands the collected result over to the cache
+func (c *resultCollector) store() {
+	if c == nil || !c.active {
+		return
+	}
+	c.cache.put(c.key, c.schema, c.batches, c.bytes)
+	c.batches = nil
+	c.active = false
+}
+
+// discard releases the collected batches. It is a no-op after store.
+func (c *resultCollector) discard() {
+	if c == nil {
+		return
+	}
+	releaseBatches(c.batches)
+	c.batches = nil
+	c.active = false
+}
+
+// cacheKey normalizes whitespace and trailing semicolons outside of quoted
+// literals and identifiers, so trivially reformatted queries share an entry
+func cacheKey(query string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+
+	var b strings.Builder
+	var quote rune
+	space := false
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// leadingKeyword returns the first word of query in upper case
+func leadingKeyword(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	first := strings.TrimLeft(fields[0], "(")
+	if idx := strings.IndexFunc(first, func(r rune) bool { return !unicode.IsLetter(r) }); idx >= 0 {
+		first = first[:idx]
+	}
+	return strings.ToUpper(first)
+}
+
+// isReadQuery reports whether query only reads data and so may be cached.
+// Anything else is treated as a write that invalidates the cache.
+func isReadQuery(query string) bool {
+	switch leadingKeyword(query) {
+	case "SELECT", "WITH", "VALUES", "TABLE", "FROM", "SHOW", "DESCRIBE", "EXPLAIN":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkCellSizes fails if any string or binary value in rec is larger than
+// maxBytes. A maxBytes of zero disables the check.
+func checkCellSizes(rec arrow.RecordBatch, maxBytes int64) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+
+	for c, col := range rec.Columns() {
+		var valueLen func(i int) int
+		switch col := col.(type) {
+		case *array.Binary:
+			valueLen = func(i int) int { return col.ValueLen(i) }
+		case *array.LargeBinary:
+			valueLen = func(i int) int { return col.ValueLen(i) }
+		case *array.String:
+			valueLen = func(i int) int { return col.ValueLen(i) }
+		case *array.LargeString:
+			valueLen = func(i int) int { return col.ValueLen(i) }
+		default:
+			continue
+		}
+
+		for i := 0; i < col.Len(); i++ {
+			if col.IsValid(i) && int64(valueLen(i)) > maxBytes {
+				return fmt.Errorf("value in column %q row %d is %d bytes, exceeding the maximum cell size of %d bytes",
+					rec.ColumnName(c), i, valueLen(i), maxBytes)
+			}
+		}
+	}
+	return nil
+}
+
+// serializeSchema encodes the result schema of a statement for a FlightInfo
+// or SchemaResult, failing when it is wider than MaxSchemaColumns or larger
+// than MaxSchemaBytes encoded
+func (s *DummyFlightSQLServer) serializeSchema(schema *arrow.Schema) ([]byte, error) {
+	if limit := s.cfg.MaxSchemaColumns; limit > 0 && schema.NumFields() > limit {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"result has %d columns, exceeding the maximum of %d; select fewer columns", schema.NumFields(), limit)
+	}
+
+	serialized := flight.SerializeSchema(schema, s.Alloc)
+	if limit := s.cfg.MaxSchemaBytes; limit > 0 && len(serialized) > limit {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"result schema is %d bytes encoded, exceeding the maximum of %d; select fewer columns", len(serialized), limit)
+	}
+	return serialized, nil
+}
+
+// checkBatchSchema fails if rec doesn't match the schema advertised for the
+// stream, which clients would otherwise only report as an opaque IPC error
+func checkBatchSchema(rec arrow.RecordBatch, schema *arrow.Schema) error {
+	if rec.Schema().Equal(schema) {
+		return nil
+	}
+	return status.Errorf(codes.Internal, "backend returned a batch with schema %s, expected %s", rec.Schema(), schema)
+}
+
+// checkAdvertisedSchema fails if the result schema of a statement differs
+// from the one GetFlightInfoStatement advertised from its WHERE 1=0 probe,
+// naming the columns that changed. Metadata and nullability are ignored.
+func checkAdvertisedSchema(actual, advertised *arrow.Schema) error {
+	if actual.NumFields() != advertised.NumFields() {
+		return status.Errorf(codes.Internal, "result has %d columns, but %d were advertised", actual.NumFields(), advertised.NumFields())
+	}
+
+	var diffs []string
+	for i, field := range actual.Fields() {
+		want := advertised.Field(i)
+		if field.Name != want.Name || !arrow.TypeEqual(field.Type, want.Type) {
+			diffs = append(diffs, fmt.Sprintf("column %d is %s %s, advertised as %s %s", i, field.Name, field.Type, want.Name, want.Type))
+		}
+	}
+	if len(diffs) > 0 {
+		return status.Errorf(codes.Internal, "result schema differs from the advertised schema: %s", strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// withSchema returns rec relabeled with schema, whose column types match
+// those of rec, taking over the caller's reference to rec
+func withSchema(rec arrow.RecordBatch, schema *arrow.Schema) arrow.RecordBatch {
+	defer rec.Release()
+	return array.NewRecordBatch(schema, rec.Columns(), rec.NumRows())
+}
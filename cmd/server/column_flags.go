@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+)
+
+// GetObjects column fields read for the column flags, by position in
+// adbc.GetObjectsSchema
+const (
+	xdbcNullableField        = 8
+	xdbcIsNullableField      = 13
+	xdbcIsAutoincrementField = 17
+)
+
+// columnFlags is what the backend reports about a column beyond its type
+type columnFlags struct {
+	nullable      bool
+	autoIncrement bool
+}
+
+// withColumnFlags returns schema with the nullability of the flagged
+// columns set from flags, and their auto-increment flag attached as
+// Flight SQL column metadata. Other columns are unchanged.
+func withColumnFlags(schema *arrow.Schema, flags map[string]columnFlags) *arrow.Schema {
+	if len(flags) == 0 {
+		return schema
+	}
+
+	fields := schema.Fields()
+	for i, field := range fields {
+		flag, ok := flags[field.Name]
+		if !ok {
+			continue
+		}
+		fields[i].Nullable = flag.nullable
+		extra := flightsql.NewColumnMetadataBuilder().IsAutoIncrement(flag.autoIncrement).Metadata()
+		fields[i].Metadata = arrow.NewMetadata(
+			slices.Concat(field.Metadata.Keys(), extra.Keys()),
+			slices.Concat(field.Metadata.Values(), extra.Values()))
+	}
+
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+// lookupColumnFlags derives the column flags of a table from GetObjects at
+// column depth. Primary key columns are not nullable, and an INTEGER
+// PRIMARY KEY in SQLite aliases the rowid, so it is auto-incrementing too.
+// Backends without GetObjects return no flags.
+func lookupColumnFlags(ctx context.Context, conn adbc.Connection, vendor, catalog, dbSchema, table string) (map[string]columnFlags, error) {
+	reader, err := conn.GetObjects(ctx, adbc.ObjectDepthColumns, optionalName(catalog), optionalName(dbSchema), &table, nil, nil)
+	if err != nil {
+		if isNotImplemented(err) {
+			return nil, nil
+		}
+		return nil, metadataError(err)
+	}
+	defer reader.Release()
+
+	flags := make(map[string]columnFlags)
+	for reader.Next() {
+		rec := reader.RecordBatch()
+
+		schemasCol := rec.Column(1).(*array.List)
+		schemaValues := schemasCol.ListValues().(*array.Struct)
+		tablesCol := schemaValues.Field(1).(*array.List)
+		tableValues := tablesCol.ListValues().(*array.Struct)
+		columnsCol := tableValues.Field(2).(*array.List)
+		columnValues := columnsCol.ListValues().(*array.Struct)
+		nullableCol := columnValues.Field(xdbcNullableField).(*array.Int16)
+		autoIncrementCol := columnValues.Field(xdbcIsAutoincrementField).(*array.Boolean)
+		constraintsCol := tableValues.Field(3).(*array.List)
+		constraintValues := constraintsCol.ListValues().(*array.Struct)
+		constraintColumnsCol := constraintValues.Field(2).(*array.List)
+
+		names, err := asStringColumns(tableValues.Field(0), columnValues.Field(0), columnValues.Field(4),
+			columnValues.Field(xdbcIsNullableField), constraintValues.Field(1), constraintColumnsCol.ListValues())
+		if err != nil {
+			return nil, fmt.Errorf("unexpected GetObjects column layout: %w", err)
+		}
+		tableNameCol, columnNameCol, typeNameCol, isNullableCol := names[0], names[1], names[2], names[3]
+		constraintTypeCol, constraintColumnNames := names[4], names[5]
+
+		// The table name is a LIKE pattern, so skip tables it matched loosely
+		for k := 0; k < tableNameCol.Len(); k++ {
+			if tableNameCol.Value(k) != table || columnsCol.IsNull(k) {
+				continue
+			}
+
+			var primaryKey []string
+			start, end := constraintsCol.ValueOffsets(k)
+			for c := int(start); c < int(end); c++ {
+				if constraintTypeCol.Value(c) != "PRIMARY KEY" {
+					continue
+				}
+				nameStart, nameEnd := constraintColumnsCol.ValueOffsets(c)
+				for n := int(nameStart); n < int(nameEnd); n++ {
+					primaryKey = append(primaryKey, constraintColumnNames.Value(n))
+				}
+			}
+
+			start, end = columnsCol.ValueOffsets(k)
+			for c := int(start); c < int(end); c++ {
+				name := columnNameCol.Value(c)
+				rowidAlias := vendor == vendorSQLite && len(primaryKey) == 1 && primaryKey[0] == name &&
+					!typeNameCol.IsNull(c) && strings.EqualFold(typeNameCol.Value(c), "INTEGER")
+				// SQLite lets other primary key columns hold NULL
+				notNull := (nullableCol.IsValid(c) && nullableCol.Value(c) == 0) ||
+					(!isNullableCol.IsNull(c) && isNullableCol.Value(c) == "NO") ||
+					rowidAlias || (vendor != vendorSQLite && slices.Contains(primaryKey, name))
+				flags[name] = columnFlags{
+					nullable:      !notNull,
+					autoIncrement: rowidAlias || (autoIncrementCol.IsValid(c) && autoIncrementCol.Value(c)),
+				}
+			}
+		}
+	}
+	return flags, reader.Err()
+}
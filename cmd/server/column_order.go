@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// columnOrder moves the columns of a statement result to the positions set
+// by ColumnOrder and SortColumns. The nil order keeps them as they are.
+type columnOrder struct {
+	indices []int // result column i is backend column indices[i]
+}
+
+// newColumnOrder returns the order for results with the columns of schema,
+// or nil when the configured order leaves them where they are
+func (s *DummyFlightSQLServer) newColumnOrder(schema *arrow.Schema) *columnOrder {
+	if len(s.cfg.ColumnOrder) == 0 && !s.cfg.SortColumns {
+		return nil
+	}
+
+	fields := schema.Fields()
+	placed := make([]bool, len(fields))
+	indices := make([]int, 0, len(fields))
+	for _, name := range s.cfg.ColumnOrder {
+		for i, field := range fields {
+			if !placed[i] && field.Name == name {
+				placed[i] = true
+				indices = append(indices, i)
+			}
+		}
+	}
+
+	var rest []int
+	for i := range fields {
+		if !placed[i] {
+			rest = append(rest, i)
+		}
+	}
+	if s.cfg.SortColumns {
+		slices.SortStableFunc(rest, func(a, b int) int {
+			return strings.Compare(strings.ToLower(fields[a].Name), strings.ToLower(fields[b].Name))
+		})
+	}
+	indices = append(indices, rest...)
+
+	for i, index := range indices {
+		if i != index {
+			return &columnOrder{indices: indices}
+		}
+	}
+	return nil
+}
+
+// schema returns schema with its fields in the result order
+func (o *columnOrder) schema(schema *arrow.Schema) *arrow.Schema {
+	if o == nil {
+		return schema
+	}
+
+	fields := make([]arrow.Field, len(o.indices))
+	for i, index := range o.indices {
+		fields[i] = schema.Field(index)
+	}
+	metadata := schema.Metadata()
+	return arrow.NewSchema(fields, &metadata)
+}
+
+// apply returns rec with its columns in the result order, taking over the
+// caller's reference to rec
+func (o *columnOrder) apply(rec arrow.RecordBatch) arrow.RecordBatch {
+	if o == nil {
+		return rec
+	}
+	defer rec.Release()
+
+	cols := make([]arrow.Array, len(o.indices))
+	for i, index := range o.indices {
+		cols[i] = rec.Column(index)
+	}
+	return array.NewRecordBatch(o.schema(rec.Schema()), cols, rec.NumRows())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// stringColumn reads the values of a string column whether the driver
+// returned it plain or dictionary-encoded
+type stringColumn interface {
+	Len() int
+	IsNull(i int) bool
+	Value(i int) string
+}
+
+// dictionaryStringColumn decodes a dictionary-encoded string column
+type dictionaryStringColumn struct {
+	*array.Dictionary
+	values stringColumn
+}
+
+func (c dictionaryStringColumn) IsNull(i int) bool {
+	return c.Dictionary.IsNull(i) || c.values.IsNull(c.GetValueIndex(i))
+}
+
+func (c dictionaryStringColumn) Value(i int) string {
+	return c.values.Value(c.GetValueIndex(i))
+}
+
+// asStringColumns returns each of arrs as a stringColumn, failing if one of
+// them doesn't hold strings
+func asStringColumns(arrs ...arrow.Array) ([]stringColumn, error) {
+	cols := make([]stringColumn, len(arrs))
+	for i, arr := range arrs {
+		col, err := asStringColumn(arr)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+func asStringColumn(arr arrow.Array) (stringColumn, error) {
+	switch arr := arr.(type) {
+	case *array.String:
+		return arr, nil
+	case *array.LargeString:
+		return arr, nil
+	case *array.Dictionary:
+		values, err := asStringColumn(arr.Dictionary())
+		if err != nil {
+			return nil, err
+		}
+		return dictionaryStringColumn{Dictionary: arr, values: values}, nil
+	}
+	return nil, fmt.Errorf("expected a string column, got %s", arr.DataType())
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+)
+
+// tableComments holds the comment on a table and on each of its columns,
+// keyed by column name
+type tableComments struct {
+	table   string
+	columns map[string]string
+}
+
+// withComments returns schema with the table and column comments attached
+// as Flight SQL remarks metadata. Columns without a comment are unchanged.
+func withComments(schema *arrow.Schema, comments tableComments) *arrow.Schema {
+	if comments.table == "" && len(comments.columns) == 0 {
+		return schema
+	}
+
+	fields := schema.Fields()
+	for i, field := range fields {
+		remarks, ok := comments.columns[field.Name]
+		if !ok || remarks == "" {
+			continue
+		}
+		fields[i].Metadata = withRemarks(field.Metadata, remarks)
+	}
+
+	md := schema.Metadata()
+	if comments.table != "" {
+		md = withRemarks(md, comments.table)
+	}
+	return arrow.NewSchema(fields, &md)
+}
+
+// withRemarks returns a copy of md with the remarks key added
+func withRemarks(md arrow.Metadata, remarks string) arrow.Metadata {
+	keys := slices.Concat(md.Keys(), []string{flightsql.RemarksKey})
+	values := slices.Concat(md.Values(), []string{remarks})
+	return arrow.NewMetadata(keys, values)
+}
+
+// lookupComments reads the comments on a table and its columns. DuckDB
+// keeps them in its catalog functions, other backends are asked for the
+// column remarks through GetObjects. Backends without comments return an
+// empty result.
+func lookupComments(ctx context.Context, conn adbc.Connection, vendor, catalog, dbSchema, table string) (tableComments, error) {
+	if vendor == vendorDuckDB {
+		return duckDBComments(ctx, conn, catalog, dbSchema, table)
+	}
+	return objectsComments(ctx, conn, catalog, dbSchema, table)
+}
+
+func duckDBComments(ctx context.Context, conn adbc.Connection, catalog, dbSchema, table string) (tableComments, error) {
+	where := "table_name = " + quoteLiteral(table)
+	if catalog != "" {
+		where += " AND database_name = " + quoteLiteral(catalog)
+	}
+	if dbSchema != "" {
+		where += " AND schema_name = " + quoteLiteral(dbSchema)
+	}
+
+	comments := tableComments{columns: make(map[string]string)}
+
+	tableComment, err := queryStrings(ctx, conn, "SELECT comment FROM duckdb_tables() WHERE "+where)
+	if err != nil {
+		return tableComments{}, err
+	}
+	if len(tableComment) > 0 {
+		comments.table = tableComment[0]
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return tableComments{}, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("SELECT column_name, comment FROM duckdb_columns() WHERE " + where); err != nil {
+		return tableComments{}, err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return tableComments{}, err
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+		cols, err := asStringColumns(reader.RecordBatch().Columns()...)
+		if err != nil {
+			return tableComments{}, err
+		}
+		for i := 0; i < cols[0].Len(); i++ {
+			if !cols[1].IsNull(i) {
+				comments.columns[cols[0].Value(i)] = cols[1].Value(i)
+			}
+		}
+	}
+	return comments, reader.Err()
+}
+
+// objectsComments reads the column remarks from GetObjects at column depth
+func objectsComments(ctx context.Context, conn adbc.Connection, catalog, dbSchema, table string) (tableComments, error) {
+	reader, err := conn.GetObjects(ctx, adbc.ObjectDepthColumns, optionalName(catalog), optionalName(dbSchema), &table, nil, nil)
+	if err != nil {
+		if isNotImplemented(err) {
+			return tableComments{}, nil
+		}
+		return tableComments{}, metadataError(err)
+	}
+	defer reader.Release()
+
+	comments := tableComments{columns: make(map[string]string)}
+	for reader.Next() {
+		rec := reader.RecordBatch()
+
+		schemasCol := rec.Column(1).(*array.List)
+		schemaValues := schemasCol.ListValues().(*array.Struct)
+		tablesCol := schemaValues.Field(1).(*array.List)
+		tableValues := tablesCol.ListValues().(*array.Struct)
+		columnsCol := tableValues.Field(2).(*array.List)
+		columnValues := columnsCol.ListValues().(*array.Struct)
+
+		names, err := asStringColumns(tableValues.Field(0), columnValues.Field(0), columnValues.Field(2))
+		if err != nil {
+			return tableComments{}, fmt.Errorf("unexpected GetObjects column layout: %w", err)
+		}
+		tableNameCol, columnNameCol, remarksCol := names[0], names[1], names[2]
+
+		// The table name is a LIKE pattern, so skip tables it matched loosely
+		for k := 0; k < tableNameCol.Len(); k++ {
+			if tableNameCol.Value(k) != table || columnsCol.IsNull(k) {
+				continue
+			}
+			start, end := columnsCol.ValueOffsets(k)
+			for c := int(start); c < int(end); c++ {
+				if !remarksCol.IsNull(c) {
+					comments.columns[columnNameCol.Value(c)] = remarksCol.Value(c)
+				}
+			}
+		}
+	}
+	return comments, reader.Err()
+}
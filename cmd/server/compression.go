@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+// zstdCompressorName is the grpc-encoding value used for zstd
+const zstdCompressorName = "zstd"
+
+// supportedCompressions lists the codecs accepted by ServerConfig.Compression
+var supportedCompressions = []string{gzip.Name, zstdCompressorName}
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// zstdCompressor implements grpc's encoding.Compressor on top of klauspost/compress
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return zstdCompressorName
+}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+// checkCompression verifies the configured codec is one the server can use
+func checkCompression(codec string) error {
+	if codec == "" || slices.Contains(supportedCompressions, codec) {
+		return nil
+	}
+	return fmt.Errorf("unsupported compression %q, supported values are: %v", codec, supportedCompressions)
+}
+
+// compressionInterceptor compresses responses with codec for clients that
+// advertise support for it. Other clients are served uncompressed.
+func compressionInterceptor(codec string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		supported, err := grpc.ClientSupportedCompressors(ss.Context())
+		if err == nil && slices.Contains(supported, codec) {
+			if err := grpc.SetSendCompressor(ss.Context(), codec); err != nil {
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// ipcCompressionHeader is the request header listing the Arrow IPC body
+// codecs a client can decode, e.g. "lz4,zstd"
+const ipcCompressionHeader = "x-arrow-ipc-compression"
+
+// supportedIPCCompressions lists the codecs accepted by
+// ServerConfig.IPCCompression
+var supportedIPCCompressions = []string{"lz4", "zstd"}
+
+// checkIPCCompression verifies the configured IPC body codec is known
+func checkIPCCompression(codec string) error {
+	if codec == "" || slices.Contains(supportedIPCCompressions, codec) {
+		return nil
+	}
+	return fmt.Errorf("unsupported IPC compression %q, supported values are: %v", codec, supportedIPCCompressions)
+}
+
+// ipcCompression returns the IPC writer option compressing record batch
+// bodies with the configured codec, or false if the client didn't list it
+func (s *DummyFlightSQLServer) ipcCompression(ctx context.Context) (ipc.Option, bool) {
+	codec := s.cfg.IPCCompression
+	if codec == "" {
+		return nil, false
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(ipcCompressionHeader) {
+		for _, accepted := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(accepted), codec) {
+				if codec == "zstd" {
+					return ipc.WithZstd(), true
+				}
+				return ipc.WithLZ4(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// writeChunks writes the stream chunks of a DoGet handler to stream, the way
+// Flight SQL does, releasing every chunk
+func writeChunks(stream flight.FlightService_DoGetServer, schema *arrow.Schema, ch <-chan flight.StreamChunk, opts ...ipc.Option) error {
+	defer func() {
+		for chunk := range ch {
+			if chunk.Data != nil {
+				chunk.Data.Release()
+			}
+		}
+	}()
+
+	wr := flight.NewRecordWriter(stream, append(opts, ipc.WithSchema(schema))...)
+	defer wr.Close()
+
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return chunk.Err
+		}
+		wr.SetFlightDescriptor(chunk.Desc)
+		err := wr.WriteWithAppMetadata(chunk.Data, chunk.AppMetadata)
+		chunk.Data.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	flightsqldriver "github.com/apache/arrow-adbc/go/adbc/driver/flightsql"
+	"github.com/apache/arrow-adbc/go/adbc/drivermgr"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ServerConfig holds the settings used to construct a DummyFlightSQLServer
+type ServerConfig struct {
+	// DatabaseOptions are passed to the ADBC driver manager as-is. The
+	// "driver" key selects the driver library to load. The driver
+	// "adbc_driver_flightsql" proxies another Flight SQL server through the
+	// built-in Go driver instead; its uri and auth options are passed as-is.
+	DatabaseOptions map[string]string
+
+	// SecretsFile is a JSON object of secret values. ${NAME} in the values
+	// of DatabaseOptions is replaced by NAME from this file or else from
+	// the environment when the server starts.
+	SecretsFile string
+
+	// AllowedDrivers restricts which driver names the server may load.
+	// An empty list allows any driver.
+	AllowedDrivers []string
+
+	// NormalizeTableTypes maps backend specific table types (e.g. SQLite's
+	// "table" or DuckDB's "BASE TABLE") to canonical Flight SQL values.
+	NormalizeTableTypes bool
+
+	// StrictTableTypes rejects table type filters the backend does not
+	// report from GetTableTypes with InvalidArgument, instead of silently
+	// matching nothing.
+	StrictTableTypes bool
+
+	// IdentifierCase folds the catalog, schema and table filters of
+	// metadata requests to "upper" or "lower" case before they are passed
+	// to GetObjects, so that clients written against a backend with other
+	// identifier case rules still match. Empty passes them as-is.
+	IdentifierCase string
+
+	// BackendQuoteChar quotes the identifiers of the qualified table names
+	// the server builds itself, e.g. for table flights and row counts.
+	// Empty uses the standard double quote, which SQLite and DuckDB use.
+	BackendQuoteChar string
+
+	// Dialect overrides SqlInfo values reported to clients, so that the
+	// server presents one dialect whatever backend it fronts
+	Dialect SqlDialect
+
+	// EmptySchemaName reports the empty schema name some backends (SQLite)
+	// use for their default schema under this name instead, e.g. "main", so
+	// clients can tell it apart from "no schema". Empty reports it as-is.
+	EmptySchemaName string
+
+	// HeartbeatInterval makes DoGetStatement send an empty batch with
+	// {"heartbeat":true} app metadata this often while the backend computes
+	// the first batch, so that proxies don't drop the idle stream. When
+	// GetFlightInfo advertised the schema, heartbeats start before the
+	// statement runs and its errors arrive on the stream. Zero disables
+	// heartbeats.
+	HeartbeatInterval time.Duration
+
+	// ProgressInterval makes DoGetStatement attach progress app metadata
+	// (rows sent so far) every N batches. Zero disables progress reporting.
+	ProgressInterval int
+
+	// StreamStats ends every DoGetStatement stream with an empty batch whose
+	// app metadata reports the rows, batches and bytes sent and the elapsed
+	// time
+	StreamStats bool
+
+	// BatchRows re-slices DoGetStatement results into batches of this many
+	// rows unless the client asks for another size with an x-batch-rows
+	// header. Zero keeps the batches as the backend returns them.
+	BatchRows int64
+
+	// CoalesceMaxRows caps the results clients may ask for as a single
+	// batch with an x-coalesce header, since the whole result is buffered.
+	// Larger results fail with ResourceExhausted. Zero disables the cap.
+	CoalesceMaxRows int64
+
+	// StatelessTickets embeds the signed query in statement tickets instead
+	// of keeping it in server memory, so that any instance sharing the
+	// TicketSigningKey can serve the ticket.
+	StatelessTickets bool
+	TicketSigningKey []byte
+
+	// Compression is the gRPC codec ("gzip" or "zstd") used for responses to
+	// clients that support it. Empty disables compression.
+	Compression string
+
+	// IPCCompression ("lz4" or "zstd") compresses the record batch bodies
+	// of statement results and table flights for clients that list the
+	// codec in the x-arrow-ipc-compression header. Empty disables it.
+	IPCCompression string
+
+	// AdminToken enables admin-only actions for clients that send it as a
+	// bearer token. Empty disables admin actions.
+	AdminToken string
+
+	// QueryRewriter is applied to every statement query when it is planned
+	// in GetFlightInfoStatement, so DoGetStatement executes the rewritten
+	// SQL. Nil leaves queries unchanged.
+	QueryRewriter QueryRewriter
+
+	// MetadataFilter hides catalogs, schemas and tables from metadata
+	// listings per client. Nil lists everything.
+	MetadataFilter MetadataFilter
+
+	// Allocator selects the Arrow memory allocator: "default", "go" or
+	// "malloc". Empty uses memory.DefaultAllocator.
+	Allocator string
+
+	// DefaultCatalog and DefaultSchema are applied to every backend
+	// connection so unqualified table names resolve predictably across
+	// backends. Empty keeps the backend default.
+	DefaultCatalog string
+	DefaultSchema  string
+
+	// MaxCellBytes fails DoGetStatement when a single string or binary
+	// value is larger than this many bytes. Zero disables the check.
+	MaxCellBytes int64
+
+	// MaxSchemaColumns and MaxSchemaBytes fail GetFlightInfoStatement and
+	// GetSchemaStatement for results with more columns, or a larger encoded
+	// schema, than this. Zero disables a limit.
+	MaxSchemaColumns int
+	MaxSchemaBytes   int
+
+	// TableRowCounts enables the GetTableRowCounts action. Counting runs
+	// COUNT(*) on every table for backends without table statistics, so it
+	// is off by default.
+	TableRowCounts bool
+
+	// SQLiteBusyTimeout makes SQLite connections wait this long for a
+	// locked database before failing with SQLITE_BUSY, and
+	// SQLiteJournalMode sets the journal mode (e.g. "wal") when connections
+	// are opened. Both are only valid with the SQLite driver; zero values
+	// keep SQLite's defaults.
+	SQLiteBusyTimeout time.Duration
+	SQLiteJournalMode string
+
+	// ExportDir enables the ExportParquet action, which writes query
+	// results to Parquet files in directories below it. Empty disables it.
+	ExportDir string
+
+	// ConnectionPoolSize keeps up to this many idle backend connections for
+	// reuse instead of opening one per request. Zero disables pooling.
+	// Pooled connections are closed once they are older than
+	// ConnectionMaxLifetime or were idle for ConnectionMaxIdleTime; zero
+	// durations don't limit them.
+	ConnectionPoolSize    int
+	ConnectionMaxIdleTime time.Duration
+	ConnectionMaxLifetime time.Duration
+
+	// DeferResultSchema makes GetFlightInfoStatement return FlightInfos
+	// without a schema instead of running the query once to discover it,
+	// so the backend only executes the query in DoGet. Clients can ask for
+	// this per statement with the x-defer-schema header.
+	DeferResultSchema bool
+
+	// ValidateResultSchema fails DoGetStatement when the result schema
+	// differs from the one GetFlightInfoStatement advertised, instead of
+	// streaming data that doesn't match it. Backends that infer types from
+	// the returned rows can't type an empty probe result reliably, so it
+	// is off by default.
+	ValidateResultSchema bool
+
+	// ColumnOrder and SortColumns fix the column order of statement results
+	// for clients that index columns by position. Result columns named in
+	// ColumnOrder come first, in that order; the others follow sorted by
+	// name when SortColumns is set, in backend order otherwise.
+	ColumnOrder []string
+	SortColumns bool
+
+	// DefaultTimezone (an IANA name such as "UTC" or "Europe/Berlin") is
+	// stamped on timezone-naive timestamp columns of statement results.
+	// Their values are read as wall clock times in that timezone.
+	DefaultTimezone string
+
+	// VirtualTables serves tables defined by a query instead of backend
+	// storage, keyed by table name. The metadata handlers list them as
+	// views in VirtualSchema of VirtualCatalog (no catalog when empty), and
+	// they are read as table flights.
+	VirtualCatalog string
+	VirtualSchema  string
+	VirtualTables  map[string]string
+
+	// RedactColumns replaces sensitive columns in statement results, keyed
+	// by "table.column". A result column is redacted when it has that name
+	// and the query mentions the table, so renaming the column with an
+	// alias is not caught.
+	RedactColumns map[string]ColumnRedaction
+
+	// AuditSink receives a record of every statement executed, successful
+	// or not. AuditLogFile appends the records to a file as JSON lines
+	// instead. AuditHashQueries records the SHA-256 of the query text.
+	AuditSink        AuditSink
+	AuditLogFile     string
+	AuditHashQueries bool
+
+	// Metrics receives query counts, latencies, errors and gauges. Nil
+	// disables metrics.
+	Metrics Metrics
+
+	// CursorIdleTimeout closes the cursor of a statement fetched in pages
+	// (see the x-page-size header) when its next page is not requested
+	// within this long, releasing its connection and dropping the ticket.
+	// Zero keeps cursors open until they are exhausted.
+	CursorIdleTimeout time.Duration
+
+	// ReadRetries re-executes statements and updates outside of
+	// transactions up to this many times when they fail with one of the
+	// ADBC RetryStatuses before any data was streamed, e.g. with SQLite's
+	// SQLITE_BUSY. The first retry waits RetryBackoff, every next one twice
+	// as long. Zero disables retries; an empty RetryStatuses retries I/O
+	// errors and timeouts.
+	ReadRetries   int
+	RetryBackoff  time.Duration
+	RetryStatuses []adbc.Status
+
+	// ReadOnly rejects maintenance actions that write to the backend
+	ReadOnly bool
+
+	// TLSCertFile and TLSKeyFile enable TLS with the given PEM encoded
+	// certificate and key. Empty serves plaintext.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of the CAs in this PEM file. The certificate's common
+	// name is used as the client identity.
+	TLSClientCAFile string
+
+	// ResultCacheTTL enables caching of statement results for this long, so
+	// repeated identical queries are served without hitting the backend.
+	// Zero disables the cache. ResultCacheMaxBytes caps its total size.
+	ResultCacheTTL      time.Duration
+	ResultCacheMaxBytes int64
+
+	// RepeatFetch governs DoGet of a statement ticket that was fetched
+	// before: "single-use" (the default) fails with NotFound, "reexecute"
+	// runs the query again and "cache-replay" streams the batches of the
+	// first fetch, kept for ReplayTTL and up to ReplayMaxBytes in total.
+	RepeatFetch    string
+	ReplayTTL      time.Duration
+	ReplayMaxBytes int64
+
+	// PreloadTTL enables the Preload action and is how long a preloaded
+	// result is kept at most. PreloadMaxBytes caps the preloaded results.
+	PreloadTTL      time.Duration
+	PreloadMaxBytes int64
+
+	// AllowedStatementOptions lists the ADBC statement options clients may
+	// set with x-statement-option-<key> headers. Other options are ignored,
+	// or rejected when RejectUnknownStatementOptions is set.
+	AllowedStatementOptions       []string
+	RejectUnknownStatementOptions bool
+
+	// EnableReflection registers the gRPC reflection service so tools like
+	// grpcurl can list and call the Flight methods. It exposes the full API
+	// surface to anyone who can connect, so it is off by default.
+	EnableReflection bool
+
+	// EnableSessions tracks clients with a session cookie so they can set
+	// the catalog and schema options through SetSessionOptions. Sessions
+	// are kept in memory until the client closes them.
+	EnableSessions bool
+
+	// AllowedBackendOptions lists the backend settings (DuckDB SET, SQLite
+	// PRAGMA) sessions may change with the SetBackendOption action
+	AllowedBackendOptions []string
+
+	// HTTPAddr enables a JSON query endpoint for non-Flight clients on the
+	// given address, served with the same TLS settings and rate limit as
+	// Flight SQL. Empty disables it.
+	HTTPAddr string
+
+	// ReadinessLatency marks the server not ready when the readiness probe
+	// takes longer than this. Zero only checks that the probe succeeds.
+	ReadinessLatency time.Duration
+
+	// LogLevel is the minimum level logged: debug, info, warn or error.
+	// Empty logs at info.
+	LogLevel string
+
+	// Logger receives the server's log lines. Nil logs text to stderr at
+	// LogLevel; a custom logger does its own level filtering.
+	Logger *slog.Logger
+
+	// RateLimit caps the gRPC and HTTP requests served per second across
+	// all clients. Zero disables rate limiting.
+	RateLimit float64
+
+	// MaxStatementHandles caps the number of statement handles waiting to
+	// be fetched. Registering one more evicts the least recently used
+	// handle, whose tickets then fail with NotFound. Zero means no cap.
+	MaxStatementHandles int
+
+	// QueryLabelHeader is the request header whose value is attached to
+	// the server's log lines and trace spans for the request, so clients can
+	// correlate them with their own. Empty disables query labels.
+	QueryLabelHeader string
+
+	// ConfigFile is a JSON file whose log_level, admin_tokens and
+	// rate_limit override the settings above. It is read at startup and
+	// again by the ReloadConfig action.
+	ConfigFile string
+}
+
+// DefaultServerConfig returns the configuration used by the standalone server
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		DatabaseOptions: map[string]string{
+			"driver":          "adbc_driver_sqlite",
+			adbc.OptionKeyURI: "bla.db",
+		},
+		MaxCellBytes:            64 << 20,
+		AllowedStatementOptions: knownStatementOptions,
+		ReadinessLatency:        time.Second,
+		QueryLabelHeader:        defaultQueryLabelHeader,
+		MaxStatementHandles:     10000,
+		CursorIdleTimeout:       5 * time.Minute,
+		CoalesceMaxRows:         1_000_000,
+	}
+}
+
+// checkDriverAllowed verifies the configured driver is on the allowlist
+func (c ServerConfig) checkDriverAllowed() error {
+	driver := c.DatabaseOptions["driver"]
+	if driver == "" {
+		return fmt.Errorf("no driver configured")
+	}
+
+	if len(c.AllowedDrivers) == 0 {
+		return nil
+	}
+
+	for _, allowed := range c.AllowedDrivers {
+		if driver == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("driver %q is not allowed, allowed drivers are: %s", driver, strings.Join(c.AllowedDrivers, ", "))
+}
+
+// validate checks the configuration for inconsistent settings
+func (c ServerConfig) validate() error {
+	if c.StatelessTickets && len(c.TicketSigningKey) == 0 {
+		return fmt.Errorf("stateless tickets require a ticket signing key")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS requires both a certificate and a key file")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return fmt.Errorf("client certificate authentication requires TLS to be enabled")
+	}
+	if c.LogLevel != "" {
+		if _, err := parseLogLevel(c.LogLevel); err != nil {
+			return err
+		}
+	}
+	if err := c.checkSQLiteOptions(); err != nil {
+		return err
+	}
+	if err := checkIdentifierCase(c.IdentifierCase); err != nil {
+		return err
+	}
+	if err := checkRedactColumns(c.RedactColumns); err != nil {
+		return err
+	}
+	if c.AuditSink != nil && c.AuditLogFile != "" {
+		return fmt.Errorf("AuditSink and AuditLogFile are mutually exclusive")
+	}
+	if c.ConnectionPoolSize < 0 || c.ConnectionMaxIdleTime < 0 || c.ConnectionMaxLifetime < 0 {
+		return fmt.Errorf("connection pool settings must not be negative")
+	}
+	if err := checkBackendOptions(c.AllowedBackendOptions); err != nil {
+		return err
+	}
+	if err := checkRepeatFetch(c.RepeatFetch); err != nil {
+		return err
+	}
+	if c.ReplayTTL < 0 || c.ReplayMaxBytes < 0 {
+		return fmt.Errorf("replay settings must not be negative")
+	}
+	if c.PreloadTTL < 0 || c.PreloadMaxBytes < 0 {
+		return fmt.Errorf("preload settings must not be negative")
+	}
+	if c.MaxSchemaColumns < 0 || c.MaxSchemaBytes < 0 {
+		return fmt.Errorf("schema limits must not be negative")
+	}
+	if err := checkVirtualTables(c.VirtualSchema, c.VirtualTables); err != nil {
+		return err
+	}
+	if err := checkQuoteChar(c.BackendQuoteChar); err != nil {
+		return err
+	}
+	if err := checkDefaultTimezone(c.DefaultTimezone); err != nil {
+		return err
+	}
+	if err := c.Dialect.validate(); err != nil {
+		return err
+	}
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("heartbeat interval must not be negative, got %s", c.HeartbeatInterval)
+	}
+	if c.BatchRows < 0 || c.CoalesceMaxRows < 0 {
+		return fmt.Errorf("batch settings must not be negative")
+	}
+	if c.CursorIdleTimeout < 0 {
+		return fmt.Errorf("cursor idle timeout must not be negative, got %s", c.CursorIdleTimeout)
+	}
+	if c.ReadRetries < 0 || c.RetryBackoff < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("rate limit must not be negative, got %v", c.RateLimit)
+	}
+	if err := checkIPCCompression(c.IPCCompression); err != nil {
+		return err
+	}
+	return checkCompression(c.Compression)
+}
+
+// sqliteJournalModes are the journal modes SQLite accepts
+var sqliteJournalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+
+// checkSQLiteOptions rejects SQLite settings that are invalid or configured
+// for another driver
+func (c ServerConfig) checkSQLiteOptions() error {
+	if c.SQLiteBusyTimeout == 0 && c.SQLiteJournalMode == "" {
+		return nil
+	}
+	if c.SQLiteBusyTimeout < 0 {
+		return fmt.Errorf("SQLite busy timeout must not be negative, got %s", c.SQLiteBusyTimeout)
+	}
+	if mode := c.SQLiteJournalMode; mode != "" && !slices.Contains(sqliteJournalModes, strings.ToUpper(mode)) {
+		return fmt.Errorf("unknown SQLite journal mode %q, expected one of: %s", mode, strings.Join(sqliteJournalModes, ", "))
+	}
+
+	driver := c.DatabaseOptions["driver"]
+	if !strings.Contains(strings.ToLower(filepath.Base(driver)), "sqlite") {
+		return fmt.Errorf("SQLite busy timeout and journal mode require the SQLite driver, got %q", driver)
+	}
+	return nil
+}
+
+// duckDBEntrypoint is the init function exported by the DuckDB library
+const duckDBEntrypoint = "duckdb_adbc_init"
+
+// checkDuckDBOptions catches DuckDB misconfigurations that the driver
+// manager would otherwise only report as an opaque symbol lookup failure.
+// The database location may be given as path or uri, but not as two
+// different ones.
+func (c ServerConfig) checkDuckDBOptions() error {
+	driver := c.DatabaseOptions["driver"]
+	if !strings.Contains(strings.ToLower(filepath.Base(driver)), "duckdb") {
+		return nil
+	}
+
+	if entrypoint, ok := c.DatabaseOptions["entrypoint"]; ok && entrypoint != duckDBEntrypoint {
+		return fmt.Errorf("DuckDB driver %q has entrypoint %q, expected %q", driver, entrypoint, duckDBEntrypoint)
+	}
+
+	path, hasPath := c.DatabaseOptions["path"]
+	uri, hasURI := c.DatabaseOptions[adbc.OptionKeyURI]
+	if hasPath && hasURI && path != uri {
+		return fmt.Errorf("DuckDB driver %q has path %q and %s %q, set only one", driver, path, adbc.OptionKeyURI, uri)
+	}
+	return nil
+}
+
+// flightSQLDriver selects proxy mode, where the backend is another Flight
+// SQL server reached through the Go ADBC Flight SQL driver
+const flightSQLDriver = "adbc_driver_flightsql"
+
+// newFlightSQLDatabase connects to the remote Flight SQL backend. The Go
+// driver rejects options it doesn't know, so the driver manager keys are
+// left out.
+func newFlightSQLDatabase(cfg ServerConfig, alloc memory.Allocator) (adbc.Database, error) {
+	opts := make(map[string]string, len(cfg.DatabaseOptions))
+	for k, v := range cfg.DatabaseOptions {
+		if k == "driver" || k == "entrypoint" {
+			continue
+		}
+		opts[k] = v
+	}
+	if opts[adbc.OptionKeyURI] == "" {
+		return nil, fmt.Errorf("driver %q requires the %s option to be set to the backend address", flightSQLDriver, adbc.OptionKeyURI)
+	}
+	return flightsqldriver.NewDriver(alloc).NewDatabase(opts)
+}
+
+// newDatabase loads the configured ADBC driver and creates the database
+func newDatabase(cfg ServerConfig, alloc memory.Allocator) (adbc.Database, error) {
+	if err := cfg.checkDriverAllowed(); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkDuckDBOptions(); err != nil {
+		return nil, err
+	}
+	if cfg.DatabaseOptions["driver"] == flightSQLDriver {
+		return newFlightSQLDatabase(cfg, alloc)
+	}
+
+	drv := &drivermgr.Driver{}
+	db, err := drv.NewDatabase(cfg.DatabaseOptions)
+	if err != nil {
+		if entrypoint, ok := cfg.DatabaseOptions["entrypoint"]; ok {
+			return nil, fmt.Errorf("failed to load driver %q with entrypoint %q: %w", cfg.DatabaseOptions["driver"], entrypoint, err)
+		}
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew_DriverAllowlist(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name+"_Allowed", func(t *testing.T) {
+			cfg := ServerConfig{
+				DatabaseOptions: testDatabaseOptions(driver),
+				AllowedDrivers:  []string{driver.driverName},
+			}
+
+			server, err := New(cfg)
+			if err != nil {
+				t.Fatalf("Expected server creation to succeed for allowed driver %s: %v", driver.driverName, err)
+			}
+			defer (*server.db).Close()
+		})
+
+		t.Run(driver.name+"_NotAllowed", func(t *testing.T) {
+			cfg := ServerConfig{
+				DatabaseOptions: testDatabaseOptions(driver),
+				AllowedDrivers:  []string{"some_other_driver"},
+			}
+
+			_, err := New(cfg)
+			if err == nil {
+				t.Fatalf("Expected server creation to fail for disallowed driver %s, but it succeeded", driver.driverName)
+			}
+
+			expectedErrorMsg := "not allowed"
+			if !strings.Contains(err.Error(), expectedErrorMsg) {
+				t.Errorf("Expected error containing '%s', got: %v", expectedErrorMsg, err)
+			}
+			if !strings.Contains(err.Error(), driver.driverName) {
+				t.Errorf("Expected error to mention driver '%s', got: %v", driver.driverName, err)
+			}
+		})
+	}
+}
+
+func TestNew_Allocator(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		for _, kind := range []string{"", AllocatorDefault, AllocatorGo, AllocatorMalloc} {
+			t.Run(driver.name+"_"+kind, func(t *testing.T) {
+				server, err := New(ServerConfig{
+					DatabaseOptions: testDatabaseOptions(driver),
+					Allocator:       kind,
+				})
+				if err != nil {
+					t.Fatalf("Expected server creation to succeed with allocator %q: %v", kind, err)
+				}
+				defer (*server.db).Close()
+
+				// Metadata projections are built with the configured allocator
+				_, streamCh, err := server.DoGetTableTypes(context.Background())
+				if err != nil {
+					t.Fatalf("DoGetTableTypes failed with allocator %q for %s: %v", kind, driver.name, err)
+				}
+
+				var totalRows int64
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error with allocator %q for %s: %v", kind, driver.name, chunk.Err)
+					}
+					totalRows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				}
+
+				if totalRows == 0 {
+					t.Errorf("Expected table types with allocator %q for %s, got none", kind, driver.name)
+				}
+			})
+		}
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			DatabaseOptions: map[string]string{"driver": "adbc_driver_sqlite"},
+			Allocator:       "buddy",
+		})
+		if err == nil || !strings.Contains(err.Error(), "unknown allocator") {
+			t.Errorf("Expected unknown allocator error, got: %v", err)
+		}
+	})
+}
+
+func TestNew_DuckDBEntrypoint(t *testing.T) {
+	t.Run("WrongEntrypoint", func(t *testing.T) {
+		_, err := New(ServerConfig{DatabaseOptions: map[string]string{
+			"driver":     "duckdb",
+			"entrypoint": "duckdb_adbc_int",
+			"path":       ":memory:",
+		}})
+		if err == nil {
+			t.Fatal("Expected server creation to fail for a misconfigured DuckDB entrypoint")
+		}
+		if !strings.Contains(err.Error(), "entrypoint") || !strings.Contains(err.Error(), duckDBEntrypoint) {
+			t.Errorf("Expected error to point at the entrypoint, got: %v", err)
+		}
+	})
+
+	t.Run("ConflictingLocations", func(t *testing.T) {
+		_, err := New(ServerConfig{DatabaseOptions: map[string]string{
+			"driver":     "duckdb",
+			"entrypoint": duckDBEntrypoint,
+			"path":       "first.db",
+			"uri":        "second.db",
+		}})
+		if err == nil || !strings.Contains(err.Error(), "path") || !strings.Contains(err.Error(), "uri") {
+			t.Errorf("Expected error to point at the path and uri options, got: %v", err)
+		}
+	})
+
+	// Consistent options are left to the driver
+	accepted := map[string]map[string]string{
+		"MissingEntrypoint": {"driver": "duckdb", "path": ":memory:"},
+		"URI":               {"driver": "duckdb", "entrypoint": duckDBEntrypoint, "uri": ":memory:"},
+		"SameLocation":      {"driver": "duckdb", "path": ":memory:", "uri": ":memory:"},
+	}
+	for name, options := range accepted {
+		t.Run(name, func(t *testing.T) {
+			if err := (ServerConfig{DatabaseOptions: options}).checkDuckDBOptions(); err != nil {
+				t.Errorf("Expected the options to be accepted, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_SQLiteWriteSettings(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, err := New(ServerConfig{
+		DatabaseOptions:   testDatabaseOptions(driver),
+		SQLiteBusyTimeout: 5 * time.Second,
+		SQLiteJournalMode: "wal",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	execTestSQL(t, server, `CREATE TABLE writes (writer INTEGER, n INTEGER)`)
+
+	const writers, inserts = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			conn, err := server.openConnection(context.Background())
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+
+			stmt, err := conn.NewStatement()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer stmt.Close()
+
+			for n := 0; n < inserts; n++ {
+				if err := stmt.SetSqlQuery(fmt.Sprintf("INSERT INTO writes VALUES (%d, %d)", w, n)); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := stmt.ExecuteUpdate(context.Background()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Expected concurrent inserts to wait for the lock, got: %v", err)
+	}
+
+	// WAL is stored in the database file, so a raw connection sees it too
+	conn, err := (*server.db).Open(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+
+	var values []string
+	for _, query := range []string{"PRAGMA journal_mode", "SELECT COUNT(*) FROM writes"} {
+		stmt, err := conn.NewStatement()
+		if err != nil {
+			t.Fatalf("Failed to create statement: %v", err)
+		}
+		if err := stmt.SetSqlQuery(query); err != nil {
+			t.Fatalf("Failed to set query %q: %v", query, err)
+		}
+		reader, _, err := stmt.ExecuteQuery(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to run %q: %v", query, err)
+		}
+		for reader.Next() {
+			values = append(values, reader.RecordBatch().Column(0).ValueStr(0))
+		}
+		reader.Release()
+		stmt.Close()
+	}
+	if want := []string{"wal", strconv.Itoa(writers * inserts)}; !slices.Equal(values, want) {
+		t.Errorf("Expected journal mode and row count %v, got %v", want, values)
+	}
+
+	t.Run("OtherDriver", func(t *testing.T) {
+		_, err := New(ServerConfig{
+			DatabaseOptions:   testDatabaseOptions(getTestDrivers(t)[1]),
+			SQLiteJournalMode: "wal",
+		})
+		if err == nil || !strings.Contains(err.Error(), "SQLite driver") {
+			t.Errorf("Expected SQLite settings to be rejected for DuckDB, got: %v", err)
+		}
+	})
+}
+
+func TestResolveDatabaseOptions(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("DB_USER", "env-user")
+
+	secretsFile := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(secretsFile, []byte(`{"DB_USER": "file-user"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+
+	cfg := ServerConfig{
+		DatabaseOptions: map[string]string{
+			"driver":   "adbc_driver_postgresql",
+			"password": "${DB_PASSWORD}",
+			"uri":      "postgresql://${DB_USER}@localhost/db",
+		},
+		SecretsFile: secretsFile,
+	}
+	resolved, err := cfg.resolveDatabaseOptions()
+	if err != nil {
+		t.Fatalf("Failed to resolve database options: %v", err)
+	}
+	if resolved["password"] != "from-env" {
+		t.Errorf("Expected the password from the environment, got %q", resolved["password"])
+	}
+	// The secrets file takes precedence over the environment
+	if resolved["uri"] != "postgresql://file-user@localhost/db" {
+		t.Errorf("Expected the user from the secrets file, got %q", resolved["uri"])
+	}
+	if cfg.DatabaseOptions["password"] != "${DB_PASSWORD}" {
+		t.Errorf("Expected the configured options to be left alone, got %q", cfg.DatabaseOptions["password"])
+	}
+
+	cfg.DatabaseOptions["password"] = "${DB_MISSING_PASSWORD}"
+	_, err = New(cfg)
+	if err == nil {
+		t.Fatal("Expected New to fail for an unset variable")
+	}
+	if !strings.Contains(err.Error(), "DB_MISSING_PASSWORD") || !strings.Contains(err.Error(), `"password"`) {
+		t.Errorf("Expected the error to name the option and the variable, got: %v", err)
+	}
+}
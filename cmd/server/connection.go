@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// openConnection returns a backend connection with the configured defaults
+// applied, from the pool when pooling is enabled. Closing it releases it.
+// Sessions with their own catalog or schema get a fresh connection, pooled
+// ones start in the defaults.
+func (s *DummyFlightSQLServer) openConnection(ctx context.Context) (adbc.Connection, error) {
+	if s.pool != nil && !hasSessionState(ctx) {
+		return s.pool.acquire(ctx)
+	}
+	return s.newConnection(ctx)
+}
+
+// newConnection opens a backend connection with the configured defaults
+// applied, bypassing the pool
+func (s *DummyFlightSQLServer) newConnection(ctx context.Context) (adbc.Connection, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	conn, err := (*s.db).Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyConnectionDefaults(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to apply default catalog/schema: %w", err)
+	}
+	if err := s.applySQLitePragmas(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to apply SQLite settings: %w", err)
+	}
+	if err := s.applySessionBackendOptions(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to apply session backend option %w", err)
+	}
+	return conn, nil
+}
+
+// applySQLitePragmas sets the configured busy timeout and journal mode on a
+// SQLite connection. They are per connection settings (WAL is remembered
+// in the file, setting it again is a no-op), so every new connection needs
+// them.
+func (s *DummyFlightSQLServer) applySQLitePragmas(ctx context.Context, conn adbc.Connection) error {
+	var pragmas []string
+	if timeout := s.cfg.SQLiteBusyTimeout; timeout > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA busy_timeout = %d", timeout.Milliseconds()))
+	}
+	if mode := s.cfg.SQLiteJournalMode; mode != "" {
+		pragmas = append(pragmas, "PRAGMA journal_mode = "+strings.ToUpper(mode))
+	}
+	if len(pragmas) == 0 {
+		return nil
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, pragma := range pragmas {
+		if err := stmt.SetSqlQuery(pragma); err != nil {
+			return err
+		}
+		// Both pragmas report the new value as a result row
+		reader, _, err := stmt.ExecuteQuery(ctx)
+		if err != nil {
+			return err
+		}
+		for reader.Next() {
+		}
+		err = reader.Err()
+		reader.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyConnectionDefaults makes unqualified names on conn resolve against
+// the session's catalog and schema, or the configured defaults. The
+// standard ADBC options are tried first, drivers that don't support them
+// get a USE statement.
+func (s *DummyFlightSQLServer) applyConnectionDefaults(ctx context.Context, conn adbc.Connection) error {
+	catalog, schema := s.connectionNamespace(ctx)
+	if catalog == "" && schema == "" {
+		return nil
+	}
+
+	if opts, ok := conn.(adbc.PostInitOptions); ok {
+		var err error
+		if catalog != "" {
+			err = opts.SetOption(adbc.OptionKeyCurrentCatalog, catalog)
+		}
+		if err == nil && schema != "" {
+			err = opts.SetOption(adbc.OptionKeyCurrentDbSchema, schema)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+
+	target := s.qualifiedName(catalog, schema)
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("USE " + target); err != nil {
+		return err
+	}
+	_, err = stmt.ExecuteUpdate(ctx)
+	return err
+}
+
+// resolveCatalog maps a metadata catalog filter onto the backend. Nil keeps
+// matching every catalog, while "" selects the tables without an explicit
+// catalog, which for both SQLite and DuckDB means the connection's current
+// catalog. Other names are case folded with IdentifierCase.
+func (s *DummyFlightSQLServer) resolveCatalog(ctx context.Context, conn adbc.Connection, catalog *string) (*string, error) {
+	if catalog == nil || *catalog != "" {
+		return s.foldIdentifier(catalog), nil
+	}
+
+	current, err := currentCatalog(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the default catalog: %w", err)
+	}
+	return &current, nil
+}
+
+// currentCatalog returns the catalog unqualified names resolve against
+func currentCatalog(ctx context.Context, conn adbc.Connection) (string, error) {
+	if opts, ok := unwrapConnection(conn).(adbc.GetSetOptions); ok {
+		if name, err := opts.GetOption(adbc.OptionKeyCurrentCatalog); err == nil && name != "" {
+			return name, nil
+		}
+	}
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	if vendor == vendorSQLite {
+		return "main", nil
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("SELECT current_database()"); err != nil {
+		return "", err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		if rec.NumRows() > 0 {
+			if col, ok := rec.Column(0).(*array.String); ok && col.IsValid(0) {
+				return col.Value(0), nil
+			}
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("backend did not report a current catalog")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pageMetadata is attached to the trailing empty batch of every page.
+// More is false on the last page, after which the ticket is consumed.
+type pageMetadata struct {
+	Page int64 `json:"page"`
+	More bool  `json:"more"`
+}
+
+// cursor holds the open result of a paged statement between DoGet calls.
+// The connection is pinned to the cursor, since the result can only be
+// read on the connection that executed it, and is released once the
+// result is exhausted or the cursor expires.
+type cursor struct {
+	mu sync.Mutex // held while a page is streamed
+
+	conn     adbc.Connection
+	stmt     adbc.Statement
+	reader   array.RecordReader
+	schema   *arrow.Schema
+	redactor *columnRedactor
+	zoner    *timestampZoner
+	order    *columnOrder
+	pending  arrow.RecordBatch // rows read but not sent yet
+	pages    int64
+	expiry   *time.Timer // runs while the cursor waits for the next page
+	closed   bool
+}
+
+// fill reads the next non-empty batch into pending, unless it already
+// holds one, and reports whether rows are left
+func (c *cursor) fill() bool {
+	for c.pending == nil && c.reader.Next() {
+		if rec := c.reader.RecordBatch(); rec.NumRows() > 0 {
+			rec.Retain()
+			c.pending = rec
+		}
+	}
+	return c.pending != nil
+}
+
+// take returns up to limit pending rows
+func (c *cursor) take(limit int64) arrow.RecordBatch {
+	rec := c.pending
+	if rec.NumRows() <= limit {
+		c.pending = nil
+		return rec
+	}
+	c.pending = rec.NewSlice(limit, rec.NumRows())
+	head := rec.NewSlice(0, limit)
+	rec.Release()
+	return head
+}
+
+// release frees the result and gives the pinned connection back
+func (c *cursor) release() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if c.expiry != nil {
+		c.expiry.Stop()
+	}
+	if c.pending != nil {
+		c.pending.Release()
+		c.pending = nil
+	}
+	if c.reader != nil {
+		c.reader.Release()
+	}
+	if c.stmt != nil {
+		c.stmt.Close()
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// acquireCursor returns the cursor of a paged statement locked for the
+// next page, executing the statement on a newly pinned connection for the
+// first page
+func (s *DummyFlightSQLServer) acquireCursor(ctx context.Context, stored statementHandle) (*cursor, error) {
+	s.mu.Lock()
+	c, ok := s.cursors[stored.handle]
+	if !ok {
+		c = &cursor{}
+		s.cursors[stored.handle] = c
+	}
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, status.Errorf(codes.NotFound, "cursor of statement %s is closed", stored.handle)
+	}
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	if c.reader != nil {
+		return c, nil
+	}
+
+	if err := s.openCursor(ctx, c, stored.query); err != nil {
+		s.closeCursor(stored.handle, c)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return c, nil
+}
+
+// openCursor executes query on a connection pinned to c. The execution
+// outlives the DoGet call of the first page, so it is not canceled with it.
+func (s *DummyFlightSQLServer) openCursor(ctx context.Context, c *cursor, query string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var err error
+	if c.conn, err = s.openConnection(ctx); err != nil {
+		return err
+	}
+	if c.stmt, err = c.conn.NewStatement(); err != nil {
+		return err
+	}
+	if err := c.stmt.SetSqlQuery(query); err != nil {
+		return err
+	}
+	if c.reader, _, err = c.stmt.ExecuteQuery(ctx); err != nil {
+		return err
+	}
+
+	c.schema = c.reader.Schema()
+	c.order = s.newColumnOrder(c.schema)
+	if c.redactor = s.newColumnRedactor(query, c.schema); c.redactor != nil {
+		c.schema = c.redactor.schema
+	}
+	if c.zoner = s.newTimestampZoner(c.schema); c.zoner != nil {
+		c.schema = c.zoner.schema
+	}
+	c.schema = c.order.schema(c.schema)
+	return nil
+}
+
+// closeCursor releases c and removes it from the cursor store. c.mu must be
+// held.
+func (s *DummyFlightSQLServer) closeCursor(handle string, c *cursor) {
+	c.release()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cursors[handle] == c {
+		delete(s.cursors, handle)
+	}
+}
+
+// expireCursorAfter closes c and drops its statement handle when no page is
+// requested within CursorIdleTimeout. c.mu must be held.
+func (s *DummyFlightSQLServer) expireCursorAfter(handle string, c *cursor) {
+	timeout := s.cfg.CursorIdleTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// A page requested meanwhile replaced or cleared the timer
+		if c.closed || c.expiry != timer {
+			return
+		}
+		s.closeCursor(handle, c)
+		s.consumeStatement(handle)
+	})
+	c.expiry = timer
+}
+
+// streamPage streams the next page of a paged statement. Every DoGet of the
+// ticket returns the following pageSize rows, ending with an empty batch
+// whose pageMetadata tells whether more pages follow.
+func (s *DummyFlightSQLServer) streamPage(ctx context.Context, stored statementHandle) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	c, err := s.acquireCursor(ctx, stored)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan flight.StreamChunk)
+	go func() {
+		defer close(ch)
+		defer s.recoverStream(ch)
+		defer c.mu.Unlock()
+
+		fail := func(err error) {
+			s.closeCursor(stored.handle, c)
+			s.consumeStatement(stored.handle)
+			ch <- flight.StreamChunk{Err: err}
+		}
+
+		var sent int64
+		for sent < stored.pageSize && c.fill() {
+			rec := c.take(stored.pageSize - sent)
+			if c.redactor != nil {
+				rec = c.redactor.apply(rec)
+			}
+			if c.zoner != nil {
+				rec = c.zoner.apply(rec)
+			}
+			rec = c.order.apply(rec)
+			if err := checkCellSizes(rec, s.cfg.MaxCellBytes); err != nil {
+				rec.Release()
+				fail(err)
+				return
+			}
+			sent += rec.NumRows()
+			ch <- flight.StreamChunk{Data: rec}
+		}
+
+		more := c.fill()
+		if err := c.reader.Err(); err != nil {
+			fail(err)
+			return
+		}
+
+		c.pages++
+		metadata, _ := json.Marshal(pageMetadata{Page: c.pages, More: more})
+		bldr := array.NewRecordBuilder(s.Alloc, c.schema)
+		defer bldr.Release()
+		ch <- flight.StreamChunk{Data: bldr.NewRecordBatch(), AppMetadata: metadata}
+
+		if more {
+			s.expireCursorAfter(stored.handle, c)
+		} else {
+			s.closeCursor(stored.handle, c)
+			s.consumeStatement(stored.handle)
+		}
+	}()
+
+	return c.schema, ch, nil
+}
+
+// closeCursors releases every open cursor, for Close
+func (s *DummyFlightSQLServer) closeCursors() {
+	s.mu.Lock()
+	cursors := s.cursors
+	s.cursors = make(map[string]*cursor)
+	s.mu.Unlock()
+
+	for _, c := range cursors {
+		c.mu.Lock()
+		c.release()
+		c.mu.Unlock()
+	}
+}
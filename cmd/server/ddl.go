@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// ddlSchema is the result schema of the ExportDDL action
+var ddlSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "table_name", Type: arrow.BinaryTypes.String},
+	{Name: "ddl", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// tableDefinition is the part of a GetObjects table entry needed for DDL
+type tableDefinition struct {
+	catalog    string
+	schema     string
+	name       string
+	tableType  string
+	columns    []columnDefinition
+	primaryKey []string
+}
+
+type columnDefinition struct {
+	name     string
+	typeName string
+	notNull  bool
+}
+
+// exportDDL returns a CREATE TABLE statement for every table in the backend.
+// DuckDB reports the DDL it stores natively, other backends get it rebuilt
+// from GetObjects.
+func (s *DummyFlightSQLServer) exportDDL(ctx context.Context, body []byte) ([][]byte, error) {
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var names, statements []string
+	if vendor == vendorDuckDB {
+		names, statements, err = nativeDuckDBDDL(ctx, conn)
+	} else {
+		names, statements, err = reconstructDDL(ctx, conn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, ddlSchema)
+	defer bldr.Release()
+
+	bldr.Field(0).(*array.StringBuilder).AppendValues(names, nil)
+	bldr.Field(1).(*array.StringBuilder).AppendValues(statements, nil)
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
+
+// nativeDuckDBDDL reads the CREATE TABLE statements DuckDB keeps in its catalog
+func nativeDuckDBDDL(ctx context.Context, conn adbc.Connection) ([]string, []string, error) {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("SELECT table_name, sql FROM duckdb_tables() WHERE NOT internal ORDER BY schema_name, table_name"); err != nil {
+		return nil, nil, err
+	}
+
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Release()
+
+	var names, statements []string
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		nameCol := rec.Column(0).(*array.String)
+		sqlCol := rec.Column(1).(*array.String)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			names = append(names, nameCol.Value(i))
+			statements = append(statements, sqlCol.Value(i))
+		}
+	}
+	return names, statements, reader.Err()
+}
+
+// reconstructDDL builds CREATE TABLE statements from GetObjects at column depth
+func reconstructDDL(ctx context.Context, conn adbc.Connection) ([]string, []string, error) {
+	tables, err := tableDefinitions(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names, statements []string
+	for _, table := range tables {
+		if normalizeTableType(table.tableType) != "TABLE" {
+			continue
+		}
+
+		// Fall back to the Arrow schema for columns without a declared type
+		var schema *arrow.Schema
+		for i, col := range table.columns {
+			if col.typeName != "" {
+				continue
+			}
+			if schema == nil {
+				schema, err = conn.GetTableSchema(ctx, optionalName(table.catalog), optionalName(table.schema), table.name)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			if fields, ok := schema.FieldsByName(col.name); ok {
+				table.columns[i].typeName = sqlTypeName(fields[0].Type)
+			}
+		}
+
+		names = append(names, table.name)
+		statements = append(statements, createTableStatement(table))
+	}
+	return names, statements, nil
+}
+
+// tableDefinitions flattens GetObjects at column depth into table definitions
+func tableDefinitions(ctx context.Context, conn adbc.Connection) ([]tableDefinition, error) {
+	reader, err := conn.GetObjects(ctx, adbc.ObjectDepthAll, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, metadataError(err)
+	}
+	defer reader.Release()
+
+	var tables []tableDefinition
+	for reader.Next() {
+		rec := reader.RecordBatch()
+
+		catalogNameCol := rec.Column(0).(*array.String)
+		schemasCol := rec.Column(1).(*array.List)
+		schemaValues := schemasCol.ListValues().(*array.Struct)
+		schemaNameCol := schemaValues.Field(0).(*array.String)
+		tablesCol := schemaValues.Field(1).(*array.List)
+		tableValues := tablesCol.ListValues().(*array.Struct)
+		tableNameCol := tableValues.Field(0).(*array.String)
+		tableTypeCol := tableValues.Field(1).(*array.String)
+		columnsCol := tableValues.Field(2).(*array.List)
+		columnValues := columnsCol.ListValues().(*array.Struct)
+		columnNameCol := columnValues.Field(0).(*array.String)
+		typeNameCol := columnValues.Field(4).(*array.String)
+		nullableCol := columnValues.Field(8).(*array.Int16)
+		isNullableCol := columnValues.Field(13).(*array.String)
+		constraintsCol := tableValues.Field(3).(*array.List)
+		constraintValues := constraintsCol.ListValues().(*array.Struct)
+		constraintTypeCol := constraintValues.Field(1).(*array.String)
+		constraintColumnsCol := constraintValues.Field(2).(*array.List)
+		constraintColumnNames := constraintColumnsCol.ListValues().(*array.String)
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			schemaStart, schemaEnd := schemasCol.ValueOffsets(i)
+			for j := schemaStart; j < schemaEnd; j++ {
+				tableStart, tableEnd := tablesCol.ValueOffsets(int(j))
+				for k := tableStart; k < tableEnd; k++ {
+					table := tableDefinition{
+						catalog:   catalogNameCol.Value(i),
+						schema:    schemaNameCol.Value(int(j)),
+						name:      tableNameCol.Value(int(k)),
+						tableType: tableTypeCol.Value(int(k)),
+					}
+
+					columnStart, columnEnd := columnsCol.ValueOffsets(int(k))
+					for c := int(columnStart); c < int(columnEnd); c++ {
+						notNull := (nullableCol.IsValid(c) && nullableCol.Value(c) == 0) ||
+							(isNullableCol.IsValid(c) && isNullableCol.Value(c) == "NO")
+						table.columns = append(table.columns, columnDefinition{
+							name:     columnNameCol.Value(c),
+							typeName: typeNameCol.Value(c),
+							notNull:  notNull,
+						})
+					}
+
+					constraintStart, constraintEnd := constraintsCol.ValueOffsets(int(k))
+					for c := int(constraintStart); c < int(constraintEnd); c++ {
+						if constraintTypeCol.Value(c) != "PRIMARY KEY" {
+							continue
+						}
+						nameStart, nameEnd := constraintColumnsCol.ValueOffsets(c)
+						for n := int(nameStart); n < int(nameEnd); n++ {
+							table.primaryKey = append(table.primaryKey, constraintColumnNames.Value(n))
+						}
+					}
+
+					tables = append(tables, table)
+				}
+			}
+		}
+	}
+	return tables, reader.Err()
+}
+
+// createTableStatement renders the DDL for a table definition
+func createTableStatement(table tableDefinition) string {
+	var lines []string
+	for _, col := range table.columns {
+		line := "  " + quoteIdentifier(col.name) + " " + col.typeName
+		if col.notNull {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+	}
+
+	if len(table.primaryKey) > 0 {
+		quoted := make([]string, len(table.primaryKey))
+		for i, name := range table.primaryKey {
+			quoted[i] = quoteIdentifier(name)
+		}
+		lines = append(lines, "  PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+
+	name := quoteIdentifier(table.name)
+	if table.schema != "" {
+		name = quoteIdentifier(table.schema) + "." + name
+	}
+	return "CREATE TABLE " + name + " (\n" + strings.Join(lines, ",\n") + "\n);"
+}
+
+// quoteIdentifier quotes a SQL identifier with double quotes
+func quoteIdentifier(name string) string {
+	return quoteIdentifierWith(`"`, name)
+}
+
+// sqlTypeName maps an Arrow type to a portable SQL type name
+func sqlTypeName(dt arrow.DataType) string {
+	switch dt := dt.(type) {
+	case *arrow.Int8Type:
+		return "TINYINT"
+	case *arrow.Int16Type:
+		return "SMALLINT"
+	case *arrow.Int32Type:
+		return "INTEGER"
+	case *arrow.Int64Type:
+		return "BIGINT"
+	case *arrow.Float32Type:
+		return "REAL"
+	case *arrow.Float64Type:
+		return "DOUBLE"
+	case *arrow.BooleanType:
+		return "BOOLEAN"
+	case *arrow.BinaryType, *arrow.LargeBinaryType:
+		return "BLOB"
+	case *arrow.Date32Type, *arrow.Date64Type:
+		return "DATE"
+	case *arrow.TimestampType:
+		return "TIMESTAMP"
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("DECIMAL(%d, %d)", dt.Precision, dt.Scale)
+	default:
+		return "TEXT"
+	}
+}
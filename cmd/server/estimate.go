@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// estimateSchema is the result schema of the EstimateQuery action. Both
+// values are -1 when the backend gives no estimate.
+var estimateSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "estimated_rows", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "estimated_cost", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+// duckDBPlanNode is an operator of DuckDB's EXPLAIN (FORMAT JSON) output
+type duckDBPlanNode struct {
+	Children  []duckDBPlanNode `json:"children"`
+	ExtraInfo map[string]any   `json:"extra_info"`
+}
+
+// cardinality returns the optimizer's row estimate for the node's output
+func (n duckDBPlanNode) cardinality() (int64, bool) {
+	value, ok := n.ExtraInfo["Estimated Cardinality"].(string)
+	if !ok {
+		return 0, false
+	}
+	rows, err := strconv.ParseInt(value, 10, 64)
+	return rows, err == nil
+}
+
+// duckDBEstimate returns the estimated rows of the root operator that has
+// one, and the sum of the estimates of all operators as the cost, which is
+// what DuckDB's own cost model uses to order joins
+func duckDBEstimate(nodes []duckDBPlanNode) (rows int64, cost float64) {
+	rows = unknownRowCount
+	var walk func(nodes []duckDBPlanNode)
+	walk = func(nodes []duckDBPlanNode) {
+		for _, node := range nodes {
+			if n, ok := node.cardinality(); ok {
+				if rows == unknownRowCount {
+					rows = n
+				}
+				cost += float64(n)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+
+	if rows == unknownRowCount {
+		return unknownRowCount, -1
+	}
+	return rows, cost
+}
+
+// estimateQuery returns the backend's estimate of the rows and cost of the
+// query in the body, without running it. DuckDB reports its optimizer's
+// estimates; SQLite only checks that the query can be planned and reports
+// -1 for both.
+func (s *DummyFlightSQLServer) estimateQuery(ctx context.Context, body []byte) ([][]byte, error) {
+	query := strings.TrimSpace(string(body))
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "expected the query to estimate in the action body")
+	}
+	query, err := s.rewriteQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var explain string
+	switch vendor {
+	case vendorDuckDB:
+		explain = "EXPLAIN (FORMAT JSON) "
+	case vendorSQLite:
+		explain = explainPrefixes[vendorSQLite]
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "query estimates are not supported by %s", vendor)
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(explain + query); err != nil {
+		return nil, err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to explain query: %v", err)
+	}
+	defer reader.Release()
+
+	plan, err := readPlan(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, cost := int64(unknownRowCount), float64(-1)
+	if vendor == vendorDuckDB {
+		var nodes []duckDBPlanNode
+		if err := json.Unmarshal([]byte(strings.Join(plan, "")), &nodes); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to parse DuckDB query plan: %v", err)
+		}
+		rows, cost = duckDBEstimate(nodes)
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, estimateSchema)
+	defer bldr.Release()
+
+	bldr.Field(0).(*array.Int64Builder).Append(rows)
+	bldr.Field(1).(*array.Float64Builder).Append(cost)
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExchangeFormatJSONLines streams every result row as one JSON object
+const ExchangeFormatJSONLines = "json_lines"
+
+// exchangeRequest is the JSON command in the descriptor of the first
+// DoExchange message
+type exchangeRequest struct {
+	Query  string `json:"query"`
+	Format string `json:"format"`
+}
+
+// DoExchange runs the query named in the first message's descriptor through
+// the statement handlers and streams the results in the requested format.
+// In json_lines mode each row is sent as its own message, with the JSON
+// object followed by a newline in the app metadata, so consumers can
+// process rows as they arrive instead of waiting for a batch.
+func (f *flightService) DoExchange(stream flight.FlightService_DoExchangeServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	var req exchangeRequest
+	desc := first.GetFlightDescriptor()
+	if desc == nil || desc.Type != flight.DescriptorCMD || json.Unmarshal(desc.Cmd, &req) != nil || req.Query == "" {
+		return status.Error(codes.InvalidArgument, `expected a command descriptor with a JSON {"query": ..., "format": ...} body`)
+	}
+	if req.Format != ExchangeFormatJSONLines {
+		return status.Errorf(codes.InvalidArgument, "unsupported exchange format %q, expected %q", req.Format, ExchangeFormatJSONLines)
+	}
+
+	schema, ch, err := f.srv.executeStatement(stream.Context(), req.Query)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]string, schema.NumFields())
+	for i, field := range schema.Fields() {
+		columns[i] = field.Name
+	}
+
+	// Keep draining after an error so the streaming goroutine can finish
+	var exchangeErr error
+	for chunk := range ch {
+		if chunk.Err != nil && exchangeErr == nil {
+			exchangeErr = chunk.Err
+		}
+		if chunk.Data == nil {
+			continue
+		}
+		for i := 0; exchangeErr == nil && i < int(chunk.Data.NumRows()); i++ {
+			row := make(map[string]any, len(columns))
+			for c, col := range chunk.Data.Columns() {
+				row[columns[c]] = col.GetOneForMarshal(i)
+			}
+			line, err := json.Marshal(row)
+			if err != nil {
+				exchangeErr = err
+				break
+			}
+			exchangeErr = stream.Send(&flight.FlightData{AppMetadata: append(line, '\n')})
+		}
+		chunk.Data.Release()
+	}
+	return exchangeErr
+}
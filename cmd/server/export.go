@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultExportRowsPerFile is the Parquet file size used when the
+// ExportParquet request doesn't set rows_per_file
+const defaultExportRowsPerFile = 1 << 20
+
+// exportParquetRequest is the JSON body of the ExportParquet action
+type exportParquetRequest struct {
+	Query string `json:"query"`
+	// Path is the target directory, relative to ExportDir
+	Path        string `json:"path"`
+	RowsPerFile int64  `json:"rows_per_file"`
+}
+
+// exportParquet runs the query through the statement handlers and writes the
+// results to part-NNNNN.parquet files of at most rows_per_file rows in the
+// target directory. One result with a file:// URI is returned per file.
+func (s *DummyFlightSQLServer) exportParquet(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.cfg.ExportDir == "" {
+		return nil, status.Error(codes.FailedPrecondition, "Parquet export is disabled")
+	}
+
+	var req exportParquetRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" || req.Path == "" {
+		return nil, status.Error(codes.InvalidArgument, `expected a JSON body with "query" and "path" fields`)
+	}
+	if req.RowsPerFile < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "rows_per_file must not be negative, got %d", req.RowsPerFile)
+	}
+	rowsPerFile := req.RowsPerFile
+	if rowsPerFile == 0 {
+		rowsPerFile = defaultExportRowsPerFile
+	}
+
+	dir, err := s.exportTarget(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	schema, ch, err := s.executeStatement(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &parquetPartWriter{dir: dir, schema: schema, rowsPerFile: rowsPerFile, alloc: s.Alloc}
+	// Keep draining after an error so the streaming goroutine can finish
+	var exportErr error
+	for chunk := range ch {
+		if chunk.Err != nil && exportErr == nil {
+			exportErr = chunk.Err
+		}
+		if chunk.Data == nil {
+			continue
+		}
+		if exportErr == nil {
+			exportErr = w.write(chunk.Data)
+		}
+		chunk.Data.Release()
+	}
+	if exportErr == nil {
+		exportErr = w.close()
+	}
+	if exportErr != nil {
+		w.abort()
+		return nil, exportErr
+	}
+
+	results := make([][]byte, 0, len(w.files))
+	for _, file := range w.files {
+		results = append(results, []byte((&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()))
+	}
+	return results, nil
+}
+
+// exportTarget resolves the requested directory below ExportDir, rejecting
+// paths that would leave it
+func (s *DummyFlightSQLServer) exportTarget(path string) (string, error) {
+	root, err := filepath.Abs(s.cfg.ExportDir)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(path) {
+		return "", status.Errorf(codes.InvalidArgument, "export path %q must be relative to the export directory", path)
+	}
+
+	dir := filepath.Join(root, path)
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", status.Errorf(codes.InvalidArgument, "export path %q is outside the export directory", path)
+	}
+	return dir, nil
+}
+
+// parquetPartWriter spreads record batches over numbered Parquet files
+type parquetPartWriter struct {
+	dir         string
+	schema      *arrow.Schema
+	rowsPerFile int64
+	alloc       memory.Allocator
+
+	files  []string
+	writer *pqarrow.FileWriter
+	rows   int64 // rows in the current file
+}
+
+// write appends rec, starting a new file whenever the current one is full
+func (w *parquetPartWriter) write(rec arrow.RecordBatch) error {
+	for offset := int64(0); offset < rec.NumRows(); {
+		if w.writer == nil || w.rows >= w.rowsPerFile {
+			if err := w.next(); err != nil {
+				return err
+			}
+		}
+
+		n := min(rec.NumRows()-offset, w.rowsPerFile-w.rows)
+		part := rec.NewSlice(offset, offset+n)
+		err := w.writer.Write(part)
+		part.Release()
+		if err != nil {
+			return err
+		}
+		w.rows += n
+		offset += n
+	}
+	return nil
+}
+
+// next closes the current file and opens the following one
+func (w *parquetPartWriter) next() error {
+	if err := w.closeCurrent(); err != nil {
+		return err
+	}
+
+	name := filepath.Join(w.dir, fmt.Sprintf("part-%05d.parquet", len(w.files)))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w.files = append(w.files, name)
+
+	props := parquet.NewWriterProperties(parquet.WithAllocator(w.alloc))
+	writer, err := pqarrow.NewFileWriter(w.schema, f, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.writer, w.rows = writer, 0
+	return nil
+}
+
+// closeCurrent finishes the open file, if any. Closing the Parquet writer
+// closes the file as well.
+func (w *parquetPartWriter) closeCurrent() error {
+	if w.writer == nil {
+		return nil
+	}
+	err := w.writer.Close()
+	w.writer = nil
+	return err
+}
+
+// close finishes the export. Empty results still get one file, so readers
+// learn the schema.
+func (w *parquetPartWriter) close() error {
+	if len(w.files) == 0 {
+		if err := w.next(); err != nil {
+			return err
+		}
+	}
+	return w.closeCurrent()
+}
+
+// abort removes the files of a failed export
+func (w *parquetPartWriter) abort() {
+	if w.writer != nil {
+		w.writer.Close()
+		w.writer = nil
+	}
+	for _, file := range w.files {
+		os.Remove(file)
+	}
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errInjected is the error returned by the faults of a faultyDatabase
+var errInjected = errors.New("injected fault")
+
+// faultyDatabase wraps a backend with deterministic faults for exercising
+// error paths. The zero value of every setting disables its fault.
+type faultyDatabase struct {
+	adbc.Database
+
+	// failOpen makes Open fail with errInjected. It may change while the
+	// backend is in use, like openDelay.
+	failOpen atomic.Bool
+	// openDelay is slept before Open, in nanoseconds
+	openDelay atomic.Int64
+	// failAfterBatches makes query readers fail with errInjected once they
+	// returned this many batches. Zero or less never fails.
+	failAfterBatches int
+	// delay is slept before ExecuteQuery runs, unless ctx ends first
+	delay time.Duration
+	// firstBatchDelay is slept before query readers return their first
+	// batch, as when the backend computes the result lazily
+	firstBatchDelay time.Duration
+	// failExecutions makes this many executions fail with a transient
+	// StatusIO error before they succeed again. Schema probes don't count.
+	failExecutions atomic.Int32
+	// missingSubstrait accepts Substrait plans but fails to run them like a
+	// DuckDB build without the substrait extension
+	missingSubstrait bool
+	// warnings are reported by every statement in place of the backend's
+	warnings []string
+	// tracker follows every batch that query readers return
+	tracker *batchTracker
+	// mismatchedBatches makes query readers return batches with a schema
+	// other than the one they advertise
+	mismatchedBatches bool
+	// driftingProbes makes schema probes report their first column as a
+	// string, like an engine whose wrapped query types differ from the raw
+	// query's
+	driftingProbes bool
+	// getObjects replaces the backend's GetObjects when set
+	getObjects func() (array.RecordReader, error)
+
+	// executed counts the query executions, leaving out the WHERE 1=0
+	// schema probes run by GetFlightInfoStatement
+	executed atomic.Int64
+	// open counts the backend connections that are open
+	open atomic.Int64
+	// rollbacks counts the rollbacks on the backend connections
+	rollbacks atomic.Int64
+}
+
+// errMissingSubstrait is DuckDB's error for a plan run without the
+// substrait extension
+var errMissingSubstrait = adbc.Error{
+	Code: adbc.StatusInternal,
+	Msg:  "Catalog Error: Table Function with name from_substrait does not exist!",
+}
+
+// transientFault fails the execution while failExecutions lasts
+func (d *faultyDatabase) transientFault() error {
+	if d.failExecutions.Add(-1) < 0 {
+		d.failExecutions.Store(0)
+		return nil
+	}
+	return adbc.Error{Code: adbc.StatusIO, Msg: errInjected.Error()}
+}
+
+func (d *faultyDatabase) Open(ctx context.Context) (adbc.Connection, error) {
+	time.Sleep(time.Duration(d.openDelay.Load()))
+	if d.failOpen.Load() {
+		return nil, errInjected
+	}
+	conn, err := d.Database.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.open.Add(1)
+	return &faultyConnection{Connection: conn, db: d}, nil
+}
+
+type faultyConnection struct {
+	adbc.Connection
+	db *faultyDatabase
+}
+
+func (c *faultyConnection) Close() error {
+	c.db.open.Add(-1)
+	return c.Connection.Close()
+}
+
+func (c *faultyConnection) Rollback(ctx context.Context) error {
+	c.db.rollbacks.Add(1)
+	return c.Connection.Rollback(ctx)
+}
+
+// SetOption forwards autocommit changes, which the embedded interface hides
+func (c *faultyConnection) SetOption(key, value string) error {
+	return c.Connection.(adbc.PostInitOptions).SetOption(key, value)
+}
+
+func (c *faultyConnection) GetObjects(ctx context.Context, depth adbc.ObjectDepth, catalog, dbSchema, tableName, columnName *string, tableType []string) (array.RecordReader, error) {
+	if c.db.getObjects != nil {
+		return c.db.getObjects()
+	}
+	return c.Connection.GetObjects(ctx, depth, catalog, dbSchema, tableName, columnName, tableType)
+}
+
+func (c *faultyConnection) NewStatement() (adbc.Statement, error) {
+	stmt, err := c.Connection.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &faultyStatement{Statement: stmt, db: c.db}, nil
+}
+
+type faultyStatement struct {
+	adbc.Statement
+	db        *faultyDatabase
+	query     string
+	substrait bool // a Substrait plan was set
+}
+
+// probe reports whether the statement is a schema probe
+func (s *faultyStatement) probe() bool {
+	return strings.HasSuffix(s.query, "WHERE 1=0")
+}
+
+func (s *faultyStatement) SetSqlQuery(query string) error {
+	s.query = query
+	return s.Statement.SetSqlQuery(query)
+}
+
+func (s *faultyStatement) SetSubstraitPlan(plan []byte) error {
+	if s.db.missingSubstrait {
+		s.substrait = true
+		return nil
+	}
+	return s.Statement.SetSubstraitPlan(plan)
+}
+
+func (s *faultyStatement) Warnings() []string {
+	if s.db.warnings != nil {
+		return s.db.warnings
+	}
+	return statementWarnings(s.Statement)
+}
+
+func (s *faultyStatement) ExecuteQuery(ctx context.Context) (array.RecordReader, int64, error) {
+	if !s.probe() {
+		s.db.executed.Add(1)
+	}
+	if s.db.delay > 0 {
+		select {
+		case <-time.After(s.db.delay):
+		case <-ctx.Done():
+			return nil, -1, ctx.Err()
+		}
+	}
+
+	if s.substrait {
+		return nil, -1, errMissingSubstrait
+	}
+	if !s.probe() {
+		if err := s.db.transientFault(); err != nil {
+			return nil, -1, err
+		}
+	}
+	reader, n, err := s.Statement.ExecuteQuery(ctx)
+	if err != nil {
+		return reader, n, err
+	}
+	if s.db.driftingProbes && s.probe() {
+		defer reader.Release()
+		fields := reader.Schema().Fields()
+		fields[0].Type = arrow.BinaryTypes.String
+		drifted, err := array.NewRecordReader(arrow.NewSchema(fields, nil), nil)
+		return drifted, 0, err
+	}
+	if s.db.tracker != nil {
+		reader = &trackingReader{RecordReader: reader, tracker: s.db.tracker}
+	}
+	if s.db.mismatchedBatches {
+		reader = &mismatchedReader{RecordReader: reader}
+	}
+	if s.db.failAfterBatches <= 0 && s.db.firstBatchDelay <= 0 {
+		return reader, n, nil
+	}
+	remaining := s.db.failAfterBatches
+	if remaining <= 0 {
+		remaining = -1
+	}
+	return &faultyReader{RecordReader: reader, remaining: remaining, firstBatchDelay: s.db.firstBatchDelay}, n, nil
+}
+
+func (s *faultyStatement) ExecuteUpdate(ctx context.Context) (int64, error) {
+	if s.substrait {
+		return -1, errMissingSubstrait
+	}
+	if err := s.db.transientFault(); err != nil {
+		return -1, err
+	}
+	return s.Statement.ExecuteUpdate(ctx)
+}
+
+// faultyReader fails with errInjected after remaining batches, unless
+// remaining is negative, and sleeps firstBatchDelay before the first one
+type faultyReader struct {
+	array.RecordReader
+	remaining       int
+	firstBatchDelay time.Duration
+	err             error
+}
+
+func (r *faultyReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.firstBatchDelay > 0 {
+		time.Sleep(r.firstBatchDelay)
+		r.firstBatchDelay = 0
+	}
+	if r.remaining == 0 {
+		r.err = errInjected
+		return false
+	}
+	r.remaining--
+	return r.RecordReader.Next()
+}
+
+func (r *faultyReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.RecordReader.Err()
+}
+
+// useFaultyDatabase replaces the server's backend with a faulty wrapper
+func useFaultyDatabase(server *DummyFlightSQLServer, faulty *faultyDatabase) {
+	faulty.Database = *server.db
+	var db adbc.Database = faulty
+	server.db = &db
+}
+
+// prepareStatementTicket runs GetFlightInfoStatement for query and returns
+// the ticket of its endpoint
+func prepareStatementTicket(t *testing.T, server *DummyFlightSQLServer, query string) flightsql.StatementQueryTicket {
+	t.Helper()
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+	flightInfo, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: query}, desc)
+	if err != nil {
+		t.Fatalf("GetFlightInfoStatement failed: %v", err)
+	}
+	ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+	if err != nil {
+		t.Fatalf("Failed to parse statement ticket: %v", err)
+	}
+	return ticket
+}
+
+func TestFaultyDatabase_OpenFailure(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			faulty := &faultyDatabase{}
+			faulty.failOpen.Store(true)
+			useFaultyDatabase(server, faulty)
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			_, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: "SELECT 1"}, desc)
+			if err == nil || !strings.Contains(err.Error(), errInjected.Error()) {
+				t.Fatalf("Expected the open failure to be reported for %s, got %v", driver.name, err)
+			}
+
+			server.mu.Lock()
+			handles := len(server.queries)
+			server.mu.Unlock()
+			if handles != 0 {
+				t.Errorf("Expected no statement handle after the failure for %s, got %d", driver.name, handles)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_MidStreamError(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ticket := prepareStatementTicket(t, server, multiBatchQuery)
+			useFaultyDatabase(server, &faultyDatabase{failAfterBatches: 1})
+
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var batches int
+			var streamErr error
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				batches++
+				chunk.Data.Release()
+			}
+			if batches != 1 {
+				t.Errorf("Expected exactly one batch before the fault for %s, got %d", driver.name, batches)
+			}
+			if streamErr == nil || !strings.Contains(streamErr.Error(), errInjected.Error()) {
+				t.Errorf("Expected the stream to end with the injected fault for %s, got %v", driver.name, streamErr)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_MidStreamErrorAfterPartialBatch(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// Larger than the backend batches, so the fault arrives while
+			// rows are still pending
+			server.cfg.BatchRows = 5000
+			ticket := prepareStatementTicket(t, server, multiBatchQuery)
+			useFaultyDatabase(server, &faultyDatabase{failAfterBatches: 1})
+
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var rows int64
+			var streamErr error
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				if streamErr != nil {
+					t.Errorf("Expected no batches after the fault for %s", driver.name)
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if rows == 0 || rows >= 5000 {
+				t.Errorf("Expected the partial batch before the fault for %s, got %d rows", driver.name, rows)
+			}
+			if streamErr == nil || !strings.Contains(streamErr.Error(), errInjected.Error()) {
+				t.Errorf("Expected the stream to end with the injected fault for %s, got %v", driver.name, streamErr)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_SlowQueryCanceled(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ticket := prepareStatementTicket(t, server, "SELECT 1")
+			useFaultyDatabase(server, &faultyDatabase{delay: time.Minute})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, streamCh, err := server.DoGetStatement(ctx, ticket)
+			if err == nil {
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						err = chunk.Err
+						continue
+					}
+					chunk.Data.Release()
+				}
+			}
+			if err == nil {
+				t.Fatalf("Expected the slow query to be canceled for %s", driver.name)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("Expected the cancellation to end the query promptly for %s, took %s", driver.name, elapsed)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_TransientErrorRetried(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			server.cfg.ReadRetries = 2
+			server.cfg.RetryBackoff = time.Millisecond
+
+			ticket := prepareStatementTicket(t, server, "SELECT name FROM test_table ORDER BY id")
+			faulty := &faultyDatabase{}
+			faulty.failExecutions.Store(1)
+			useFaultyDatabase(server, faulty)
+
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("Expected the transient error to be retried for %s, got %v", driver.name, err)
+			}
+
+			var rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if rows != 3 {
+				t.Errorf("Expected 3 rows after the retry for %s, got %d", driver.name, rows)
+			}
+
+			// Without retries the same fault reaches the client
+			server.cfg.ReadRetries = 0
+			faulty.failExecutions.Store(1)
+			if _, err := server.executeUpdate(context.Background(), "DELETE FROM test_table WHERE id = 1", nil); err == nil {
+				t.Errorf("Expected the transient error to fail the update without retries for %s", driver.name)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_HeartbeatBeforeFirstBatch(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			server.cfg.HeartbeatInterval = 20 * time.Millisecond
+
+			ticket := prepareStatementTicket(t, server, "SELECT * FROM test_table")
+			useFaultyDatabase(server, &faultyDatabase{firstBatchDelay: 200 * time.Millisecond})
+
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var heartbeats, rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if string(chunk.AppMetadata) == string(heartbeatMetadata) {
+					if chunk.Data.NumRows() != 0 {
+						t.Errorf("Expected an empty heartbeat batch for %s, got %d rows", driver.name, chunk.Data.NumRows())
+					}
+					if rows > 0 {
+						t.Errorf("Expected no heartbeat after the first batch for %s", driver.name)
+					}
+					heartbeats++
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if heartbeats == 0 {
+				t.Errorf("Expected a heartbeat before the first batch for %s", driver.name)
+			}
+			if rows != 3 {
+				t.Errorf("Expected 3 rows for %s, got %d", driver.name, rows)
+			}
+		})
+	}
+}
+
+func TestFaultyDatabase_HeartbeatDuringSlowExecution(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			server.cfg.HeartbeatInterval = 20 * time.Millisecond
+
+			ticket := prepareStatementTicket(t, server, "SELECT * FROM test_table")
+			useFaultyDatabase(server, &faultyDatabase{delay: 200 * time.Millisecond})
+
+			// The stream starts while the statement is still running
+			start := time.Now()
+			schema, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+				t.Errorf("Expected DoGetStatement to return before the execution finished for %s, took %s", driver.name, elapsed)
+			}
+
+			var heartbeats, rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if !chunk.Data.Schema().Equal(schema) {
+					t.Errorf("Expected every batch to have the stream schema for %s, got %s", driver.name, chunk.Data.Schema())
+				}
+				if string(chunk.AppMetadata) == string(heartbeatMetadata) {
+					if rows > 0 {
+						t.Errorf("Expected no heartbeat after the first batch for %s", driver.name)
+					}
+					heartbeats++
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if heartbeats == 0 {
+				t.Errorf("Expected a heartbeat while the statement ran for %s", driver.name)
+			}
+			if rows != 3 {
+				t.Errorf("Expected 3 rows for %s, got %d", driver.name, rows)
+			}
+		})
+	}
+}
+
+// mockSubstraitPlan is a Substrait plan command outside of a transaction
+type mockSubstraitPlan struct {
+	plan []byte
+}
+
+func (m *mockSubstraitPlan) GetTransactionId() []byte { return nil }
+
+func (m *mockSubstraitPlan) GetPlan() flightsql.SubstraitPlan {
+	return flightsql.SubstraitPlan{Plan: m.plan, Version: "0.53.0"}
+}
+
+func TestFaultyDatabase_MissingSubstraitExtension(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			useFaultyDatabase(server, &faultyDatabase{missingSubstrait: true})
+
+			ctx := context.Background()
+			plan := &mockSubstraitPlan{plan: []byte("serialized plan")}
+			// friendly checks err is the Unimplemented hint to install the extension
+			friendly := func(method string, err error) {
+				if status.Code(err) != codes.Unimplemented || !strings.Contains(status.Convert(err).Message(), "INSTALL substrait") {
+					t.Errorf("Expected Unimplemented with an install hint from %s for %s, got %v", method, driver.name, err)
+				}
+			}
+
+			_, err := server.DoPutCommandSubstraitPlan(ctx, plan)
+			friendly("DoPutCommandSubstraitPlan", err)
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			info, err := server.GetFlightInfoSubstraitPlan(ctx, plan, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoSubstraitPlan failed for %s: %v", driver.name, err)
+			}
+			ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+			_, _, err = server.DoGetStatement(ctx, ticket)
+			friendly("DoGetStatement", err)
+		})
+	}
+}
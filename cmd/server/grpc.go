@@ -0,0 +1,29 @@
+package main
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcServerOptions returns the gRPC options derived from the server configuration
+func (s *DummyFlightSQLServer) grpcServerOptions() []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{s.recoveryUnaryInterceptor, s.queryLabelUnaryInterceptor, s.rateLimitUnaryInterceptor}
+	stream := []grpc.StreamServerInterceptor{s.recoveryStreamInterceptor, s.queryLabelStreamInterceptor, s.rateLimitStreamInterceptor}
+
+	if s.cfg.TLSClientCAFile != "" {
+		unary = append(unary, s.identityUnaryInterceptor)
+		stream = append(stream, s.identityStreamInterceptor)
+	}
+	if s.cfg.Compression != "" {
+		stream = append(stream, compressionInterceptor(s.cfg.Compression))
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	return opts
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkReady runs SELECT 1 against the backend. It fails when the query
+// fails or takes longer than ReadinessLatency.
+func (s *DummyFlightSQLServer) checkReady(ctx context.Context) error {
+	start := time.Now()
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("SELECT 1"); err != nil {
+		return err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	if limit := s.cfg.ReadinessLatency; limit > 0 {
+		if elapsed := time.Since(start); elapsed > limit {
+			return fmt.Errorf("readiness probe took %s, over the %s threshold", elapsed, limit)
+		}
+	}
+	return nil
+}
+
+// healthService is the gRPC health service. Every Check runs the readiness
+// probe and records the result, so Watch callers see changes as well.
+type healthService struct {
+	*health.Server
+	s *DummyFlightSQLServer
+}
+
+func newHealthService(s *DummyFlightSQLServer) *healthService {
+	return &healthService{Server: health.NewServer(), s: s}
+}
+
+func (h *healthService) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if err := h.s.checkReady(ctx); err != nil {
+		h.s.log().Warn("readiness probe failed", "error", err)
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.SetServingStatus("", servingStatus)
+	return h.Server.Check(ctx, req)
+}
+
+// handleReady serves GET /ready with 200 when the backend passes the
+// readiness probe and 503 otherwise
+func (s *DummyFlightSQLServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkReady(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
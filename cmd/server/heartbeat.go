@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// heartbeatMetadata marks the empty batches sent while a stream waits for
+// its first batch
+var heartbeatMetadata = []byte(`{"heartbeat":true}`)
+
+// heartbeat returns the result of fn, sending an empty batch of schema
+// carrying heartbeatMetadata on ch every interval until fn returns
+func heartbeat[T any](ch chan<- flight.StreamChunk, alloc memory.Allocator, schema *arrow.Schema, interval time.Duration, fn func() T) T {
+	done := make(chan T, 1)
+	go func() { done <- fn() }()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case result := <-done:
+			return result
+		case <-ticker.C:
+			bldr := array.NewRecordBuilder(alloc, schema)
+			ch <- flight.StreamChunk{Data: bldr.NewRecordBatch(), AppMetadata: heartbeatMetadata}
+			bldr.Release()
+		}
+	}
+}
+
+// heartbeatReader sends heartbeats on ch while the backend computes the
+// first batch, so that proxies don't drop the idle stream. Later batches
+// are read directly.
+type heartbeatReader struct {
+	array.RecordReader
+	schema   *arrow.Schema // of the stream, which may differ from the reader's
+	alloc    memory.Allocator
+	interval time.Duration
+	ch       chan<- flight.StreamChunk
+	started  bool
+}
+
+func (r *heartbeatReader) Next() bool {
+	if r.started {
+		return r.RecordReader.Next()
+	}
+	r.started = true
+	return heartbeat(r.ch, r.alloc, r.schema, r.interval, r.RecordReader.Next)
+}
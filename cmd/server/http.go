@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxHTTPQueryBytes bounds the body of a JSON query request
+const maxHTTPQueryBytes = 1 << 20
+
+// httpQueryRequest is the body accepted by the JSON query endpoint
+type httpQueryRequest struct {
+	Query string `json:"query"`
+}
+
+// httpQueryResponse holds the result rows keyed by column name
+type httpQueryResponse struct {
+	Columns []string         `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+// sqlQuery is a flightsql.StatementQuery outside of any transaction
+type sqlQuery string
+
+func (q sqlQuery) GetQuery() string         { return string(q) }
+func (q sqlQuery) GetTransactionId() []byte { return nil }
+
+// executeStatement plans query and starts streaming its results through the
+// Flight SQL statement handlers, for callers outside of Flight
+func (s *DummyFlightSQLServer) executeStatement(ctx context.Context, query string) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD}
+	info, err := s.GetFlightInfoStatement(ctx, sqlQuery(query), desc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, ch, err := s.DoGetStatement(ctx, ticket)
+	if err != nil {
+		// Nobody else holds the ticket to consume the handle
+		s.consumeStatement(string(ticket.GetStatementHandle()))
+		return nil, nil, err
+	}
+	return schema, ch, nil
+}
+
+// httpHandler serves POST /query for clients that can't speak Flight, and
+// the GET /ready readiness probe. The query is planned and executed through
+// the Flight SQL statement handlers, so rewriting, limits and caching apply
+// as for any other statement.
+func (s *DummyFlightSQLServer) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /query", s.handleHTTPQuery)
+	mux.HandleFunc("GET /ready", s.handleReady)
+	return s.httpInterceptor(mux)
+}
+
+// httpInterceptor applies the gRPC interceptor chain to HTTP requests: it
+// enforces the rate limit and passes the caller's certificate and
+// authorization header on the way the handlers expect them from gRPC, so
+// that identity based filters, auditing and admin checks work the same
+func (s *DummyFlightSQLServer) httpInterceptor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.Method + " " + r.URL.Path
+		if err := s.checkRateLimit(method); err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+
+		addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+		if err != nil {
+			addr = &net.TCPAddr{}
+		}
+		caller := &peer.Peer{Addr: addr}
+		if r.TLS != nil {
+			caller.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+		}
+		ctx := peer.NewContext(r.Context(), caller)
+		if auth := r.Header.Values("Authorization"); len(auth) > 0 {
+			ctx = metadata.NewIncomingContext(ctx, metadata.MD{"authorization": auth})
+		}
+
+		if s.cfg.TLSClientCAFile != "" {
+			s.log().Debug("call", "client", clientIdentity(ctx), "method", method)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *DummyFlightSQLServer) handleHTTPQuery(w http.ResponseWriter, r *http.Request) {
+	var req httpQueryRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHTTPQueryBytes)).Decode(&req)
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil || req.Query == "" {
+		http.Error(w, `expected a JSON body with a "query" field`, http.StatusBadRequest)
+		return
+	}
+
+	schema, ch, err := s.executeStatement(r.Context(), req.Query)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	resp := httpQueryResponse{Columns: make([]string, 0, schema.NumFields()), Rows: []map[string]any{}}
+	for _, field := range schema.Fields() {
+		resp.Columns = append(resp.Columns, field.Name)
+	}
+
+	// Keep draining after an error so the streaming goroutine can finish
+	var streamErr error
+	for chunk := range ch {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+			continue
+		}
+		if streamErr == nil {
+			for i := 0; i < int(chunk.Data.NumRows()); i++ {
+				row := make(map[string]any, len(resp.Columns))
+				for c, col := range chunk.Data.Columns() {
+					row[resp.Columns[c]] = col.GetOneForMarshal(i)
+				}
+				resp.Rows = append(resp.Rows, row)
+			}
+		}
+		chunk.Data.Release()
+	}
+	if streamErr != nil {
+		writeHTTPError(w, streamErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeHTTPError reports err with the HTTP status closest to its gRPC code
+func writeHTTPError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		code = http.StatusBadRequest
+	case codes.PermissionDenied, codes.Unauthenticated:
+		code = http.StatusForbidden
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	}
+	http.Error(w, err.Error(), code)
+}
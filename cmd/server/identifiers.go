@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// quoteIdentifierWith quotes a SQL identifier with quote, doubling the
+// quote inside it
+func quoteIdentifierWith(quote, name string) string {
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// checkQuoteChar validates BackendQuoteChar
+func checkQuoteChar(quote string) error {
+	if quote != "" && utf8.RuneCountInString(quote) != 1 {
+		return fmt.Errorf("backend quote char must be a single character, got %q", quote)
+	}
+	return nil
+}
+
+// qualifiedName quotes the non-empty parts of a catalog, schema and table
+// name with the backend's quote character and joins them with dots, so
+// that names with spaces or reserved words resolve
+func (s *DummyFlightSQLServer) qualifiedName(parts ...string) string {
+	quote := s.cfg.BackendQuoteChar
+	if quote == "" {
+		quote = `"`
+	}
+
+	quoted := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			quoted = append(quoted, quoteIdentifierWith(quote, part))
+		}
+	}
+	return strings.Join(quoted, ".")
+}
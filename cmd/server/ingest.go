@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ingestTarget is the table a StatementIngest writes to
+type ingestTarget struct {
+	catalog, schema, table string
+	temporary              bool
+}
+
+// key identifies the target table of an ingestGroup
+func (t ingestTarget) key() string {
+	return strings.Join([]string{t.catalog, t.schema, t.table}, "\x00")
+}
+
+// setOn points an ADBC ingest statement at the target with the given mode
+func (t ingestTarget) setOn(stmt adbc.Statement, mode string) error {
+	options := map[string]string{
+		adbc.OptionKeyIngestTargetTable: t.table,
+		adbc.OptionKeyIngestMode:        mode,
+	}
+	if t.catalog != "" {
+		options[adbc.OptionValueIngestTargetCatalog] = t.catalog
+	}
+	if t.schema != "" {
+		options[adbc.OptionValueIngestTargetDBSchema] = t.schema
+	}
+	if t.temporary {
+		options[adbc.OptionValueIngestTemporary] = adbc.OptionValueEnabled
+	}
+	for key, value := range options {
+		if err := stmt.SetOption(key, value); err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to set ingest option %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// ingestCreateMode returns the ADBC mode that prepares the target table
+// before the data is appended, or "" when the table must already exist
+func ingestCreateMode(opts *flightsql.TableDefinitionOptions) (string, error) {
+	ifNotExist, ifExists := opts.GetIfNotExist(), opts.GetIfExists()
+	switch {
+	case ifNotExist == flightsql.TableDefinitionOptionsTableNotExistOptionCreate:
+		switch ifExists {
+		case flightsql.TableDefinitionOptionsTableExistsOptionAppend:
+			return adbc.OptionValueIngestModeCreateAppend, nil
+		case flightsql.TableDefinitionOptionsTableExistsOptionReplace:
+			return adbc.OptionValueIngestModeReplace, nil
+		default:
+			return adbc.OptionValueIngestModeCreate, nil
+		}
+	case ifExists == flightsql.TableDefinitionOptionsTableExistsOptionAppend:
+		return "", nil
+	case ifExists == flightsql.TableDefinitionOptionsTableExistsOptionReplace:
+		return "", status.Error(codes.Unimplemented, "replacing a table requires creating it when it does not exist")
+	default:
+		return "", status.Error(codes.InvalidArgument, "ingest fails whether or not the table exists")
+	}
+}
+
+// ingestGroup coordinates the ingest streams running concurrently into one
+// table, so that only the first of them creates or replaces it
+type ingestGroup struct {
+	streams int // guarded by the server mutex
+
+	mu      sync.Mutex // held while the table is prepared
+	created bool
+}
+
+// joinIngest adds a stream to the group of the target table. leave must be
+// called when the stream is done, the last one out ends the group.
+func (s *DummyFlightSQLServer) joinIngest(key string) (group *ingestGroup, leave func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.ingestGroups[key]
+	if !ok {
+		group = &ingestGroup{}
+		s.ingestGroups[key] = group
+	}
+	group.streams++
+
+	return group, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if group.streams--; group.streams == 0 {
+			delete(s.ingestGroups, key)
+		}
+	}
+}
+
+// DoPutCommandStatementIngest bulk loads the uploaded batches into a table.
+// Streams may target the same table concurrently, each on its own
+// connection. The first of them creates or replaces the table from its
+// schema, the others running at the same time skip that step, and all of
+// them append their data. Options sent with the command are not passed to
+// the backend.
+func (s *DummyFlightSQLServer) DoPutCommandStatementIngest(ctx context.Context, cmd flightsql.StatementIngest, reader flight.MessageReader) (int64, error) {
+	if cmd.GetTable() == "" {
+		return 0, status.Error(codes.InvalidArgument, "ingest requires a target table")
+	}
+	createMode, err := ingestCreateMode(cmd.GetTableDefinitionOptions())
+	if err != nil {
+		return 0, err
+	}
+	target := ingestTarget{
+		catalog:   cmd.GetCatalog(),
+		schema:    cmd.GetSchema(),
+		table:     cmd.GetTable(),
+		temporary: cmd.GetTemporary(),
+	}
+
+	var conn adbc.Connection
+	if id := cmd.GetTransactionId(); len(id) > 0 {
+		txn, err := s.lookupTransaction(id)
+		if err != nil {
+			return 0, err
+		}
+		var done func()
+		if ctx, done, err = txn.startRead(ctx); err != nil {
+			return 0, err
+		}
+		defer done()
+		conn = txn.conn
+	} else {
+		if conn, err = s.openConnection(ctx); err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+	}
+
+	if s.cache != nil {
+		defer s.cache.invalidate()
+	}
+
+	if createMode != "" {
+		group, leave := s.joinIngest(target.key())
+		defer leave()
+		if err := s.prepareIngestTarget(ctx, conn, group, target, createMode, reader.Schema()); err != nil {
+			return 0, err
+		}
+	}
+
+	return runIngest(ctx, conn, target, adbc.OptionValueIngestModeAppend, reader)
+}
+
+// prepareIngestTarget creates or replaces the target table unless another
+// stream of the group already did
+func (s *DummyFlightSQLServer) prepareIngestTarget(ctx context.Context, conn adbc.Connection, group *ingestGroup, target ingestTarget, mode string, schema *arrow.Schema) error {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	if group.created {
+		return nil
+	}
+
+	empty, err := array.NewRecordReader(schema, nil)
+	if err != nil {
+		return err
+	}
+	defer empty.Release()
+
+	if _, err := runIngest(ctx, conn, target, mode, empty); err != nil {
+		return err
+	}
+	group.created = true
+	return nil
+}
+
+// runIngest loads the batches of reader into the target in the given mode
+// and returns the row count
+func runIngest(ctx context.Context, conn adbc.Connection, target ingestTarget, mode string, reader array.RecordReader) (int64, error) {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	if err := target.setOn(stmt, mode); err != nil {
+		return 0, err
+	}
+	if err := stmt.BindStream(ctx, reader); err != nil {
+		return 0, err
+	}
+	return stmt.ExecuteUpdate(ctx)
+}
@@ -0,0 +1,1452 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	flightsqldriver "github.com/apache/arrow-adbc/go/adbc/driver/flightsql"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+// startTestFlightServer serves the given server over gRPC on a random local
+// port and returns the address to connect to.
+func startTestFlightServer(t *testing.T, server *DummyFlightSQLServer) string {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	return serveTestFlightServer(t, server, lis)
+}
+
+// serveTestFlightServer serves the given server on an existing listener
+func serveTestFlightServer(t *testing.T, server *DummyFlightSQLServer, lis net.Listener) string {
+	srv := server.newFlightServer()
+	srv.InitListener(lis)
+
+	go srv.Serve()
+	t.Cleanup(srv.Shutdown)
+
+	return lis.Addr().String()
+}
+
+// countingListener counts the bytes written to every accepted connection
+type countingListener struct {
+	net.Listener
+	written atomic.Int64
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, written: &l.written}, nil
+}
+
+type countingConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// openFlightSQLClient connects an ADBC Flight SQL client to the given address.
+func openFlightSQLClient(t *testing.T, addr string) adbc.Connection {
+	drv := flightsqldriver.NewDriver(memory.DefaultAllocator)
+
+	db, err := drv.NewDatabase(map[string]string{
+		adbc.OptionKeyURI: "grpc+tcp://" + addr,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create flightsql client database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	conn, err := db.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open flightsql client connection: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// collectObjects flattens a GetObjects result into sorted "catalog.schema.table"
+// entries. Catalogs without schemas and schemas without tables are included
+// with the missing parts left empty.
+func collectObjects(t *testing.T, reader array.RecordReader) []string {
+	defer reader.Release()
+
+	var objects []string
+	for reader.Next() {
+		rec := reader.RecordBatch()
+
+		catalogNameCol := rec.Column(0).(*array.String)
+		schemasCol := rec.Column(1).(*array.List)
+		catalogSchemasValues := schemasCol.ListValues().(*array.Struct)
+		schemaNameCol := catalogSchemasValues.Field(0).(*array.String)
+		tablesCol := catalogSchemasValues.Field(1).(*array.List)
+		schemaTablesValues := tablesCol.ListValues().(*array.Struct)
+		tableNameCol := schemaTablesValues.Field(0).(*array.String)
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			catalogName := catalogNameCol.Value(i)
+
+			schemaStart, schemaEnd := schemasCol.ValueOffsets(i)
+			if schemaStart == schemaEnd {
+				objects = append(objects, catalogName+"..")
+			}
+
+			for j := schemaStart; j < schemaEnd; j++ {
+				schemaName := schemaNameCol.Value(int(j))
+
+				tableStart, tableEnd := tablesCol.ValueOffsets(int(j))
+				if tableStart == tableEnd {
+					objects = append(objects, catalogName+"."+schemaName+".")
+				}
+
+				for k := tableStart; k < tableEnd; k++ {
+					objects = append(objects, catalogName+"."+schemaName+"."+tableNameCol.Value(int(k)))
+				}
+			}
+		}
+	}
+
+	if err := reader.Err(); err != nil {
+		t.Fatalf("Failed to read GetObjects result: %v", err)
+	}
+
+	sort.Strings(objects)
+	return objects
+}
+
+func TestIntegration_GetObjects(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			setupTestSchemas(t, server, driver)
+
+			ctx := context.Background()
+
+			// Read what the backend holds directly
+			backend, err := (*server.db).Open(ctx)
+			if err != nil {
+				t.Fatalf("Failed to open backend connection for %s: %v", driver.name, err)
+			}
+			defer backend.Close()
+
+			client := openFlightSQLClient(t, startTestFlightServer(t, server))
+
+			for _, depth := range []adbc.ObjectDepth{adbc.ObjectDepthCatalogs, adbc.ObjectDepthDBSchemas, adbc.ObjectDepthTables} {
+				expectedReader, err := backend.GetObjects(ctx, depth, nil, nil, nil, nil, nil)
+				if err != nil {
+					t.Fatalf("Backend GetObjects failed for %s: %v", driver.name, err)
+				}
+				expected := collectObjects(t, expectedReader)
+
+				// Read the same objects through the Flight SQL server
+				actualReader, err := client.GetObjects(ctx, depth, nil, nil, nil, nil, nil)
+				if err != nil {
+					t.Fatalf("GetObjects over the wire failed for %s: %v", driver.name, err)
+				}
+				actual := collectObjects(t, actualReader)
+
+				if len(actual) != len(expected) {
+					t.Fatalf("Object count mismatch at depth %d for %s.\nExpected: %v\nGot: %v", depth, driver.name, expected, actual)
+				}
+				for i := range expected {
+					if actual[i] != expected[i] {
+						t.Errorf("Object mismatch at depth %d for %s.\nExpected: %v\nGot: %v", depth, driver.name, expected, actual)
+						break
+					}
+				}
+
+				t.Logf("Objects at depth %d for %s: %v", depth, driver.name, actual)
+			}
+		})
+	}
+}
+
+func TestIntegration_GetTableSchema(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := context.Background()
+
+			backend, err := (*server.db).Open(ctx)
+			if err != nil {
+				t.Fatalf("Failed to open backend connection for %s: %v", driver.name, err)
+			}
+			defer backend.Close()
+
+			expected, err := backend.GetTableSchema(ctx, nil, nil, "test_table")
+			if err != nil {
+				t.Fatalf("Backend GetTableSchema failed for %s: %v", driver.name, err)
+			}
+
+			client := openFlightSQLClient(t, startTestFlightServer(t, server))
+			actual, err := client.GetTableSchema(ctx, nil, nil, "test_table")
+			if err != nil {
+				t.Fatalf("GetTableSchema over the wire failed for %s: %v", driver.name, err)
+			}
+
+			if actual.NumFields() != expected.NumFields() {
+				t.Fatalf("Field count mismatch for %s.\nExpected: %v\nGot: %v", driver.name, expected, actual)
+			}
+
+			for i := 0; i < expected.NumFields(); i++ {
+				if actual.Field(i).Name != expected.Field(i).Name {
+					t.Errorf("Expected field %d to be '%s' for %s, got '%s'", i, expected.Field(i).Name, driver.name, actual.Field(i).Name)
+				}
+				if !arrow.TypeEqual(actual.Field(i).Type, expected.Field(i).Type) {
+					t.Errorf("Expected field %d type %s for %s, got %s", i, expected.Field(i).Type, driver.name, actual.Field(i).Type)
+				}
+			}
+		})
+	}
+}
+
+// compressibleQuery returns a few thousand rows of highly repetitive data
+const compressibleQuery = "WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < 5000) " +
+	"SELECT x, 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa' AS filler FROM seq"
+
+func TestIntegration_Compression(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+
+			// fetch runs the compressible query with the given codec and
+			// returns the number of bytes the server wrote
+			fetch := func(codec string) int64 {
+				server.cfg.Compression = codec
+
+				lis, err := net.Listen("tcp", "localhost:0")
+				if err != nil {
+					t.Fatalf("Failed to listen: %v", err)
+				}
+				counting := &countingListener{Listener: lis}
+				client := openFlightSQLClient(t, serveTestFlightServer(t, server, counting))
+
+				stmt, err := client.NewStatement()
+				if err != nil {
+					t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+				}
+				defer stmt.Close()
+
+				if err := stmt.SetSqlQuery(compressibleQuery); err != nil {
+					t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+				}
+
+				// Only count the bytes of the query itself
+				counting.written.Store(0)
+
+				reader, _, err := stmt.ExecuteQuery(ctx)
+				if err != nil {
+					t.Fatalf("ExecuteQuery with compression %q failed for %s: %v", codec, driver.name, err)
+				}
+				defer reader.Release()
+
+				var totalRows int64
+				for reader.Next() {
+					rec := reader.RecordBatch()
+					filler := rec.Column(1).(*array.String)
+					for i := 0; i < filler.Len(); i++ {
+						if filler.Value(i) != strings.Repeat("a", 64) {
+							t.Fatalf("Unexpected filler value with compression %q for %s: %q", codec, driver.name, filler.Value(i))
+						}
+					}
+					totalRows += rec.NumRows()
+				}
+				if err := reader.Err(); err != nil {
+					t.Fatalf("Failed to read result with compression %q for %s: %v", codec, driver.name, err)
+				}
+
+				if totalRows != 5000 {
+					t.Errorf("Expected 5000 rows with compression %q for %s, got %d", codec, driver.name, totalRows)
+				}
+
+				return counting.written.Load()
+			}
+
+			uncompressed := fetch("")
+			for _, codec := range supportedCompressions {
+				compressed := fetch(codec)
+				if compressed >= uncompressed {
+					t.Errorf("Expected %s to reduce bytes on the wire for %s: %d compressed vs %d uncompressed", codec, driver.name, compressed, uncompressed)
+				}
+				t.Logf("Bytes written for %s with %s: %d (uncompressed %d)", driver.name, codec, compressed, uncompressed)
+			}
+		})
+	}
+}
+
+// malformedObjects returns a GetObjects result with the wrong column types
+func malformedObjects() (array.RecordReader, error) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "catalog_name", Type: arrow.PrimitiveTypes.Int32}}, nil)
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int32Builder).Append(1)
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	return array.NewRecordReader(schema, []arrow.RecordBatch{rec})
+}
+
+func TestIntegration_PanicRecovery(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			useFaultyDatabase(server, &faultyDatabase{getObjects: malformedObjects})
+
+			ctx := context.Background()
+			client := openFlightSQLClient(t, startTestFlightServer(t, server))
+
+			// The catalog projection would panic on the malformed batch
+			objects, err := client.GetObjects(ctx, adbc.ObjectDepthCatalogs, nil, nil, nil, nil, nil)
+			if err == nil {
+				// Some clients only surface stream errors while reading
+				for objects.Next() {
+				}
+				err = objects.Err()
+				objects.Release()
+			}
+			if err == nil {
+				t.Fatalf("Expected GetObjects to fail on a malformed batch for %s", driver.name)
+			}
+			if !strings.Contains(err.Error(), "internal error") {
+				t.Errorf("Expected an internal error for %s, got: %v", driver.name, err)
+			}
+
+			// The server must keep serving other requests
+			reader, err := client.GetTableTypes(ctx)
+			if err != nil {
+				t.Fatalf("Expected the server to keep serving after a panic for %s: %v", driver.name, err)
+			}
+			defer reader.Release()
+
+			var rows int64
+			for reader.Next() {
+				rows += reader.RecordBatch().NumRows()
+			}
+			if err := reader.Err(); err != nil {
+				t.Fatalf("Failed to read table types for %s: %v", driver.name, err)
+			}
+			if rows == 0 {
+				t.Errorf("Expected table types after recovering from a panic for %s", driver.name)
+			}
+		})
+	}
+}
+
+// lockedBuffer collects log output written from server goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// testCertificate is a PEM encoded certificate and key written to disk
+type testCertificate struct {
+	certFile, keyFile string
+	cert              *x509.Certificate
+	key               *ecdsa.PrivateKey
+}
+
+// writeTestCertificate creates a certificate for commonName signed by parent,
+// or a self-signed CA when parent is nil
+func writeTestCertificate(t *testing.T, dir, commonName string, parent *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate for %s: %v", commonName, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate for %s: %v", commonName, err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key for %s: %v", commonName, err)
+	}
+
+	ret := &testCertificate{
+		certFile: filepath.Join(dir, commonName+".crt"),
+		keyFile:  filepath.Join(dir, commonName+".key"),
+		cert:     cert,
+		key:      key,
+	}
+	if err := os.WriteFile(ret.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(ret.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return ret
+}
+
+func TestIntegration_MutualTLS(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	dir := t.TempDir()
+	ca := writeTestCertificate(t, dir, "test-ca", nil, 0)
+	serverCert := writeTestCertificate(t, dir, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := writeTestCertificate(t, dir, "analyst", ca, x509.ExtKeyUsageClientAuth)
+
+	// Client identities are logged at debug level
+	logged := &lockedBuffer{}
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelDebug)
+	server, err := New(ServerConfig{
+		DatabaseOptions: testDatabaseOptions(driver),
+		TLSCertFile:     serverCert.certFile,
+		TLSKeyFile:      serverCert.keyFile,
+		TLSClientCAFile: ca.certFile,
+		Logger:          newLogger(logged, level),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer (*server.db).Close()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := serveTestFlightServer(t, server, lis)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	listActions := func(tlsConfig *tls.Config) error {
+		client, err := flight.NewClientWithMiddleware(addr, nil, nil, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+		if err != nil {
+			t.Fatalf("Failed to create flight client: %v", err)
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stream, err := client.ListActions(ctx, &flight.Empty{})
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err := stream.Recv(); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	t.Run("ValidClientCertificate", func(t *testing.T) {
+		keyPair, err := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+		if err != nil {
+			t.Fatalf("Failed to load client certificate: %v", err)
+		}
+
+		err = listActions(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{keyPair}})
+		if err != nil {
+			t.Fatalf("Expected a client with a valid certificate to be accepted: %v", err)
+		}
+		if !strings.Contains(logged.String(), "client=analyst") {
+			t.Errorf("Expected the client identity to be logged, got: %s", logged.String())
+		}
+	})
+
+	t.Run("NoClientCertificate", func(t *testing.T) {
+		err := listActions(&tls.Config{RootCAs: roots})
+		if err == nil {
+			t.Fatal("Expected a client without a certificate to be rejected")
+		}
+		if code := status.Code(err); code != codes.Unavailable {
+			t.Errorf("Expected the connection to fail with Unavailable, got %v: %v", code, err)
+		}
+	})
+}
+
+func TestIntegration_HTTPQuery(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			httpServer := httptest.NewServer(server.httpHandler())
+			defer httpServer.Close()
+
+			body := strings.NewReader(`{"query": "SELECT id, name FROM test_table ORDER BY id"}`)
+			resp, err := http.Post(httpServer.URL+"/query", "application/json", body)
+			if err != nil {
+				t.Fatalf("POST /query failed for %s: %v", driver.name, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				msg, _ := io.ReadAll(resp.Body)
+				t.Fatalf("Expected 200 for %s, got %d: %s", driver.name, resp.StatusCode, msg)
+			}
+
+			var result struct {
+				Columns []string         `json:"columns"`
+				Rows    []map[string]any `json:"rows"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response for %s: %v", driver.name, err)
+			}
+
+			if strings.Join(result.Columns, ",") != "id,name" {
+				t.Errorf("Expected columns id,name for %s, got %v", driver.name, result.Columns)
+			}
+			if len(result.Rows) != 3 {
+				t.Fatalf("Expected 3 rows for %s, got %d", driver.name, len(result.Rows))
+			}
+			for i, row := range result.Rows {
+				if row["id"] != float64(i+1) || row["name"] != fmt.Sprintf("test%d", i+1) {
+					t.Errorf("Unexpected row %d for %s: %v", i, driver.name, row)
+				}
+			}
+
+			resp, err = http.Post(httpServer.URL+"/query", "application/json", strings.NewReader(`{}`))
+			if err != nil {
+				t.Fatalf("POST /query failed for %s: %v", driver.name, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected 400 for a missing query for %s, got %d", driver.name, resp.StatusCode)
+			}
+
+			oversized := `{"query": "SELECT '` + strings.Repeat("a", maxHTTPQueryBytes) + `'"}`
+			resp, err = http.Post(httpServer.URL+"/query", "application/json", strings.NewReader(oversized))
+			if err != nil {
+				t.Fatalf("POST /query failed for %s: %v", driver.name, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected 413 for an oversized body for %s, got %d", driver.name, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestIntegration_HTTPQueryRateLimit(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	// One request per second, so the second request in a row is rejected
+	server.cfg.RateLimit = 1
+	if err := server.applyConfig(fileConfig{}); err != nil {
+		t.Fatalf("Failed to apply the rate limit: %v", err)
+	}
+
+	httpServer := httptest.NewServer(server.httpHandler())
+	defer httpServer.Close()
+
+	var statuses []int
+	for range 2 {
+		resp, err := http.Post(httpServer.URL+"/query", "application/json", strings.NewReader(`{"query": "SELECT 1"}`))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if !slices.Equal(statuses, []int{http.StatusOK, http.StatusTooManyRequests}) {
+		t.Errorf("Expected the second request to be rate limited, got %v", statuses)
+	}
+}
+
+func TestIntegration_HTTPQueryMutualTLS(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	dir := t.TempDir()
+	ca := writeTestCertificate(t, dir, "test-ca", nil, 0)
+	serverCert := writeTestCertificate(t, dir, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := writeTestCertificate(t, dir, "analyst", ca, x509.ExtKeyUsageClientAuth)
+
+	server, err := New(ServerConfig{
+		DatabaseOptions: testDatabaseOptions(driver),
+		TLSCertFile:     serverCert.certFile,
+		TLSKeyFile:      serverCert.keyFile,
+		TLSClientCAFile: ca.certFile,
+		AuditSink:       &memoryAuditSink{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer (*server.db).Close()
+
+	httpServer := httptest.NewUnstartedServer(server.httpHandler())
+	httpServer.TLS = server.tlsConfig
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	query := func(tlsConfig *tls.Config) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		return client.Post(httpServer.URL+"/query", "application/json", strings.NewReader(`{"query": "SELECT 1"}`))
+	}
+
+	if resp, err := query(&tls.Config{RootCAs: roots}); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected a client without a certificate to be rejected")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	resp, err := query(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{keyPair}})
+	if err != nil {
+		t.Fatalf("Expected a client with a valid certificate to be accepted: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	// The statement handlers see the certificate identity
+	records := server.cfg.AuditSink.(*memoryAuditSink).all()
+	if len(records) == 0 || records[0].Client != "analyst" {
+		t.Errorf("Expected the query to be audited for analyst, got %+v", records)
+	}
+}
+
+func TestIntegration_Readiness(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			degraded := &faultyDatabase{}
+			useFaultyDatabase(server, degraded)
+			server.cfg.ReadinessLatency = 200 * time.Millisecond
+
+			conn, err := grpc.NewClient(startTestFlightServer(t, server), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("Failed to connect for %s: %v", driver.name, err)
+			}
+			defer conn.Close()
+			healthClient := healthpb.NewHealthClient(conn)
+
+			httpServer := httptest.NewServer(server.httpHandler())
+			defer httpServer.Close()
+
+			expectReady := func(step string, ready bool) {
+				t.Helper()
+
+				resp, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{})
+				if err != nil {
+					t.Fatalf("Health check %s failed for %s: %v", step, driver.name, err)
+				}
+				want := healthpb.HealthCheckResponse_NOT_SERVING
+				if ready {
+					want = healthpb.HealthCheckResponse_SERVING
+				}
+				if resp.GetStatus() != want {
+					t.Errorf("Expected gRPC health %s %s for %s, got %s", want, step, driver.name, resp.GetStatus())
+				}
+
+				httpResp, err := http.Get(httpServer.URL + "/ready")
+				if err != nil {
+					t.Fatalf("GET /ready %s failed for %s: %v", step, driver.name, err)
+				}
+				httpResp.Body.Close()
+				wantCode := http.StatusServiceUnavailable
+				if ready {
+					wantCode = http.StatusOK
+				}
+				if httpResp.StatusCode != wantCode {
+					t.Errorf("Expected /ready to return %d %s for %s, got %d", wantCode, step, driver.name, httpResp.StatusCode)
+				}
+			}
+
+			expectReady("initially", true)
+
+			degraded.openDelay.Store(int64(300 * time.Millisecond))
+			expectReady("with a slow backend", false)
+			degraded.openDelay.Store(0)
+
+			degraded.failOpen.Store(true)
+			expectReady("with a failing backend", false)
+			degraded.failOpen.Store(false)
+
+			expectReady("after recovery", true)
+		})
+	}
+}
+
+func TestIntegration_PreparedStatementRepeatedBinds(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			conn := openFlightSQLClient(t, startTestFlightServer(t, server))
+			ctx := context.Background()
+
+			stmt, err := conn.NewStatement()
+			if err != nil {
+				t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+			}
+			defer stmt.Close()
+
+			if err := stmt.SetSqlQuery("SELECT ? AS x"); err != nil {
+				t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+			}
+			if err := stmt.Prepare(ctx); err != nil {
+				t.Fatalf("Failed to prepare statement for %s: %v", driver.name, err)
+			}
+
+			paramSchema := arrow.NewSchema([]arrow.Field{{Name: "x", Type: arrow.PrimitiveTypes.Int64}}, nil)
+			bldr := array.NewRecordBuilder(memory.DefaultAllocator, paramSchema)
+			defer bldr.Release()
+			bldr.Field(0).(*array.Int64Builder).AppendValues([]int64{10, 20, 30}, nil)
+			params := bldr.NewRecordBatch()
+			defer params.Release()
+
+			if err := stmt.Bind(ctx, params); err != nil {
+				t.Fatalf("Failed to bind parameters for %s: %v", driver.name, err)
+			}
+
+			reader, _, err := stmt.ExecuteQuery(ctx)
+			if err != nil {
+				t.Fatalf("Failed to execute prepared statement for %s: %v", driver.name, err)
+			}
+			defer reader.Release()
+
+			var values []string
+			for reader.Next() {
+				rec := reader.RecordBatch()
+				// Each binding's results end on a batch boundary
+				if rec.NumRows() > 1 {
+					t.Errorf("Expected at most 1 row per batch for %s, got %d", driver.name, rec.NumRows())
+				}
+				for i := 0; i < int(rec.NumRows()); i++ {
+					values = append(values, rec.Column(0).ValueStr(i))
+				}
+			}
+			if err := reader.Err(); err != nil {
+				t.Fatalf("Failed to read results for %s: %v", driver.name, err)
+			}
+
+			if strings.Join(values, ",") != "10,20,30" {
+				t.Errorf("Expected one row per binding in order for %s, got %v", driver.name, values)
+			}
+		})
+	}
+}
+
+func TestIntegration_QueryLabel(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			var logs lockedBuffer
+			server.cfg.QueryLabelHeader = defaultQueryLabelHeader
+			server.logger = newLogger(&logs, &server.logLevel)
+			server.logLevel.Set(slog.LevelDebug)
+
+			conn := openFlightSQLClient(t, startTestFlightServer(t, server))
+
+			stmt, err := conn.NewStatement()
+			if err != nil {
+				t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+			}
+			defer stmt.Close()
+
+			if err := stmt.(adbc.PostInitOptions).SetOption(flightsqldriver.OptionRPCCallHeaderPrefix+defaultQueryLabelHeader, "nightly-report"); err != nil {
+				t.Fatalf("Failed to set the query label header for %s: %v", driver.name, err)
+			}
+			if err := stmt.SetSqlQuery("SELECT id FROM test_table"); err != nil {
+				t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+			}
+
+			reader, _, err := stmt.ExecuteQuery(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to execute query for %s: %v", driver.name, err)
+			}
+			for reader.Next() {
+			}
+			reader.Release()
+
+			var found bool
+			for _, line := range strings.Split(logs.String(), "\n") {
+				if strings.Contains(line, "executing statement") && strings.Contains(line, "SELECT id FROM test_table") {
+					found = strings.Contains(line, "query_label=nightly-report")
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Expected the query's log line to carry the label for %s, got:\n%s", driver.name, logs.String())
+			}
+		})
+	}
+}
+
+func TestIntegration_ProxyMode(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			backend, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, backend)
+			backendAddr := startTestFlightServer(t, backend)
+
+			cfg := DefaultServerConfig()
+			cfg.DatabaseOptions = map[string]string{
+				"driver":          flightSQLDriver,
+				adbc.OptionKeyURI: "grpc+tcp://" + backendAddr,
+			}
+			proxy, err := New(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create proxy server for %s: %v", driver.name, err)
+			}
+			defer (*proxy.db).Close()
+
+			conn := openFlightSQLClient(t, startTestFlightServer(t, proxy))
+
+			stmt, err := conn.NewStatement()
+			if err != nil {
+				t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+			}
+			defer stmt.Close()
+
+			if err := stmt.SetSqlQuery("SELECT name FROM test_table ORDER BY id"); err != nil {
+				t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+			}
+			reader, _, err := stmt.ExecuteQuery(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to execute query through the proxy for %s: %v", driver.name, err)
+			}
+			defer reader.Release()
+
+			var names []string
+			for reader.Next() {
+				rec := reader.RecordBatch()
+				for i := 0; i < int(rec.NumRows()); i++ {
+					names = append(names, rec.Column(0).ValueStr(i))
+				}
+			}
+			if err := reader.Err(); err != nil {
+				t.Fatalf("Failed to read results through the proxy for %s: %v", driver.name, err)
+			}
+
+			if strings.Join(names, ",") != "test1,test2,test3" {
+				t.Errorf("Expected the backend's rows through the proxy for %s, got %v", driver.name, names)
+			}
+		})
+	}
+}
+
+func TestIntegration_NewAndServe(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			var logs lockedBuffer
+			server, err := New(ServerConfig{
+				DatabaseOptions: testDatabaseOptions(driver),
+				Allocator:       AllocatorGo,
+				Logger:          newLogger(&logs, new(slog.LevelVar)),
+				AdminToken:      "secret",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create server for %s: %v", driver.name, err)
+			}
+			defer server.Close()
+
+			served := make(chan error, 1)
+			go func() { served <- server.Serve("localhost:0") }()
+
+			addr := server.Addr()
+			if addr == nil {
+				t.Fatalf("Server failed to listen for %s: %v", driver.name, <-served)
+			}
+
+			conn := openFlightSQLClient(t, addr.String())
+			stmt, err := conn.NewStatement()
+			if err != nil {
+				t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+			}
+			if err := stmt.SetSqlQuery("SELECT 1"); err != nil {
+				t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+			}
+			reader, _, err := stmt.ExecuteQuery(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to execute query for %s: %v", driver.name, err)
+			}
+			for reader.Next() {
+			}
+			reader.Release()
+			stmt.Close()
+
+			server.Shutdown()
+			if err := <-served; err != nil {
+				t.Errorf("Expected Serve to return cleanly after Shutdown for %s, got: %v", driver.name, err)
+			}
+			if !strings.Contains(logs.String(), "Flight SQL server listening") {
+				t.Errorf("Expected the configured logger to be used for %s, got:\n%s", driver.name, logs.String())
+			}
+		})
+	}
+}
+
+// listReflectedServices asks the reflection service at addr for the names
+// of the registered services
+func listReflectedServices(t *testing.T, addr string) ([]string, error) {
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create gRPC client: %v", err)
+	}
+	defer cc.Close()
+
+	stream, err := reflectionpb.NewServerReflectionClient(cc).ServerReflectionInfo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		names = append(names, service.GetName())
+	}
+	return names, nil
+}
+
+func TestIntegration_Reflection(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	t.Run("Disabled", func(t *testing.T) {
+		_, err := listReflectedServices(t, startTestFlightServer(t, server))
+		if status.Code(err) != codes.Unimplemented {
+			t.Errorf("Expected reflection to be unavailable by default, got %v", err)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		server.cfg.EnableReflection = true
+		defer func() { server.cfg.EnableReflection = false }()
+
+		services, err := listReflectedServices(t, startTestFlightServer(t, server))
+		if err != nil {
+			t.Fatalf("Failed to list services: %v", err)
+		}
+		if !slices.Contains(services, "arrow.flight.protocol.FlightService") {
+			t.Errorf("Expected the Flight service to be listed, got %v", services)
+		}
+	})
+}
+
+func TestIntegration_ExchangeJSONLines(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			stream, err := client.DoExchange(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to start exchange for %s: %v", driver.name, err)
+			}
+
+			cmd, _ := json.Marshal(exchangeRequest{Query: "SELECT id, name FROM test_table ORDER BY id", Format: ExchangeFormatJSONLines})
+			if err := stream.Send(&flight.FlightData{FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: cmd}}); err != nil {
+				t.Fatalf("Failed to send exchange command for %s: %v", driver.name, err)
+			}
+			stream.CloseSend()
+
+			var rows []map[string]any
+			for {
+				data, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Exchange failed for %s: %v", driver.name, err)
+				}
+				if !bytes.HasSuffix(data.AppMetadata, []byte("\n")) {
+					t.Errorf("Expected a newline terminated JSON line for %s, got %q", driver.name, data.AppMetadata)
+				}
+				var row map[string]any
+				if err := json.Unmarshal(data.AppMetadata, &row); err != nil {
+					t.Fatalf("Failed to decode JSON line for %s: %v", driver.name, err)
+				}
+				rows = append(rows, row)
+			}
+
+			if len(rows) != 3 {
+				t.Fatalf("Expected 3 JSON lines for %s, got %d: %v", driver.name, len(rows), rows)
+			}
+			for i, row := range rows {
+				if len(row) != 2 || row["id"] != float64(i+1) || row["name"] != fmt.Sprintf("test%d", i+1) {
+					t.Errorf("Unexpected row %d for %s: %v", i, driver.name, row)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegration_UpdateRowCounts(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := context.Background()
+			client := openFlightSQLClient(t, startTestFlightServer(t, server))
+
+			executeUpdate := func(query string) int64 {
+				t.Helper()
+				stmt, err := client.NewStatement()
+				if err != nil {
+					t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+				}
+				defer stmt.Close()
+				if err := stmt.SetSqlQuery(query); err != nil {
+					t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+				}
+				affected, err := stmt.ExecuteUpdate(ctx)
+				if err != nil {
+					t.Fatalf("ExecuteUpdate %q failed for %s: %v", query, driver.name, err)
+				}
+				return affected
+			}
+
+			if affected := executeUpdate("DELETE FROM test_table WHERE id <= 2"); affected != 2 {
+				t.Errorf("Expected DELETE to report 2 rows for %s, got %d", driver.name, affected)
+			}
+			// The DELETE count must not leak into the DDL's unknown count
+			if affected := executeUpdate("CREATE TABLE update_counts (id INTEGER)"); affected != -1 {
+				t.Errorf("Expected DDL to report an unknown count (-1) for %s, got %d", driver.name, affected)
+			}
+		})
+	}
+}
+
+// barrierReader holds back its first batch until every reader of the
+// barrier has been asked for one, so concurrent uploads overlap
+type barrierReader struct {
+	array.RecordReader
+	barrier *sync.WaitGroup
+	once    sync.Once
+}
+
+func (r *barrierReader) Next() bool {
+	r.once.Do(func() {
+		r.barrier.Done()
+		r.barrier.Wait()
+	})
+	return r.RecordReader.Next()
+}
+
+func TestIntegration_ParallelIngest(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			// Parallel SQLite writers wait for each other's locks
+			server.cfg.SQLiteBusyTimeout = 10 * time.Second
+
+			client, err := flightsql.NewClient(startTestFlightServer(t, server), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("Failed to create Flight SQL client for %s: %v", driver.name, err)
+			}
+			defer client.Close()
+
+			const streams, rowsPerStream = 3, 100
+			schema := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+			var barrier sync.WaitGroup
+			barrier.Add(streams)
+			errs := make(chan error, streams)
+			for i := 0; i < streams; i++ {
+				bldr := array.NewInt64Builder(memory.DefaultAllocator)
+				for j := 0; j < rowsPerStream; j++ {
+					bldr.Append(int64(i*rowsPerStream + j))
+				}
+				col := bldr.NewArray()
+				bldr.Release()
+				rec := array.NewRecordBatch(schema, []arrow.Array{col}, rowsPerStream)
+				col.Release()
+
+				rdr, err := array.NewRecordReader(schema, []arrow.RecordBatch{rec})
+				rec.Release()
+				if err != nil {
+					t.Fatalf("Failed to create reader: %v", err)
+				}
+
+				go func() {
+					defer rdr.Release()
+					// Every stream asks to create the table, as a partitioned
+					// load would
+					_, err := client.ExecuteIngest(context.Background(), &barrierReader{RecordReader: rdr, barrier: &barrier}, &flightsql.ExecuteIngestOpts{
+						TableDefinitionOptions: &flightsql.TableDefinitionOptions{
+							IfNotExist: flightsql.TableDefinitionOptionsTableNotExistOptionCreate,
+							IfExists:   flightsql.TableDefinitionOptionsTableExistsOptionFail,
+						},
+						Table: "ingested",
+					})
+					errs <- err
+				}()
+			}
+			for i := 0; i < streams; i++ {
+				if err := <-errs; err != nil {
+					t.Errorf("Ingest stream failed for %s: %v", driver.name, err)
+				}
+			}
+
+			var count string
+			for chunk := range runStatement(t, server, "SELECT COUNT(*) FROM ingested") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				count = chunk.Data.Column(0).ValueStr(0)
+				chunk.Data.Release()
+			}
+			if want := fmt.Sprint(streams * rowsPerStream); count != want {
+				t.Errorf("Expected %s ingested rows for %s, got %s", want, driver.name, count)
+			}
+		})
+	}
+}
+
+func TestIntegration_TableFlight(t *testing.T) {
+	for _, driver := range getTestDrivers(t) {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			ctx := context.Background()
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			info, err := client.GetFlightInfo(ctx, &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"test_table"}})
+			if err != nil {
+				t.Fatalf("GetFlightInfo failed for %s: %v", driver.name, err)
+			}
+			schema, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to deserialize schema for %s: %v", driver.name, err)
+			}
+			var names []string
+			for _, field := range schema.Fields() {
+				names = append(names, field.Name)
+			}
+			if want := []string{"id", "name", "value"}; !slices.Equal(names, want) {
+				t.Errorf("Expected columns %v for %s, got %v", want, driver.name, names)
+			}
+
+			stream, err := client.DoGet(ctx, info.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("DoGet failed for %s: %v", driver.name, err)
+			}
+			rdr, err := flight.NewRecordReader(stream)
+			if err != nil {
+				t.Fatalf("Failed to read table stream for %s: %v", driver.name, err)
+			}
+			defer rdr.Release()
+
+			if !rdr.Schema().Equal(schema) {
+				t.Errorf("Streamed schema differs for %s: %s, advertised %s", driver.name, rdr.Schema(), schema)
+			}
+			var rows []string
+			for rdr.Next() {
+				rec := rdr.RecordBatch()
+				for i := 0; i < int(rec.NumRows()); i++ {
+					rows = append(rows, rec.Column(1).ValueStr(i))
+				}
+			}
+			if err := rdr.Err(); err != nil {
+				t.Fatalf("Table stream failed for %s: %v", driver.name, err)
+			}
+			sort.Strings(rows)
+			if want := []string{"test1", "test2", "test3"}; !slices.Equal(rows, want) {
+				t.Errorf("Expected rows %v for %s, got %v", want, driver.name, rows)
+			}
+
+			// The table is listed with a descriptor that resolves to it again
+			flights, err := client.ListFlights(ctx, &flight.Criteria{})
+			if err != nil {
+				t.Fatalf("ListFlights failed for %s: %v", driver.name, err)
+			}
+			var listed *flight.FlightInfo
+			for {
+				info, err := flights.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("ListFlights failed for %s: %v", driver.name, err)
+				}
+				if path := info.FlightDescriptor.GetPath(); len(path) > 0 && path[len(path)-1] == "test_table" {
+					listed = info
+				}
+			}
+			if listed == nil {
+				t.Fatalf("test_table is not listed for %s", driver.name)
+			}
+			if _, err := client.GetFlightInfo(ctx, listed.FlightDescriptor); err != nil {
+				t.Errorf("GetFlightInfo of the listed descriptor failed for %s: %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestIntegration_TableFlightsEmptySchemaName(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	setupTestData(t, server)
+	server.cfg.EmptySchemaName = "main"
+
+	conn, err := server.openConnection(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to open a connection: %v", err)
+	}
+	tables, err := server.listTables(context.Background(), conn)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("listTables failed: %v", err)
+	}
+
+	var listed *tableRef
+	for i := range tables {
+		if tables[i].table == "test_table" {
+			listed = &tables[i]
+		}
+	}
+	if listed == nil {
+		t.Fatalf("test_table is not listed in %v", tables)
+	}
+	if listed.schema != "main" {
+		t.Errorf("Expected test_table to be listed in the main schema, got %q", listed.schema)
+	}
+
+	// The reported schema name resolves to the table again
+	if _, err := server.tableSchema(context.Background(), *listed); err != nil {
+		t.Errorf("tableSchema of the listed table failed: %v", err)
+	}
+}
+
+// bodyCountingStream sums the record batch body bytes received on a DoGet
+type bodyCountingStream struct {
+	flight.FlightService_DoGetClient
+	body int
+}
+
+func (s *bodyCountingStream) Recv() (*flight.FlightData, error) {
+	data, err := s.FlightService_DoGetClient.Recv()
+	if data != nil {
+		s.body += len(data.DataBody)
+	}
+	return data, err
+}
+
+func TestIntegration_IPCCompression(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.IPCCompression = "lz4"
+
+			client := openFlightClient(t, startTestFlightServer(t, server))
+
+			// fetch runs the compressible query, listing lz4 as decodable when
+			// accept is set, and returns the body bytes received
+			fetch := func(accept bool) int {
+				ctx := context.Background()
+				desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+				info, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: compressibleQuery}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+
+				if accept {
+					ctx = metadata.AppendToOutgoingContext(ctx, ipcCompressionHeader, "lz4")
+				}
+				stream, err := client.DoGet(ctx, info.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("DoGet failed for %s: %v", driver.name, err)
+				}
+				counting := &bodyCountingStream{FlightService_DoGetClient: stream}
+				rdr, err := flight.NewRecordReader(counting)
+				if err != nil {
+					t.Fatalf("Failed to read the stream for %s: %v", driver.name, err)
+				}
+				defer rdr.Release()
+
+				var totalRows int64
+				for rdr.Next() {
+					rec := rdr.RecordBatch()
+					filler := rec.Column(1).(*array.String)
+					for i := 0; i < filler.Len(); i++ {
+						if filler.Value(i) != strings.Repeat("a", 64) {
+							t.Fatalf("Unexpected filler value for %s: %q", driver.name, filler.Value(i))
+						}
+					}
+					totalRows += rec.NumRows()
+				}
+				if err := rdr.Err(); err != nil {
+					t.Fatalf("Failed to decode the stream for %s: %v", driver.name, err)
+				}
+				if totalRows != 5000 {
+					t.Errorf("Expected 5000 rows for %s, got %d", driver.name, totalRows)
+				}
+				return counting.body
+			}
+
+			uncompressed := fetch(false)
+			compressed := fetch(true)
+			if compressed >= uncompressed {
+				t.Errorf("Expected lz4 bodies to be smaller for %s: %d compressed vs %d uncompressed", driver.name, compressed, uncompressed)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultQueryLabelHeader is the request header read for query labels
+const defaultQueryLabelHeader = "x-query-label"
+
+// queryLabelKey is the context key holding the client's query label
+type queryLabelKey struct{}
+
+// queryLabel returns the label the client attached to the request, if any
+func queryLabel(ctx context.Context) string {
+	label, _ := ctx.Value(queryLabelKey{}).(string)
+	return label
+}
+
+// withQueryLabel reads the QueryLabelHeader from the request metadata into
+// the context and tags the current trace span with it
+func (s *DummyFlightSQLServer) withQueryLabel(ctx context.Context) context.Context {
+	if s.cfg.QueryLabelHeader == "" {
+		return ctx
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(s.cfg.QueryLabelHeader)
+	if len(values) == 0 || values[0] == "" {
+		return ctx
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("flightsql.query_label", values[0]))
+	return context.WithValue(ctx, queryLabelKey{}, values[0])
+}
+
+// logFor returns the server logger annotated with the request's query label
+func (s *DummyFlightSQLServer) logFor(ctx context.Context) *slog.Logger {
+	if label := queryLabel(ctx); label != "" {
+		return s.log().With("query_label", label)
+	}
+	return s.log()
+}
+
+func (s *DummyFlightSQLServer) queryLabelUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(s.withQueryLabel(ctx), req)
+}
+
+func (s *DummyFlightSQLServer) queryLabelStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: s.withQueryLabel(ss.Context())})
+}
+
+// contextStream is a grpc.ServerStream with a replaced context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *contextStream) Context() context.Context {
+	return c.ctx
+}
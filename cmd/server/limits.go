@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxRowsHeader is the request header a client sets on GetFlightInfo to cap
+// the number of rows the statement returns
+const maxRowsHeader = "x-max-rows"
+
+// pageSizeHeader is the request header a client sets on GetFlightInfo to
+// fetch the results in pages of this many rows, one page per DoGet
+const pageSizeHeader = "x-page-size"
+
+// columnsHeader is the request header a client sets on GetFlightInfo to a
+// comma separated list of the result columns to return
+const columnsHeader = "x-columns"
+
+// deferSchemaHeader is the request header a client sets to "true" on
+// GetFlightInfo to skip the schema probe, see ServerConfig.DeferResultSchema
+const deferSchemaHeader = "x-defer-schema"
+
+// deferSchemaFromContext reports whether the client asked for the result
+// schema to be left out of the FlightInfo
+func deferSchemaFromContext(ctx context.Context) (bool, error) {
+	deferSchema, _, err := boolHeader(ctx, deferSchemaHeader)
+	return deferSchema, err
+}
+
+// boolHeader parses a request header that must be a boolean when set. ok
+// is false when it is missing.
+func boolHeader(ctx context.Context, header string) (value, ok bool, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(header)
+	if len(values) == 0 {
+		return false, false, nil
+	}
+
+	value, err = strconv.ParseBool(values[0])
+	if err != nil {
+		return false, false, status.Errorf(codes.InvalidArgument, "invalid %s value %q: must be a boolean", header, values[0])
+	}
+	return value, true, nil
+}
+
+// maxRowsFromContext returns the row cap requested by the client, or 0 if
+// none was requested
+func maxRowsFromContext(ctx context.Context) (int64, error) {
+	return positiveHeader(ctx, maxRowsHeader)
+}
+
+// pageSizeFromContext returns the page size requested by the client, or 0
+// if the results are not paged
+func pageSizeFromContext(ctx context.Context) (int64, error) {
+	return positiveHeader(ctx, pageSizeHeader)
+}
+
+// columnsFromContext returns the result columns requested by the client, or
+// nil if it wants all of them
+func columnsFromContext(ctx context.Context) ([]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(columnsHeader)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	columns := strings.Split(values[0], ",")
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+		if columns[i] == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s value %q: column names must not be empty", columnsHeader, values[0])
+		}
+	}
+	return columns, nil
+}
+
+// positiveHeader parses a request header that must be a positive integer
+// when set, returning 0 when it is missing
+func positiveHeader(ctx context.Context, header string) (int64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(header)
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || value <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s value %q: must be a positive integer", header, values[0])
+	}
+	return value, nil
+}
+
+// limitQuery wraps query so that the backend returns at most maxRows rows
+func limitQuery(query string, maxRows int64) string {
+	return fmt.Sprintf("SELECT * FROM (%s\n) LIMIT %d", trimQuery(query), maxRows)
+}
+
+// projectQuery wraps query so that the backend returns only the given
+// columns, in that order
+func (s *DummyFlightSQLServer) projectQuery(query string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = s.qualifiedName(column)
+	}
+	return fmt.Sprintf("SELECT %s FROM (%s\n)", strings.Join(quoted, ", "), trimQuery(query))
+}
+
+// schemaQuery wraps query so that the backend returns its schema without
+// executing it
+func schemaQuery(query string) string {
+	return fmt.Sprintf("SELECT * FROM (%s\n) WHERE 1=0", trimQuery(query))
+}
+
+// trimQuery strips the trailing semicolons and whitespace that would make
+// query invalid as a subquery. The closing parenthesis of the wrappers goes
+// on its own line so that a trailing line comment can't swallow it.
+func trimQuery(query string) string {
+	return strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+}
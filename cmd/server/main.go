@@ -1,95 +1,235 @@
 package main
 
 import (
+	"cmp"
+	"container/list"
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"log"
-	"net"
+	"log/slog"
+	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/apache/arrow-adbc/go/adbc"
-	"github.com/apache/arrow-adbc/go/adbc/drivermgr"
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/flight"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql/schema_ref"
-	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // DummyFlightSQLServer implements the FlightSQLServer interface
 type DummyFlightSQLServer struct {
 	flightsql.BaseServer
-	db      *adbc.Database
-	queries map[string]string // map of statement handle to query
+	db  *adbc.Database
+	cfg ServerConfig
+
+	tlsConfig *tls.Config
+	cache     *resultCache // nil when result caching is disabled
+	preloads  *resultCache // results kept by Preload, nil when disabled
+	replays   *resultCache // fetched results by handle, for cache-replay
+	pool      *connPool    // nil when connections are not pooled
+
+	logLevel slog.LevelVar
+	logger   *slog.Logger
+	settings atomic.Pointer[reloadableSettings]
+
+	mu           sync.Mutex
+	queries      map[string]statementHandle // map of statement handle to query
+	queryLRU     list.List                  // statement handles, least recently used first
+	prepared     map[string]*preparedStatement
+	transactions map[string]*transaction
+	savepoints   map[string]*transaction                   // transaction by savepoint id
+	running      map[string]map[*runningStatement]struct{} // streams by statement handle
+	ingestGroups map[string]*ingestGroup                   // concurrent ingest streams by target table
+	cursors      map[string]*cursor                        // open results of paged statements by handle
+
+	// set by Serve, ready is closed once it has tried to listen
+	flightServer flight.Server
+	httpServer   *http.Server
+	ready        chan struct{}
+	readyOnce    sync.Once
 }
 
-func NewDummyFlightSQLServer() (*DummyFlightSQLServer, error) {
-	drv := &drivermgr.Driver{}
+// New loads the backend driver and sets up the server from cfg alone. It
+// doesn't listen yet, see Serve.
+func New(cfg ServerConfig) (*DummyFlightSQLServer, error) {
+	options, err := cfg.resolveDatabaseOptions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration: %w", err)
+	}
+	cfg.DatabaseOptions = options
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid server configuration: %w", err)
+	}
 
-	db, err := drv.NewDatabase(map[string]string{
-		"driver":          "adbc_driver_sqlite",
-		adbc.OptionKeyURI: "bla.db",
-	})
+	alloc, err := newAllocator(cfg.Allocator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration: %w", err)
+	}
 
+	tlsConfig, err := loadTLSConfig(cfg)
 	if err != nil {
-		fmt.Println(err)
+		return nil, fmt.Errorf("invalid server configuration: %w", err)
+	}
+
+	db, err := newDatabase(cfg, alloc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database: %w", err)
 	}
 
 	ret := &DummyFlightSQLServer{
-		db:      &db,
-		queries: make(map[string]string),
+		db:           &db,
+		cfg:          cfg,
+		queries:      make(map[string]statementHandle),
+		prepared:     make(map[string]*preparedStatement),
+		transactions: make(map[string]*transaction),
+		savepoints:   make(map[string]*transaction),
+		ingestGroups: make(map[string]*ingestGroup),
+		cursors:      make(map[string]*cursor),
+		running:      make(map[string]map[*runningStatement]struct{}),
+		tlsConfig:    tlsConfig,
+		ready:        make(chan struct{}),
+	}
+
+	if cfg.ResultCacheTTL > 0 {
+		ret.cache = newResultCache(cfg.ResultCacheTTL, cfg.ResultCacheMaxBytes)
+	}
+	if cfg.PreloadTTL > 0 {
+		ret.preloads = newResultCache(cfg.PreloadTTL, cfg.PreloadMaxBytes)
+	}
+	if cfg.RepeatFetch == repeatFetchCacheReplay {
+		ret.replays = newResultCache(cmp.Or(cfg.ReplayTTL, defaultReplayTTL), cfg.ReplayMaxBytes)
+	}
+	if cfg.ConnectionPoolSize > 0 {
+		ret.pool = newConnPool(ret.newConnection, cfg.ConnectionPoolSize, cfg.ConnectionMaxIdleTime, cfg.ConnectionMaxLifetime)
+	}
+
+	if cfg.AuditLogFile != "" {
+		sink, err := newFileAuditSink(cfg.AuditLogFile)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		ret.cfg.AuditSink = sink
 	}
 
-	ret.Alloc = memory.DefaultAllocator
-	// for k, v := range SqlInfoResultMap() {
-	// 	ret.RegisterSqlInfo(flightsql.SqlInfo(k), v)
-	// }
+	ret.logger = cfg.Logger
+	if ret.logger == nil {
+		ret.logger = newLogger(os.Stderr, &ret.logLevel)
+	}
+	if err := ret.loadConfig(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid server configuration: %w", err)
+	}
+
+	ret.Alloc = alloc
+	if err := ret.registerSqlInfo(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to register SQL info: %w", err)
+	}
 	return ret, nil
 }
 
 func (s *DummyFlightSQLServer) GetFlightInfoCatalogs(context context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	ticket, err := createCatalogsTicket()
+	if err != nil {
+		return nil, err
+	}
+
 	return &flight.FlightInfo{
 		Endpoint: []*flight.FlightEndpoint{{
-			Ticket: &flight.Ticket{Ticket: desc.Cmd},
+			Ticket: &flight.Ticket{Ticket: ticket},
 		}},
 		FlightDescriptor: desc,
 		Schema:           flight.SerializeSchema(schema_ref.Catalogs, s.Alloc),
 	}, nil
 }
 
-func (s *DummyFlightSQLServer) DoGetCatalogs(context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+func (s *DummyFlightSQLServer) DoGetCatalogs(ctx context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	schema := schema_ref.Catalogs
 
 	if s.db == nil {
 		return nil, nil, fmt.Errorf("database is not initialized")
 	}
 
-	db := *s.db
-	conn, err := db.Open(context.Background())
+	conn, err := s.openConnection(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer conn.Close()
-
-	reader, err := conn.GetObjects(context.Background(), adbc.ObjectDepthCatalogs, nil, nil, nil, nil, nil)
 
+	reader, err := s.getObjects(ctx, conn, adbc.ObjectDepthCatalogs, nil, nil, nil, nil)
 	if err != nil {
+		conn.Close()
 		return nil, nil, err
 	}
 
-	ch := make(chan flight.StreamChunk, 1)
+	filter := s.metadataFilter()
+	ch := make(chan flight.StreamChunk)
 
-	for reader.Next() {
-		rec := reader.Record()
-		record := array.NewRecord(schema, []arrow.Array{rec.Column(0)}, 0)
-		ch <- flight.StreamChunk{Data: record}
-	}
+	go func() {
+		defer close(ch)
+		defer s.recoverStream(ch)
+		defer conn.Close()
+		defer reader.Release()
+
+		// The virtual catalog is listed unless the backend has one of the
+		// same name
+		listVirtual := s.cfg.VirtualCatalog != "" && s.virtualSchemaMatches(ctx, nil, nil)
+		for reader.Next() {
+			rec := reader.RecordBatch()
+
+			// Only catalog_name is projected, the db_schemas column is empty
+			// at catalog depth
+			catalogNameCol, err := asStringColumn(rec.Column(0))
+			if err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+
+			catalogNameBuilder := array.NewStringBuilder(s.Alloc)
+
+			for i := 0; i < int(rec.NumRows()); i++ {
+				if !filter.AllowCatalog(ctx, catalogNameCol.Value(i)) {
+					continue
+				}
+				if catalogNameCol.Value(i) == s.cfg.VirtualCatalog {
+					listVirtual = false
+				}
+				appendNullableString(catalogNameBuilder, catalogNameCol, i)
+			}
 
-	close(ch)
+			catalogNames := catalogNameBuilder.NewArray()
+			catalogNameBuilder.Release()
+
+			record := array.NewRecordBatch(schema, []arrow.Array{catalogNames}, int64(catalogNames.Len()))
+			catalogNames.Release()
+			ch <- flight.StreamChunk{Data: record}
+		}
+
+		if err := reader.Err(); err != nil {
+			ch <- flight.StreamChunk{Err: err}
+			return
+		}
+
+		if listVirtual {
+			bldr := array.NewRecordBuilder(s.Alloc, schema)
+			defer bldr.Release()
+			bldr.Field(0).(*array.StringBuilder).Append(s.cfg.VirtualCatalog)
+			ch <- flight.StreamChunk{Data: bldr.NewRecordBatch()}
+		}
+	}()
 
 	return schema, ch, nil
 }
@@ -107,45 +247,66 @@ func (s *DummyFlightSQLServer) GetFlightInfoSchemas(ctx context.Context, cmd fli
 func (s *DummyFlightSQLServer) DoGetDBSchemas(ctx context.Context, cmd flightsql.GetDBSchemas) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	schema := schema_ref.DBSchemas
 
-	db := *s.db
-	conn, err := db.Open(ctx)
+	conn, err := s.openConnection(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer conn.Close()
 
-	reader, err := conn.GetObjects(ctx, adbc.ObjectDepthDBSchemas, cmd.GetCatalog(), cmd.GetDBSchemaFilterPattern(), nil, nil, nil)
+	catalog, err := s.resolveCatalog(ctx, conn, cmd.GetCatalog())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schemaFilter, schemaMatcher := s.schemaFilter(cmd.GetDBSchemaFilterPattern())
+
+	reader, err := s.getObjects(ctx, conn, adbc.ObjectDepthDBSchemas, catalog, schemaFilter, nil, nil)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
+	filter := s.metadataFilter()
 	ch := make(chan flight.StreamChunk)
 
 	go func() {
 		defer close(ch)
+		defer s.recoverStream(ch)
 		defer reader.Release()
 
 		for reader.Next() {
 			rec := reader.RecordBatch()
 
-			catalogNameBuilder := array.NewStringBuilder(s.Alloc)
-			dbSchemaNameBuilder := array.NewStringBuilder(s.Alloc)
-
-			catalogNameCol := rec.Column(0).(*array.String)
 			schemasCol := rec.Column(1).(*array.List)
 			catalogSchemasValues := schemasCol.ListValues().(*array.Struct)
-			schemaNameCol := catalogSchemasValues.Field(0).(*array.String)
+			nameCols, err := asStringColumns(rec.Column(0), catalogSchemasValues.Field(0))
+			if err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+			catalogNameCol, schemaNameCol := nameCols[0], nameCols[1]
+
+			catalogNameBuilder := array.NewStringBuilder(s.Alloc)
+			dbSchemaNameBuilder := array.NewStringBuilder(s.Alloc)
 
 			for i := 0; i < int(rec.NumRows()); i++ {
 				catalogName := catalogNameCol.Value(i)
+				if !filter.AllowCatalog(ctx, catalogName) {
+					continue
+				}
 
 				start := schemasCol.Offsets()[i]
 				end := schemasCol.Offsets()[i+1] // Fix: use i+1 instead of i
 
 				for j := start; j < end; j++ {
-					schemaName := schemaNameCol.Value(int(j))
-					catalogNameBuilder.Append(catalogName)
+					schemaName := s.reportedSchemaName(schemaNameCol.Value(int(j)))
+					if schemaMatcher != nil && !schemaMatcher.Match(schemaName) {
+						continue
+					}
+					if !filter.AllowSchema(ctx, catalogName, schemaName) {
+						continue
+					}
+					appendNullableString(catalogNameBuilder, catalogNameCol, i)
 					dbSchemaNameBuilder.Append(schemaName)
 				}
 			}
@@ -161,59 +322,127 @@ func (s *DummyFlightSQLServer) DoGetDBSchemas(ctx context.Context, cmd flightsql
 			record := array.NewRecordBatch(schema, cols, int64(cols[0].Len()))
 			ch <- flight.StreamChunk{Data: record}
 		}
+
+		if record := s.virtualSchemasBatch(ctx, cmd, schema); record != nil {
+			ch <- flight.StreamChunk{Data: record}
+		}
 	}()
 
 	return schema, ch, nil
 }
 
 func (s *DummyFlightSQLServer) GetFlightInfoStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
-	fmt.Println("Received query:", cmd.GetQuery())
+	s.logFor(ctx).Debug("planning statement", "query", cmd.GetQuery())
 
 	if s.db == nil {
 		return nil, fmt.Errorf("database is not initialized")
 	}
 
-	// Generate a unique handle for this query
-	handleBytes := make([]byte, 16)
-	_, err := rand.Read(handleBytes)
+	maxRows, err := maxRowsFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	handle := hex.EncodeToString(handleBytes)
 
-	// Store the original query for later retrieval
-	s.queries[handle] = cmd.GetQuery()
+	pageSize, err := pageSizeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	db := *s.db
-	conn, err := db.Open(ctx)
+	shape, err := batchingFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	stmt, err := conn.NewStatement()
+	columns, err := columnsFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
 
-	// Wrap the original query with WHERE 1=0 to get schema without executing the full query
-	schemaQuery := fmt.Sprintf("SELECT * FROM (%s) WHERE 1=0", cmd.GetQuery())
-	err = stmt.SetSqlQuery(schemaQuery)
+	deferSchema, err := deferSchemaFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	deferSchema = deferSchema || s.cfg.DeferResultSchema
 
-	reader, _, err := stmt.ExecuteQuery(ctx)
+	rewritten, err := s.rewriteQuery(ctx, cmd.GetQuery())
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Release()
+	if hasNoResultSet(rewritten) {
+		return nil, errNoResultSet(rewritten)
+	}
+	// Prune the columns before probing, so the advertised schema matches
+	if len(columns) > 0 {
+		rewritten = s.projectQuery(rewritten, columns)
+	}
 
-	schema := reader.Schema()
+	// Push the row cap down to the backend
+	query := rewritten
+	if maxRows > 0 {
+		query = limitQuery(query, maxRows)
+	}
+
+	transactionID := cmd.GetTransactionId()
+	if len(transactionID) > 0 {
+		if pageSize > 0 {
+			return nil, status.Error(codes.InvalidArgument, "paged results are not supported in transactions")
+		}
+		if _, err := s.lookupTransaction(transactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	var handle []byte
+	// Statements in a transaction are bound to this server's connection,
+	// and paged ones to the connection of their cursor, so they can't use
+	// stateless tickets
+	stateless := s.cfg.StatelessTickets && len(transactionID) == 0 && pageSize == 0
+	if stateless {
+		// Embed the query in the handle so no server-side state is needed
+		handle, err = encodeStatelessHandle(s.cfg.TicketSigningKey, query)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Generate a unique handle for this query
+		handleBytes := make([]byte, 16)
+		_, err := rand.Read(handleBytes)
+		if err != nil {
+			return nil, err
+		}
+		handle = []byte(hex.EncodeToString(handleBytes))
+	}
+
+	// Without the probe the schema is left unknown, DoGet discovers it
+	var (
+		schema     *arrow.Schema
+		serialized []byte
+	)
+	if !deferSchema {
+		if schema, err = s.probeSchema(ctx, rewritten); err != nil {
+			return nil, err
+		}
+		if serialized, err = s.serializeSchema(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	if !stateless {
+		// Store the query for later retrieval, together with the schema
+		// the results are advertised with
+		s.storeStatement(statementHandle{
+			handle:        string(handle),
+			query:         query,
+			maxRows:       maxRows,
+			pageSize:      pageSize,
+			batching:      shape,
+			transactionID: string(transactionID),
+			schema:        schema,
+		})
+	}
 
 	// Create a ticket with the statement handle
-	ticket, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+	ticket, err := flightsql.CreateStatementQueryTicket(handle)
 	if err != nil {
 		return nil, err
 	}
@@ -223,19 +452,14 @@ func (s *DummyFlightSQLServer) GetFlightInfoStatement(ctx context.Context, cmd f
 			Ticket: &flight.Ticket{Ticket: ticket},
 		}},
 		FlightDescriptor: desc,
-		Schema:           flight.SerializeSchema(schema, s.Alloc),
+		Schema:           serialized,
 	}, nil
 }
 
-func (s *DummyFlightSQLServer) GetSchemaStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
-	fmt.Println("Getting schema for query:", cmd.GetQuery())
-
-	if s.db == nil {
-		return nil, fmt.Errorf("database is not initialized")
-	}
-
-	db := *s.db
-	conn, err := db.Open(ctx)
+// probeSchema returns the result schema of query, with redacted columns
+// made nullable, without running the full query
+func (s *DummyFlightSQLServer) probeSchema(ctx context.Context, query string) (*arrow.Schema, error) {
+	conn, err := s.openConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -248,8 +472,7 @@ func (s *DummyFlightSQLServer) GetSchemaStatement(ctx context.Context, cmd fligh
 	defer stmt.Close()
 
 	// Wrap the original query with WHERE 1=0 to get schema without executing the full query
-	schemaQuery := fmt.Sprintf("SELECT * FROM (%s) WHERE 1=0", cmd.GetQuery())
-	err = stmt.SetSqlQuery(schemaQuery)
+	err = stmt.SetSqlQuery(schemaQuery(query))
 	if err != nil {
 		return nil, err
 	}
@@ -261,135 +484,613 @@ func (s *DummyFlightSQLServer) GetSchemaStatement(ctx context.Context, cmd fligh
 	defer reader.Release()
 
 	schema := reader.Schema()
-	return &flight.SchemaResult{
-		Schema: flight.SerializeSchema(schema, s.Alloc),
-	}, nil
+	if redactor := s.newColumnRedactor(query, schema); redactor != nil {
+		schema = redactor.schema
+	}
+	if zoner := s.newTimestampZoner(schema); zoner != nil {
+		schema = zoner.schema
+	}
+	return s.newColumnOrder(schema).schema(schema), nil
 }
 
-func (s *DummyFlightSQLServer) DoGetStatement(ctx context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
-	fmt.Println("Executing statement for ticket")
+func (s *DummyFlightSQLServer) GetSchemaStatement(ctx context.Context, cmd flightsql.StatementQuery, desc *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	s.logFor(ctx).Debug("getting statement schema", "query", cmd.GetQuery())
 
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	columns, err := columnsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := s.rewriteQuery(ctx, cmd.GetQuery())
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) > 0 {
+		query = s.projectQuery(query, columns)
+	}
+
+	// The same schema GetFlightInfoStatement advertises
+	schema, err := s.probeSchema(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := s.serializeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &flight.SchemaResult{Schema: serialized}, nil
+}
+
+func (s *DummyFlightSQLServer) DoGetStatement(ctx context.Context, cmd flightsql.StatementQueryTicket) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	// Get the statement handle and look up the query
-	handle := string(cmd.GetStatementHandle())
-	query, exists := s.queries[handle]
-	if !exists {
-		return nil, nil, fmt.Errorf("unknown statement handle: %s", handle)
+	handle := cmd.GetStatementHandle()
+	// Results the statement no longer describes are batched as requested
+	// with DoGet or by default
+	shape, err := s.batchingFor(ctx, batching{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.preloads != nil && strings.HasPrefix(string(handle), preloadTokenPrefix) {
+		schema, batches, ok := s.preloads.get(string(handle))
+		if !ok {
+			return nil, nil, status.Errorf(codes.NotFound, "unknown or expired preload token: %s", handle)
+		}
+		return schema, s.reshape(s.streamBatches(schema, batches, func() {}), shape), nil
+	}
+
+	var (
+		stmt      statementHandle
+		delivered = func() {}
+	)
+	if s.cfg.StatelessTickets && isStatelessHandle(handle) {
+		decoded, err := decodeStatelessHandle(s.cfg.TicketSigningKey, handle)
+		if err != nil {
+			return nil, nil, err
+		}
+		stmt = statementHandle{handle: string(handle), query: decoded}
+	} else {
+		if s.replays != nil {
+			if schema, batches, ok := s.replays.get(string(handle)); ok {
+				return schema, s.reshape(s.streamBatches(schema, batches, func() {}), shape), nil
+			}
+		}
+
+		stored, exists := s.lookupStatement(string(handle))
+		if !exists {
+			return nil, nil, status.Errorf(codes.NotFound, "unknown statement handle: %s", handle)
+		}
+		stmt = stored
+
+		if shape, err = s.batchingFor(ctx, stmt.batching); err != nil {
+			return nil, nil, err
+		}
+
+		if stmt.pageSize > 0 {
+			start := time.Now()
+			schema, ch, err := s.streamPage(ctx, stmt)
+			return schema, s.auditStream(ctx, "DoGetStatement", stmt.query, start, ch, err), err
+		}
+
+		switch s.cfg.RepeatFetch {
+		case repeatFetchReexecute:
+			// The handle is kept, every fetch runs the query again
+		case repeatFetchCacheReplay:
+			// The handle is dropped once the result is kept for replay
+			start := time.Now()
+			schema, ch, err := s.streamStatement(ctx, stmt, delivered)
+			if err == nil {
+				ch = s.reshape(s.recordReplay(string(handle), schema, ch, func() { s.consumeStatement(string(handle)) }), shape)
+			}
+			return schema, s.auditStream(ctx, "DoGetStatement", stmt.query, start, ch, err), err
+		default:
+			// The handle stays valid until results start flowing, so a
+			// client retrying a fetch that failed before any data was sent
+			// re-executes
+			delivered = sync.OnceFunc(func() { s.consumeStatement(string(handle)) })
+		}
 	}
 
-	fmt.Println("Query:", query)
+	start := time.Now()
+	schema, ch, err := s.streamStatement(ctx, stmt, delivered)
+	if err == nil {
+		ch = s.reshape(ch, shape)
+	}
+	return schema, s.auditStream(ctx, "DoGetStatement", stmt.query, start, ch, err), err
+}
+
+// streamStatement executes a statement resolved from its ticket and streams
+// the results. delivered is called once data has been sent.
+func (s *DummyFlightSQLServer) streamStatement(ctx context.Context, stored statementHandle, delivered func()) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	handle, query, maxRows, transactionID := stored.handle, stored.query, stored.maxRows, stored.transactionID
+	advertised := stored.schema // nil when unknown
+	started := time.Now()
+
+	s.logFor(ctx).Debug("executing statement", "query", query)
 
 	if s.db == nil {
 		return nil, nil, fmt.Errorf("database is not initialized")
 	}
+	// Reject statements without rows before they run, so that their side
+	// effects don't happen behind an error
+	if hasNoResultSet(query) {
+		return nil, nil, errNoResultSet(query)
+	}
 
-	db := *s.db
-	conn, err := db.Open(ctx)
+	options, err := s.statementOptionsFromContext(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer conn.Close()
+
+	// Options and session namespaces may change the result and transactions
+	// may see uncommitted data, so only plain statements are cached
+	cacheable := s.cache != nil && len(options) == 0 && transactionID == "" && !hasSessionState(ctx) && isReadQuery(query)
+	if cacheable {
+		if schema, batches, ok := s.cache.get(cacheKey(query)); ok {
+			return schema, s.streamBatches(schema, batches, delivered), nil
+		}
+	}
+
+	var (
+		conn     adbc.Connection
+		release  func()          // gives the connection back
+		canceled <-chan struct{} // closed when the transaction ends mid-read
+	)
+	if transactionID != "" {
+		txn, err := s.lookupTransaction([]byte(transactionID))
+		if err != nil {
+			return nil, nil, err
+		}
+		readCtx, done, err := txn.startRead(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx, conn, release, canceled = readCtx, txn.conn, done, readCtx.Done()
+	} else {
+		conn, err = s.openConnection(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		release = func() { conn.Close() }
+	}
 
 	stmt, err := conn.NewStatement()
 	if err != nil {
+		release()
 		return nil, nil, err
 	}
-	defer stmt.Close()
 
-	err = stmt.SetSqlQuery(query)
+	if stored.substrait != nil {
+		err = substraitError(stmt.SetSubstraitPlan(stored.substrait))
+	} else {
+		err = stmt.SetSqlQuery(query)
+	}
 	if err != nil {
+		stmt.Close()
+		release()
 		return nil, nil, err
 	}
 
-	reader, _, err := stmt.ExecuteQuery(ctx)
-	if err != nil {
+	if err := applyStatementOptions(stmt, options); err != nil {
+		stmt.Close()
+		release()
 		return nil, nil, err
 	}
 
-	schema := reader.Schema()
+	// KillStatement stops the stream through killed, and cancels the
+	// execution for drivers that honor the context
+	ctx, killed, untrack := s.trackRunning(ctx, string(handle))
+
+	// With heartbeats and a known schema the stream starts before the
+	// statement runs, so that heartbeats cover a slow execution too. Its
+	// batches then carry the advertised schema.
+	interval := s.cfg.HeartbeatInterval
+	early := interval > 0 && advertised != nil
+
+	var (
+		reader    array.RecordReader
+		schema    *arrow.Schema
+		order     *columnOrder
+		redactor  *columnRedactor
+		zoner     *timestampZoner
+		collector *resultCollector
+		relabel   bool // batches are relabeled with the advertised schema
+	)
+	// open runs the statement and prepares its result for streaming. When
+	// it fails, everything the stream would have released is released.
+	open := func() error {
+		execute := func() (err error) {
+			reader, _, err = stmt.ExecuteQuery(ctx)
+			return err
+		}
+		var err error
+		// Nothing has been streamed yet, but a failed statement may have
+		// aborted the transaction, so only statements outside of one are retried
+		if transactionID == "" {
+			err = s.retryTransient(ctx, "DoGetStatement", execute)
+		} else {
+			err = execute()
+		}
+		if err != nil {
+			if stored.substrait != nil {
+				err = substraitError(err)
+			}
+			untrack()
+			stmt.Close()
+			release()
+			return err
+		}
+
+		if s.cache != nil && !cacheable {
+			// The statement may have changed any cached result
+			s.cache.invalidate()
+		}
+
+		fail := func(err error) error {
+			untrack()
+			reader.Release()
+			stmt.Close()
+			release()
+			return err
+		}
+
+		schema = reader.Schema()
+		// Statements not caught by hasNoResultSet come back without columns
+		if schema.NumFields() == 0 {
+			return fail(errNoResultSet(query))
+		}
+		order = s.newColumnOrder(schema)
+		if s.cfg.ValidateResultSchema && advertised != nil {
+			if err := checkAdvertisedSchema(order.schema(schema), advertised); err != nil {
+				return fail(err)
+			}
+		}
+		// Redaction happens before caching, so cached results are redacted too
+		redactor = s.newColumnRedactor(query, schema)
+		if redactor != nil {
+			schema = redactor.schema
+		}
+		zoner = s.newTimestampZoner(schema)
+		if zoner != nil {
+			schema = zoner.schema
+		}
+		schema = order.schema(schema)
+		if early {
+			// The stream already started with the advertised schema
+			if err := checkAdvertisedSchema(schema, advertised); err != nil {
+				return fail(err)
+			}
+			relabel = !schema.Equal(advertised)
+			schema = advertised
+		}
+		if cacheable {
+			collector = newResultCollector(s.cache, cacheKey(query), schema)
+		}
+		return nil
+	}
+
 	ch := make(chan flight.StreamChunk)
 
+	stream := func() {
+		defer untrack()
+
+		// The stream reads through the wrapper, while releaseAll frees the
+		// backend reader
+		batches := array.RecordReader(reader)
+		if interval > 0 {
+			batches = &heartbeatReader{RecordReader: reader, schema: schema, alloc: s.Alloc, interval: interval, ch: ch}
+		}
+
+		// The statement and connection back the reader, so they are only
+		// released once streaming is done, or early when the transaction
+		// the read belongs to ends or the statement is killed
+		var cleanup sync.Once
+		releaseAll := func() {
+			cleanup.Do(func() {
+				reader.Release()
+				stmt.Close()
+				release()
+			})
+		}
+		defer releaseAll()
+		defer collector.discard()
+
+		abort := func(err error) {
+			releaseAll()
+			ch <- flight.StreamChunk{Err: err}
+		}
+		transactionEnded := status.Error(codes.Aborted, "read canceled because its transaction ended")
+		statementKilled := status.Errorf(codes.Canceled, "statement %s was killed", handle)
+
+		// Batches are sent as soon as they are read so that the server never
+		// holds more than one of them, which matters for large binary values
+		progress := &progressTracker{interval: s.cfg.ProgressInterval, stats: s.cfg.StreamStats, start: started}
+		var sent int64
+		for batches.Next() {
+			select {
+			case <-canceled:
+				abort(transactionEnded)
+				return
+			case <-killed:
+				abort(statementKilled)
+				return
+			default:
+			}
+
+			rec := reader.RecordBatch()
+			if err := checkBatchSchema(rec, reader.Schema()); err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+
+			if maxRows > 0 && sent+rec.NumRows() > maxRows {
+				rec = rec.NewSlice(0, maxRows-sent)
+			} else {
+				rec.Retain()
+			}
+			if redactor != nil {
+				rec = redactor.apply(rec)
+			}
+			if zoner != nil {
+				rec = zoner.apply(rec)
+			}
+			rec = order.apply(rec)
+			if relabel {
+				rec = withSchema(rec, schema)
+			}
+
+			if err := checkCellSizes(rec, s.cfg.MaxCellBytes); err != nil {
+				rec.Release()
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+
+			collector.add(rec)
+			sent += rec.NumRows()
+			select {
+			case ch <- progress.chunk(rec):
+				delivered()
+			case <-canceled:
+				rec.Release()
+				abort(transactionEnded)
+				return
+			case <-killed:
+				rec.Release()
+				abort(statementKilled)
+				return
+			}
+
+			// Stop reading from the backend once the cap is reached
+			if maxRows > 0 && sent >= maxRows {
+				break
+			}
+		}
+
+		if err := reader.Err(); err != nil {
+			ch <- flight.StreamChunk{Err: err}
+			return
+		}
+		collector.store()
+		delivered()
+
+		progress.warnings = statementWarnings(stmt)
+		if final, ok := progress.final(schema, s.Alloc); ok {
+			ch <- final
+		}
+	}
+
+	if early {
+		go func() {
+			defer close(ch)
+			defer s.recoverStream(ch)
+
+			if err := heartbeat(ch, s.Alloc, advertised, interval, open); err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+			stream()
+		}()
+		return advertised, ch, nil
+	}
+
+	if err := open(); err != nil {
+		return nil, nil, err
+	}
 	go func() {
 		defer close(ch)
-		defer reader.Release()
-		for reader.Next() {
-			rec := reader.RecordBatch()
-			rec.Retain()
-			ch <- flight.StreamChunk{Data: rec}
+		defer s.recoverStream(ch)
+		stream()
+	}()
+	return schema, ch, nil
+}
+
+// streamBatches streams an already materialized result, taking ownership of
+// the batches. delivered is called once the stream has started.
+func (s *DummyFlightSQLServer) streamBatches(schema *arrow.Schema, batches []arrow.RecordBatch, delivered func()) <-chan flight.StreamChunk {
+	ch := make(chan flight.StreamChunk)
+
+	go func() {
+		defer close(ch)
+		defer s.recoverStream(ch)
+
+		progress := &progressTracker{interval: s.cfg.ProgressInterval, stats: s.cfg.StreamStats, start: time.Now()}
+		for _, rec := range batches {
+			ch <- progress.chunk(rec)
+			delivered()
+		}
+		delivered()
+		if final, ok := progress.final(schema, s.Alloc); ok {
+			ch <- final
 		}
 	}()
 
-	return schema, ch, nil
+	return ch
 }
 
 func (s *DummyFlightSQLServer) GetFlightInfoTables(ctx context.Context, cmd flightsql.GetTables, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	schema := schema_ref.Tables
+	if cmd.GetIncludeSchema() {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
+
 	return &flight.FlightInfo{
 		Endpoint: []*flight.FlightEndpoint{{
 			Ticket: &flight.Ticket{Ticket: desc.Cmd},
 		}},
 		FlightDescriptor: desc,
-		Schema:           flight.SerializeSchema(schema_ref.Tables, s.Alloc),
+		Schema:           flight.SerializeSchema(schema, s.Alloc),
 	}, nil
 }
 
 func (s *DummyFlightSQLServer) DoGetTables(ctx context.Context, cmd flightsql.GetTables) (*arrow.Schema, <-chan flight.StreamChunk, error) {
 	schema := schema_ref.Tables
+	if cmd.GetIncludeSchema() {
+		schema = schema_ref.TablesWithIncludedSchema
+	}
 
-	db := *s.db
-	conn, err := db.Open(ctx)
+	conn, err := s.openConnection(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer conn.Close()
 
-	// Use GetObjects with table depth to get table metadata
-	reader, err := conn.GetObjects(ctx, adbc.ObjectDepthTables, cmd.GetCatalog(), cmd.GetDBSchemaFilterPattern(), cmd.GetTableNameFilterPattern(), nil, cmd.GetTableTypes())
+	tableTypes := cmd.GetTableTypes()
+	if s.cfg.StrictTableTypes {
+		if err := checkTableTypes(ctx, conn, tableTypes); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	if s.cfg.NormalizeTableTypes {
+		tableTypes = expandTableTypes(tableTypes)
+	}
+
+	catalog, err := s.resolveCatalog(ctx, conn, cmd.GetCatalog())
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	schemaFilter, schemaMatcher := s.schemaFilter(cmd.GetDBSchemaFilterPattern())
+	tableFilter, tableMatcher := s.tableFilter(cmd.GetTableNameFilterPattern())
+
+	// Use GetObjects with table depth to get table metadata, falling back to
+	// information_schema for drivers that lack it
+	reader, err := s.getObjects(ctx, conn, adbc.ObjectDepthTables, catalog, schemaFilter, tableFilter, tableTypes)
 	if err != nil {
+		conn.Close()
 		return nil, nil, err
 	}
 
+	// Comments and column flags are looked up per vendor alongside the schemas
+	var vendor string
+	if cmd.GetIncludeSchema() {
+		if vendor, err = backendVendor(ctx, conn); err != nil {
+			reader.Release()
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	filter := s.metadataFilter()
 	ch := make(chan flight.StreamChunk)
 
 	go func() {
 		defer close(ch)
+		defer s.recoverStream(ch)
+		// The connection stays open while streaming since the table schemas
+		// are looked up lazily when include_schema is requested
+		defer conn.Close()
 		defer reader.Release()
 
 		for reader.Next() {
 			rec := reader.RecordBatch()
 
-			catalogNameBuilder := array.NewStringBuilder(s.Alloc)
-			dbSchemaNameBuilder := array.NewStringBuilder(s.Alloc)
-			tableNameBuilder := array.NewStringBuilder(s.Alloc)
-			tableTypeBuilder := array.NewStringBuilder(s.Alloc)
-
-			catalogNameCol := rec.Column(0).(*array.String)
 			schemasCol := rec.Column(1).(*array.List)
 			catalogSchemasValues := schemasCol.ListValues().(*array.Struct)
-			schemaNameCol := catalogSchemasValues.Field(0).(*array.String)
 			tablesCol := catalogSchemasValues.Field(1).(*array.List)
 			schemaTablesValues := tablesCol.ListValues().(*array.Struct)
-			tableNameCol := schemaTablesValues.Field(0).(*array.String)
-			tableTypeCol := schemaTablesValues.Field(1).(*array.String)
+			nameCols, err := asStringColumns(rec.Column(0), catalogSchemasValues.Field(0),
+				schemaTablesValues.Field(0), schemaTablesValues.Field(1))
+			if err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+			catalogNameCol, schemaNameCol, tableNameCol, tableTypeCol := nameCols[0], nameCols[1], nameCols[2], nameCols[3]
+
+			catalogNameBuilder := array.NewStringBuilder(s.Alloc)
+			dbSchemaNameBuilder := array.NewStringBuilder(s.Alloc)
+			tableNameBuilder := array.NewStringBuilder(s.Alloc)
+			tableTypeBuilder := array.NewStringBuilder(s.Alloc)
+			tableSchemaBuilder := array.NewBinaryBuilder(s.Alloc, arrow.BinaryTypes.Binary)
 
 			for i := 0; i < int(rec.NumRows()); i++ {
 				catalogName := catalogNameCol.Value(i)
+				if !filter.AllowCatalog(ctx, catalogName) {
+					continue
+				}
 
 				schemaStart := schemasCol.Offsets()[i]
 				schemaEnd := schemasCol.Offsets()[i+1]
 
 				for j := schemaStart; j < schemaEnd; j++ {
+					// Lookups use the backend's name, clients see the reported one
 					schemaName := schemaNameCol.Value(int(j))
+					reportedSchema := s.reportedSchemaName(schemaName)
+					if schemaMatcher != nil && !schemaMatcher.Match(reportedSchema) {
+						continue
+					}
+					if !filter.AllowSchema(ctx, catalogName, reportedSchema) {
+						continue
+					}
 
 					tableStart := tablesCol.Offsets()[j]
 					tableEnd := tablesCol.Offsets()[j+1]
 
 					for k := tableStart; k < tableEnd; k++ {
 						tableName := tableNameCol.Value(int(k))
+						if tableMatcher != nil && !tableMatcher.Match(tableName) {
+							continue
+						}
+						if !filter.AllowTable(ctx, catalogName, reportedSchema, tableName) {
+							continue
+						}
 						tableType := tableTypeCol.Value(int(k))
-
-						catalogNameBuilder.Append(catalogName)
-						dbSchemaNameBuilder.Append(schemaName)
+						if s.cfg.NormalizeTableTypes {
+							tableType = normalizeTableType(tableType)
+						}
+
+						appendNullableString(catalogNameBuilder, catalogNameCol, i)
+						if schemaNameCol.IsNull(int(j)) {
+							dbSchemaNameBuilder.AppendNull()
+						} else {
+							dbSchemaNameBuilder.Append(reportedSchema)
+						}
 						tableNameBuilder.Append(tableName)
 						tableTypeBuilder.Append(tableType)
+
+						if cmd.GetIncludeSchema() {
+							tableSchema, err := conn.GetTableSchema(ctx, optionalName(catalogName), optionalName(schemaName), tableName)
+							if err != nil {
+								ch <- flight.StreamChunk{Err: err}
+								return
+							}
+							comments, err := lookupComments(ctx, conn, vendor, catalogName, schemaName, tableName)
+							if err != nil {
+								ch <- flight.StreamChunk{Err: err}
+								return
+							}
+							flags, err := lookupColumnFlags(ctx, conn, vendor, catalogName, schemaName, tableName)
+							if err != nil {
+								ch <- flight.StreamChunk{Err: err}
+								return
+							}
+							tableSchema = withColumnFlags(withComments(tableSchema, comments), flags)
+							tableSchemaBuilder.Append(flight.SerializeSchema(tableSchema, s.Alloc))
+						}
 					}
 				}
 			}
@@ -400,34 +1101,143 @@ func (s *DummyFlightSQLServer) DoGetTables(ctx context.Context, cmd flightsql.Ge
 				tableNameBuilder.NewArray(),
 				tableTypeBuilder.NewArray(),
 			}
+			if cmd.GetIncludeSchema() {
+				cols = append(cols, tableSchemaBuilder.NewArray())
+			}
 
 			catalogNameBuilder.Release()
 			dbSchemaNameBuilder.Release()
 			tableNameBuilder.Release()
 			tableTypeBuilder.Release()
+			tableSchemaBuilder.Release()
 
 			record := array.NewRecordBatch(schema, cols, int64(cols[0].Len()))
 			ch <- flight.StreamChunk{Data: record}
 		}
+
+		record, err := s.virtualTablesBatch(ctx, cmd, schema)
+		if err != nil {
+			ch <- flight.StreamChunk{Err: err}
+			return
+		}
+		if record != nil {
+			ch <- flight.StreamChunk{Data: record}
+		}
 	}()
 
 	return schema, ch, nil
 }
 
-func main() {
-	addr := "localhost"
-	port := 33333
+func (s *DummyFlightSQLServer) GetFlightInfoTableTypes(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	return &flight.FlightInfo{
+		Endpoint: []*flight.FlightEndpoint{{
+			Ticket: &flight.Ticket{Ticket: desc.Cmd},
+		}},
+		FlightDescriptor: desc,
+		Schema:           flight.SerializeSchema(schema_ref.TableTypes, s.Alloc),
+	}, nil
+}
 
-	server := flight.NewServerWithMiddleware(nil)
+func (s *DummyFlightSQLServer) DoGetTableTypes(ctx context.Context) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	schema := schema_ref.TableTypes
 
-	s, _ := NewDummyFlightSQLServer()
-	server.RegisterFlightService(flightsql.NewFlightServer(s))
-	server.Init(net.JoinHostPort(addr, strconv.Itoa(port)))
-	server.SetShutdownOnSignals(os.Interrupt, os.Kill)
+	if s.db == nil {
+		return nil, nil, fmt.Errorf("database is not initialized")
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	reader, err := conn.GetTableTypes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	fmt.Printf("Dummy FlightSQL server listening on %s\n", addr)
+	ch := make(chan flight.StreamChunk)
+
+	go func() {
+		defer close(ch)
+		defer s.recoverStream(ch)
+		defer reader.Release()
+
+		// Normalized types are deduplicated across batches since several
+		// backend types may map to the same canonical value
+		seen := make(map[string]bool)
+
+		for reader.Next() {
+			rec := reader.RecordBatch()
+
+			tableTypeCol, err := asStringColumn(rec.Column(0))
+			if err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+
+			tableTypeBuilder := array.NewStringBuilder(s.Alloc)
+
+			for i := 0; i < tableTypeCol.Len(); i++ {
+				tableType := tableTypeCol.Value(i)
+				if s.cfg.NormalizeTableTypes {
+					tableType = normalizeTableType(tableType)
+					if seen[tableType] {
+						continue
+					}
+					seen[tableType] = true
+				}
+				tableTypeBuilder.Append(tableType)
+			}
+
+			cols := []arrow.Array{tableTypeBuilder.NewArray()}
+			tableTypeBuilder.Release()
+
+			record := array.NewRecordBatch(schema, cols, int64(cols[0].Len()))
+			ch <- flight.StreamChunk{Data: record}
+		}
+	}()
+
+	return schema, ch, nil
+}
+
+// appendNullableString copies the i-th value of src into b, keeping nulls
+// as nulls rather than turning them into empty strings
+func appendNullableString(b *array.StringBuilder, src stringColumn, i int) {
+	if src.IsNull(i) {
+		b.AppendNull()
+		return
+	}
+	b.Append(src.Value(i))
+}
+
+// optionalName converts a catalog or schema name from GetObjects into the
+// optional form expected by ADBC, treating the empty string as unset.
+func optionalName(name string) *string {
+	if name == "" {
+		return nil
+	}
+	return &name
+}
+
+// listenAddr is where the standalone server accepts Flight SQL clients
+const listenAddr = "localhost:33333"
+
+func main() {
+	s, err := New(DefaultServerConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		s.Shutdown()
+	}()
 
-	if err := server.Serve(); err != nil {
+	if err := s.Serve(listenAddr); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkpointStatements are the per-vendor statements that flush the backend
+// to durable storage
+var checkpointStatements = map[string]string{
+	vendorDuckDB: "CHECKPOINT",
+	vendorSQLite: "PRAGMA wal_checkpoint(FULL)",
+}
+
+// maintenanceOperations maps the operations accepted by the Maintain action
+// to their per-vendor statements. DuckDB has no VACUUM that reclaims space,
+// a checkpoint is the closest equivalent.
+var maintenanceOperations = map[string]map[string]string{
+	"vacuum": {
+		vendorDuckDB: "CHECKPOINT",
+		vendorSQLite: "VACUUM",
+	},
+	"analyze": {
+		vendorDuckDB: "ANALYZE",
+		vendorSQLite: "ANALYZE",
+	},
+}
+
+// checkpoint flushes the backend's write-ahead log into the database file
+func (s *DummyFlightSQLServer) checkpoint(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.cfg.ReadOnly {
+		return nil, status.Error(codes.FailedPrecondition, "checkpoint is not allowed in read-only mode")
+	}
+
+	if err := s.runVendorStatement(ctx, "checkpoint", checkpointStatements); err != nil {
+		return nil, err
+	}
+	return [][]byte{[]byte("checkpoint completed")}, nil
+}
+
+// maintain runs the maintenance operation named in the action body
+func (s *DummyFlightSQLServer) maintain(ctx context.Context, body []byte) ([][]byte, error) {
+	operation := strings.ToLower(strings.TrimSpace(string(body)))
+	statements, ok := maintenanceOperations[operation]
+	if !ok {
+		known := make([]string, 0, len(maintenanceOperations))
+		for name := range maintenanceOperations {
+			known = append(known, name)
+		}
+		sort.Strings(known)
+		return nil, status.Errorf(codes.InvalidArgument, "unknown maintenance operation %q, expected one of: %s", operation, strings.Join(known, ", "))
+	}
+
+	if s.cfg.ReadOnly {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is not allowed in read-only mode", operation)
+	}
+
+	if err := s.runVendorStatement(ctx, operation, statements); err != nil {
+		return nil, err
+	}
+	return [][]byte{[]byte(operation + " completed")}, nil
+}
+
+// runVendorStatement executes the statement registered for the backend's
+// vendor, failing with Unimplemented for backends without one
+func (s *DummyFlightSQLServer) runVendorStatement(ctx context.Context, operation string, statements map[string]string) error {
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	query, ok := statements[vendor]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "%s is not supported for backend %q", operation, vendor)
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return err
+	}
+
+	// Maintenance statements may return a status row, so run them as
+	// queries and drain the result
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", operation, err)
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+	}
+	if err := reader.Err(); err != nil {
+		return fmt.Errorf("%s failed: %w", operation, err)
+	}
+	return nil
+}
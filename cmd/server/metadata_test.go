@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow-adbc/go/adbc"
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql/schema_ref"
 	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
 	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 func TestDoGetCatalogs(t *testing.T) {
@@ -78,6 +90,182 @@ func TestDoGetCatalogs(t *testing.T) {
 	}
 }
 
+// attachDatabase wraps a SQLite backend so that every connection attaches
+// extra databases, since ATTACH only applies to the connection it ran on
+type attachDatabase struct {
+	adbc.Database
+	attach map[string]string // schema name to database file
+}
+
+func (d *attachDatabase) Open(ctx context.Context) (adbc.Connection, error) {
+	conn, err := d.Database.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for name, path := range d.attach {
+		if err := stmt.SetSqlQuery(fmt.Sprintf("ATTACH DATABASE '%s' AS %s", path, name)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if _, err := stmt.ExecuteUpdate(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func TestDoGetCatalogs_MultipleCatalogs(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	var db adbc.Database = &attachDatabase{
+		Database: *server.db,
+		attach: map[string]string{
+			"first":  filepath.Join(t.TempDir(), "first.db"),
+			"second": filepath.Join(t.TempDir(), "second.db"),
+		},
+	}
+	server.db = &db
+
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	server.Alloc = mem
+
+	_, streamCh, err := server.DoGetCatalogs(context.Background())
+	if err != nil {
+		t.Fatalf("DoGetCatalogs failed: %v", err)
+	}
+
+	var catalogs []string
+	for chunk := range streamCh {
+		if chunk.Err != nil {
+			t.Fatalf("Stream error: %v", chunk.Err)
+		}
+		if chunk.Data.NumCols() != 1 {
+			t.Errorf("Expected 1 column, got %d", chunk.Data.NumCols())
+		}
+		catalogCol := chunk.Data.Column(0).(*array.String)
+		for i := 0; i < catalogCol.Len(); i++ {
+			catalogs = append(catalogs, catalogCol.Value(i))
+		}
+		chunk.Data.Release()
+	}
+
+	for _, expected := range []string{"main", "first", "second"} {
+		if !slices.Contains(catalogs, expected) {
+			t.Errorf("Expected catalog %q, got %v", expected, catalogs)
+		}
+	}
+
+	// Every buffer built for the projection must have been released
+	mem.AssertSize(t, 0)
+}
+
+// dictionaryObjects returns a GetObjects result listing catalogs with
+// dictionary-encoded names
+func dictionaryObjects(catalogs []string) (array.RecordReader, error) {
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "catalog_name", Type: dictType, Nullable: true},
+		{Name: "catalog_db_schemas", Type: arrow.Null, Nullable: true},
+	}, nil)
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer bldr.Release()
+
+	names := bldr.Field(0).(*array.BinaryDictionaryBuilder)
+	for _, name := range catalogs {
+		if err := names.AppendString(name); err != nil {
+			return nil, err
+		}
+	}
+	bldr.Field(1).(*array.NullBuilder).AppendEmptyValues(len(catalogs))
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	return array.NewRecordReader(schema, []arrow.RecordBatch{rec})
+}
+
+func TestDoGetCatalogs_DictionaryEncoded(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	catalogs := []string{"main", "aux", "main"}
+	useFaultyDatabase(server, &faultyDatabase{getObjects: func() (array.RecordReader, error) {
+		return dictionaryObjects(catalogs)
+	}})
+
+	_, streamCh, err := server.DoGetCatalogs(context.Background())
+	if err != nil {
+		t.Fatalf("DoGetCatalogs failed: %v", err)
+	}
+
+	var got []string
+	for chunk := range streamCh {
+		if chunk.Err != nil {
+			t.Fatalf("Stream error: %v", chunk.Err)
+		}
+		catalogCol := chunk.Data.Column(0).(*array.String)
+		for i := 0; i < catalogCol.Len(); i++ {
+			got = append(got, catalogCol.Value(i))
+		}
+		chunk.Data.Release()
+	}
+
+	if !slices.Equal(got, catalogs) {
+		t.Errorf("Expected decoded catalogs %v, got %v", catalogs, got)
+	}
+}
+
+func TestGetFlightInfoCatalogs(t *testing.T) {
+	server := &DummyFlightSQLServer{}
+	server.Alloc = memory.DefaultAllocator
+
+	// An empty descriptor command must not leak into the ticket
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD}
+
+	flightInfo, err := server.GetFlightInfoCatalogs(context.Background(), desc)
+	if err != nil {
+		t.Fatalf("GetFlightInfoCatalogs failed: %v", err)
+	}
+
+	if len(flightInfo.Endpoint) != 1 {
+		t.Fatalf("Expected 1 endpoint, got %d", len(flightInfo.Endpoint))
+	}
+
+	ticket := flightInfo.Endpoint[0].Ticket.GetTicket()
+	if len(ticket) == 0 {
+		t.Fatal("Expected a non-empty catalogs ticket")
+	}
+
+	var anycmd anypb.Any
+	if err := proto.Unmarshal(ticket, &anycmd); err != nil {
+		t.Fatalf("Failed to decode catalogs ticket: %v", err)
+	}
+
+	cmd, err := anycmd.UnmarshalNew()
+	if err != nil {
+		t.Fatalf("Failed to unmarshal catalogs ticket command: %v", err)
+	}
+
+	if _, ok := cmd.(*pb.CommandGetCatalogs); !ok {
+		t.Errorf("Expected ticket to carry CommandGetCatalogs, got %T", cmd)
+	}
+}
+
 func TestDoGetDBSchemas(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -197,6 +385,104 @@ func TestDoGetDBSchemas(t *testing.T) {
 	}
 }
 
+// schemaAllowlist is a MetadataFilter that only exposes the given schemas
+type schemaAllowlist []string
+
+func (a schemaAllowlist) AllowCatalog(context.Context, string) bool { return true }
+
+func (a schemaAllowlist) AllowSchema(_ context.Context, _, schema string) bool {
+	return slices.Contains(a, schema)
+}
+
+func (a schemaAllowlist) AllowTable(context.Context, string, string, string) bool { return true }
+
+func TestDoGetDBSchemas_MetadataFilter(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestSchemas(t, server, driver)
+			server.cfg.MetadataFilter = schemaAllowlist{"test_schema"}
+
+			_, streamCh, err := server.DoGetDBSchemas(context.Background(), &mockGetDBSchemas{})
+			if err != nil {
+				t.Fatalf("DoGetDBSchemas failed for %s: %v", driver.name, err)
+			}
+
+			var schemas []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				schemaCol := chunk.Data.Column(1).(*array.String)
+				for i := 0; i < schemaCol.Len(); i++ {
+					schemas = append(schemas, schemaCol.Value(i))
+				}
+				chunk.Data.Release()
+			}
+
+			for _, schema := range schemas {
+				if schema != "test_schema" {
+					t.Errorf("Expected schema %q to be hidden for %s", schema, driver.name)
+				}
+			}
+			// Only DuckDB has a test_schema, SQLite lists nothing
+			if driver.driverName == "duckdb" && !slices.Contains(schemas, "test_schema") {
+				t.Errorf("Expected test_schema to be listed for %s, got %v", driver.name, schemas)
+			}
+		})
+	}
+}
+
+func TestDoGetDBSchemas_EmptySchemaName(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	setupTestData(t, server)
+	server.cfg.EmptySchemaName = "main"
+
+	listSchemas := func(pattern *string) []string {
+		t.Helper()
+
+		_, streamCh, err := server.DoGetDBSchemas(context.Background(), &mockGetDBSchemas{dbSchemaFilterPattern: pattern})
+		if err != nil {
+			t.Fatalf("DoGetDBSchemas failed: %v", err)
+		}
+
+		var schemas []string
+		for chunk := range streamCh {
+			if chunk.Err != nil {
+				t.Fatalf("Stream error: %v", chunk.Err)
+			}
+			schemaCol := chunk.Data.Column(1).(*array.String)
+			for i := 0; i < schemaCol.Len(); i++ {
+				schemas = append(schemas, schemaCol.Value(i))
+			}
+			chunk.Data.Release()
+		}
+		return schemas
+	}
+
+	schemas := listSchemas(nil)
+	if slices.Contains(schemas, "") {
+		t.Errorf("Expected no empty schema name with normalization on, got %q", schemas)
+	}
+	if !slices.Contains(schemas, "main") {
+		t.Errorf("Expected the default schema to be reported as main, got %q", schemas)
+	}
+
+	// Clients filter on the reported name
+	pattern := "main"
+	if schemas := listSchemas(&pattern); !slices.Equal(schemas, []string{"main"}) {
+		t.Errorf("Expected the main filter to match the default schema, got %q", schemas)
+	}
+}
+
 func TestDoGetDBSchemasWithFilter(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -387,6 +673,52 @@ func TestDoGetTables(t *testing.T) {
 	}
 }
 
+func TestDoGetTables_EmptyDatabase(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			// A fresh database without any tables
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+
+			schema, streamCh, err := server.DoGetTables(ctx, &mockGetTables{})
+			if err != nil {
+				t.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+			}
+
+			if !schema.Equal(schema_ref.Tables) {
+				t.Errorf("Schema mismatch for %s.\nExpected: %v\nGot: %v", driver.name, schema_ref.Tables, schema)
+			}
+
+			var totalRows int64
+			timeout := time.After(10 * time.Second)
+			for done := false; !done; {
+				select {
+				case chunk, ok := <-streamCh:
+					if !ok {
+						done = true
+						break
+					}
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					totalRows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				case <-timeout:
+					t.Fatalf("Stream was not closed for %s", driver.name)
+				}
+			}
+
+			if totalRows != 0 {
+				t.Errorf("Expected no tables for %s, got %d rows", driver.name, totalRows)
+			}
+		})
+	}
+}
+
 func TestDoGetTablesWithFilters(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -441,6 +773,130 @@ func TestDoGetTablesWithFilters(t *testing.T) {
 	}
 }
 
+func TestDoGetTablesWithEscapedFilter(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// "testax" matches the unescaped pattern "test_x" as well
+			execTestSQL(t, server,
+				`CREATE TABLE test_x (id INTEGER)`,
+				`CREATE TABLE testax (id INTEGER)`,
+			)
+
+			ctx := context.Background()
+
+			listTables := func(pattern string) []string {
+				cmd := &mockGetTables{tableNameFilterPattern: &pattern}
+
+				_, streamCh, err := server.DoGetTables(ctx, cmd)
+				if err != nil {
+					t.Fatalf("DoGetTables with filter %q failed for %s: %v", pattern, driver.name, err)
+				}
+
+				var tables []string
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					tableCol := chunk.Data.Column(2).(*array.String)
+					for i := 0; i < tableCol.Len(); i++ {
+						tables = append(tables, tableCol.Value(i))
+					}
+					chunk.Data.Release()
+				}
+				sort.Strings(tables)
+				return tables
+			}
+
+			if tables := listTables("test_x"); len(tables) != 2 {
+				t.Errorf("Expected the wildcard pattern to match 2 tables for %s, got %v", driver.name, tables)
+			}
+
+			exact := escapeLikePattern("test_x")
+			if !isLiteralPattern(exact) {
+				t.Errorf("Expected %q to be a literal pattern", exact)
+			}
+
+			tables := listTables(exact)
+			if len(tables) != 1 || tables[0] != "test_x" {
+				t.Errorf("Expected exact-match filter %q to return only test_x for %s, got %v", exact, driver.name, tables)
+			}
+		})
+	}
+}
+
+func TestDoGetTables_CatalogFilter(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			// Put a second table in a named catalog next to the default one
+			otherPath := filepath.Join(t.TempDir(), "other.db")
+			if driver.driverName == "duckdb" {
+				// DuckDB attachments are shared by every connection
+				execTestSQL(t, server, fmt.Sprintf("ATTACH '%s' AS other", otherPath))
+			} else {
+				var db adbc.Database = &attachDatabase{
+					Database: *server.db,
+					attach:   map[string]string{"other": otherPath},
+				}
+				server.db = &db
+			}
+			execTestSQL(t, server, `CREATE TABLE other.other_table (id INTEGER)`)
+
+			ctx := context.Background()
+
+			listTables := func(catalog *string) []string {
+				_, streamCh, err := server.DoGetTables(ctx, &mockGetTables{catalog: catalog})
+				if err != nil {
+					t.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+				}
+
+				var tables []string
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					tableCol := chunk.Data.Column(2).(*array.String)
+					for i := 0; i < tableCol.Len(); i++ {
+						tables = append(tables, tableCol.Value(i))
+					}
+					chunk.Data.Release()
+				}
+				return tables
+			}
+
+			// nil matches every catalog
+			all := listTables(nil)
+			if !slices.Contains(all, "test_table") || !slices.Contains(all, "other_table") {
+				t.Errorf("Expected tables from every catalog for %s, got %v", driver.name, all)
+			}
+
+			// "" only matches the default catalog
+			empty := ""
+			unnamed := listTables(&empty)
+			if !slices.Contains(unnamed, "test_table") || slices.Contains(unnamed, "other_table") {
+				t.Errorf("Expected only default catalog tables for %s, got %v", driver.name, unnamed)
+			}
+
+			other := "other"
+			named := listTables(&other)
+			if len(named) != 1 || named[0] != "other_table" {
+				t.Errorf("Expected only other_table in catalog %q for %s, got %v", other, driver.name, named)
+			}
+		})
+	}
+}
+
 func TestDoGetTablesWithTypeFilter(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -541,6 +997,220 @@ func (m *mockGetTables) GetIncludeSchema() bool {
 	return m.includeSchema
 }
 
+func TestDoGetTables_GetObjectsUnsupported(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			// A driver that does not implement GetObjects
+			useFaultyDatabase(server, &faultyDatabase{getObjects: func() (array.RecordReader, error) {
+				return nil, adbc.Error{Code: adbc.StatusNotImplemented, Msg: "GetObjects not implemented"}
+			}})
+
+			_, streamCh, err := server.DoGetTables(context.Background(), &mockGetTables{})
+			if driver.driverName == "adbc_driver_sqlite" {
+				// SQLite has no information_schema to fall back to
+				if status.Code(err) != codes.Unimplemented {
+					t.Fatalf("Expected Unimplemented, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DoGetTables failed: %v", err)
+			}
+
+			var tables []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error: %v", chunk.Err)
+				}
+				nameCol := chunk.Data.Column(2).(*array.String)
+				for i := 0; i < nameCol.Len(); i++ {
+					tables = append(tables, nameCol.Value(i))
+				}
+				chunk.Data.Release()
+			}
+
+			if !slices.Contains(tables, "test_table") {
+				t.Errorf("Expected test_table from the information_schema fallback, got %v", tables)
+			}
+		})
+	}
+}
+
+func TestDoGetTables_Comments(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			execTestSQL(t, server, `CREATE TABLE commented (id INTEGER, amount REAL)`)
+			if driver.driverName == "duckdb" {
+				execTestSQL(t, server,
+					`COMMENT ON TABLE commented IS 'Customer payments'`,
+					`COMMENT ON COLUMN commented.amount IS 'Amount in cents'`)
+			}
+
+			tableName := "commented"
+			cmd := &mockGetTables{tableNameFilterPattern: &tableName, includeSchema: true}
+			_, streamCh, err := server.DoGetTables(context.Background(), cmd)
+			if err != nil {
+				t.Fatalf("DoGetTables failed: %v", err)
+			}
+
+			var schemas []*arrow.Schema
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error: %v", chunk.Err)
+				}
+				schemaCol := chunk.Data.Column(4).(*array.Binary)
+				for i := 0; i < schemaCol.Len(); i++ {
+					schema, err := flight.DeserializeSchema(schemaCol.Value(i), memory.DefaultAllocator)
+					if err != nil {
+						t.Fatalf("Failed to deserialize table schema: %v", err)
+					}
+					schemas = append(schemas, schema)
+				}
+				chunk.Data.Release()
+			}
+			if len(schemas) != 1 {
+				t.Fatalf("Expected 1 table, got %d", len(schemas))
+			}
+			schema := schemas[0]
+
+			tableRemarks, _ := schema.Metadata().GetValue(flightsql.RemarksKey)
+			idRemarks, _ := schema.Field(0).Metadata.GetValue(flightsql.RemarksKey)
+			amountRemarks, _ := schema.Field(1).Metadata.GetValue(flightsql.RemarksKey)
+
+			wantTable, wantAmount := "", ""
+			if driver.driverName == "duckdb" {
+				wantTable, wantAmount = "Customer payments", "Amount in cents"
+			}
+			if tableRemarks != wantTable {
+				t.Errorf("Expected table remarks %q, got %q", wantTable, tableRemarks)
+			}
+			if amountRemarks != wantAmount {
+				t.Errorf("Expected amount remarks %q, got %q", wantAmount, amountRemarks)
+			}
+			if idRemarks != "" {
+				t.Errorf("Expected no remarks on the uncommented column, got %q", idRemarks)
+			}
+		})
+	}
+}
+
+func TestDoGetTables_ColumnFlags(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			execTestSQL(t, server, `CREATE TABLE flagged (id INTEGER PRIMARY KEY, name TEXT)`)
+
+			tableName := "flagged"
+			cmd := &mockGetTables{tableNameFilterPattern: &tableName, includeSchema: true}
+			_, streamCh, err := server.DoGetTables(context.Background(), cmd)
+			if err != nil {
+				t.Fatalf("DoGetTables failed: %v", err)
+			}
+
+			var schemas []*arrow.Schema
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error: %v", chunk.Err)
+				}
+				schemaCol := chunk.Data.Column(4).(*array.Binary)
+				for i := 0; i < schemaCol.Len(); i++ {
+					schema, err := flight.DeserializeSchema(schemaCol.Value(i), memory.DefaultAllocator)
+					if err != nil {
+						t.Fatalf("Failed to deserialize table schema: %v", err)
+					}
+					schemas = append(schemas, schema)
+				}
+				chunk.Data.Release()
+			}
+			if len(schemas) != 1 {
+				t.Fatalf("Expected 1 table, got %d", len(schemas))
+			}
+			id, name := schemas[0].Field(0), schemas[0].Field(1)
+
+			if id.Nullable {
+				t.Errorf("Expected the primary key column to be non-nullable")
+			}
+			if !name.Nullable {
+				t.Errorf("Expected the name column to be nullable")
+			}
+
+			// Only SQLite's INTEGER PRIMARY KEY aliases the auto-incrementing rowid
+			wantAutoIncrement := driver.driverName != "duckdb"
+			idAutoIncrement, ok := (&flightsql.ColumnMetadata{Data: &id.Metadata}).IsAutoIncrement()
+			if !ok || idAutoIncrement != wantAutoIncrement {
+				t.Errorf("Expected id auto-increment %v, got %v (reported: %v)", wantAutoIncrement, idAutoIncrement, ok)
+			}
+			if nameAutoIncrement, _ := (&flightsql.ColumnMetadata{Data: &name.Metadata}).IsAutoIncrement(); nameAutoIncrement {
+				t.Errorf("Expected the name column not to be auto-incrementing")
+			}
+		})
+	}
+}
+
+func TestDoGetTables_IdentifierCase(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		for _, identifierCase := range []string{"", "lower"} {
+			t.Run(driver.name+"_"+identifierCase, func(t *testing.T) {
+				server, cleanup := setupTestServer(t, driver)
+				defer cleanup()
+
+				setupTestData(t, server)
+				server.cfg.IdentifierCase = identifierCase
+
+				pattern := "TEST_TABLE"
+				_, streamCh, err := server.DoGetTables(context.Background(), &mockGetTables{tableNameFilterPattern: &pattern})
+				if err != nil {
+					t.Fatalf("DoGetTables failed: %v", err)
+				}
+
+				var tables []string
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error: %v", chunk.Err)
+					}
+					tableCol := chunk.Data.Column(2).(*array.String)
+					for i := 0; i < tableCol.Len(); i++ {
+						tables = append(tables, tableCol.Value(i))
+					}
+					chunk.Data.Release()
+				}
+
+				switch {
+				case identifierCase == "lower":
+					if len(tables) != 1 || tables[0] != "test_table" {
+						t.Errorf("Expected the folded pattern to match test_table, got %v", tables)
+					}
+				case driver.driverName == "duckdb":
+					if len(tables) != 0 {
+						t.Errorf("Expected the upper case pattern not to match without folding, got %v", tables)
+					}
+				default:
+					// SQLite's LIKE ignores ASCII case, which is the
+					// difference folding papers over
+				}
+			})
+		}
+	}
+}
+
 func TestGetFlightInfoTables(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -606,6 +1276,120 @@ func TestGetFlightInfoTables(t *testing.T) {
 	}
 }
 
+func TestDoGetTables_NormalizedTableTypes(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			server.cfg.NormalizeTableTypes = true
+
+			// Setup test data
+			setupTestData(t, server)
+
+			ctx := context.Background()
+
+			// Filter by the canonical type, which must match every backend spelling
+			cmd := &mockGetTables{
+				tableTypes: []string{"TABLE"},
+			}
+
+			_, streamCh, err := server.DoGetTables(ctx, cmd)
+			if err != nil {
+				t.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+			}
+
+			tableTypes := make(map[string]string)
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("DoGetTables stream failed for %s: %v", driver.name, chunk.Err)
+				}
+				if chunk.Data != nil {
+					record := chunk.Data
+					tableCol := record.Column(2).(*array.String)
+					typeCol := record.Column(3).(*array.String)
+					for i := 0; i < tableCol.Len(); i++ {
+						tableTypes[tableCol.Value(i)] = typeCol.Value(i)
+					}
+				}
+			}
+
+			tableType, ok := tableTypes["test_table"]
+			if !ok {
+				t.Fatalf("Expected 'test_table' in results for %s, got: %v", driver.name, tableTypes)
+			}
+			if tableType != "TABLE" {
+				t.Errorf("Expected normalized table type 'TABLE' for %s, got '%s'", driver.name, tableType)
+			}
+		})
+	}
+}
+
+func TestDoGetTableTypes(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		for _, normalize := range []bool{false, true} {
+			name := driver.name + "_Raw"
+			if normalize {
+				name = driver.name + "_Normalized"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				server, cleanup := setupTestServer(t, driver)
+				defer cleanup()
+
+				server.cfg.NormalizeTableTypes = normalize
+
+				ctx := context.Background()
+
+				schema, streamCh, err := server.DoGetTableTypes(ctx)
+				if err != nil {
+					t.Fatalf("DoGetTableTypes failed for %s: %v", driver.name, err)
+				}
+
+				expectedSchema := schema_ref.TableTypes
+				if !schema.Equal(expectedSchema) {
+					t.Errorf("Schema mismatch for %s.\nExpected: %v\nGot: %v", driver.name, expectedSchema, schema)
+				}
+
+				var tableTypes []string
+				for chunk := range streamCh {
+					if chunk.Data != nil {
+						typeCol := chunk.Data.Column(0).(*array.String)
+						for i := 0; i < typeCol.Len(); i++ {
+							tableTypes = append(tableTypes, typeCol.Value(i))
+						}
+					}
+				}
+
+				if len(tableTypes) == 0 {
+					t.Fatalf("Expected at least one table type for %s, got none", driver.name)
+				}
+
+				if normalize {
+					hasTable := false
+					for _, tableType := range tableTypes {
+						if tableType == "TABLE" {
+							hasTable = true
+						}
+						if tableType != normalizeTableType(tableType) {
+							t.Errorf("Expected only canonical table types for %s, got '%s'", driver.name, tableType)
+						}
+					}
+					if !hasTable {
+						t.Errorf("Expected 'TABLE' among normalized table types for %s, got: %v", driver.name, tableTypes)
+					}
+				}
+
+				t.Logf("Table types for %s: %v", driver.name, tableTypes)
+			})
+		}
+	}
+}
+
 // func TestDoGetCatalogs_ErrorHandling(t *testing.T) {
 // 	// Create server with nil database to test error handling
 // 	server := &DummyFlightSQLServer{
@@ -715,9 +1499,9 @@ func TestGetFlightInfoTables(t *testing.T) {
 
 // func TestDummyFlightSQLServer_Creation(t *testing.T) {
 // 	// Test the constructor function (uses default SQLite)
-// 	server, err := NewDummyFlightSQLServer()
+// 	server, err := New()
 // 	if err != nil {
-// 		t.Fatalf("NewDummyFlightSQLServer failed: %v", err)
+// 		t.Fatalf("New failed: %v", err)
 // 	}
 
 // 	if server == nil {
@@ -737,3 +1521,283 @@ func TestGetFlightInfoTables(t *testing.T) {
 // 		(*server.db).Close()
 // 	}
 // }
+
+type mockGetSqlInfo struct {
+	info []uint32
+}
+
+func (m *mockGetSqlInfo) GetInfo() []uint32 {
+	return m.info
+}
+
+func TestDoGetSqlInfo_Keywords(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+			if err := server.registerSqlInfo(ctx); err != nil {
+				t.Fatalf("registerSqlInfo failed for %s: %v", driver.name, err)
+			}
+
+			// stringList returns the string list registered for info
+			stringList := func(info flightsql.SqlInfo) []string {
+				_, streamCh, err := server.DoGetSqlInfo(ctx, &mockGetSqlInfo{info: []uint32{uint32(info)}})
+				if err != nil {
+					t.Fatalf("DoGetSqlInfo failed for %s: %v", driver.name, err)
+				}
+
+				var values []string
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					union := chunk.Data.Column(1).(*array.DenseUnion)
+					for i := 0; i < union.Len(); i++ {
+						list := union.Field(union.ChildID(i)).(*array.List)
+						start, end := list.ValueOffsets(int(union.ValueOffset(i)))
+						names := list.ListValues().(*array.String)
+						for j := start; j < end; j++ {
+							values = append(values, names.Value(int(j)))
+						}
+					}
+					chunk.Data.Release()
+				}
+				return values
+			}
+
+			keywords := stringList(flightsql.SqlInfoKeywords)
+			if !slices.Contains(keywords, "SELECT") {
+				t.Errorf("Expected the keywords to include SELECT for %s, got %v", driver.name, keywords)
+			}
+
+			if functions := stringList(flightsql.SqlInfoStringFunctions); !slices.Contains(functions, "UPPER") {
+				t.Errorf("Expected the string functions to include UPPER for %s, got %v", driver.name, functions)
+			}
+		})
+	}
+}
+
+func TestDoGetSqlInfo_DialectOverride(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			server.cfg.Dialect = SqlDialect{
+				IdentifierQuoteChar: "`",
+				SchemaTerm:          "database",
+				StringFunctions:     []string{"MY_UPPER"},
+			}
+			ctx := context.Background()
+			if err := server.registerSqlInfo(ctx); err != nil {
+				t.Fatalf("registerSqlInfo failed for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetSqlInfo(ctx, &mockGetSqlInfo{info: []uint32{
+				uint32(flightsql.SqlInfoIdentifierQuoteChar),
+				uint32(flightsql.SqlInfoSchemaTerm),
+				uint32(flightsql.SqlInfoStringFunctions),
+			}})
+			if err != nil {
+				t.Fatalf("DoGetSqlInfo failed for %s: %v", driver.name, err)
+			}
+
+			strs := make(map[flightsql.SqlInfo]string)
+			var functions []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				names := chunk.Data.Column(0).(*array.Uint32)
+				union := chunk.Data.Column(1).(*array.DenseUnion)
+				for i := 0; i < union.Len(); i++ {
+					info := flightsql.SqlInfo(names.Value(i))
+					offset := int(union.ValueOffset(i))
+					switch child := union.Field(union.ChildID(i)).(type) {
+					case *array.String:
+						strs[info] = child.Value(offset)
+					case *array.List:
+						start, end := child.ValueOffsets(offset)
+						values := child.ListValues().(*array.String)
+						for j := start; j < end; j++ {
+							functions = append(functions, values.Value(int(j)))
+						}
+					}
+				}
+				chunk.Data.Release()
+			}
+
+			if got := strs[flightsql.SqlInfoIdentifierQuoteChar]; got != "`" {
+				t.Errorf("Expected identifier quote char ` for %s, got %q", driver.name, got)
+			}
+			if got := strs[flightsql.SqlInfoSchemaTerm]; got != "database" {
+				t.Errorf("Expected schema term database for %s, got %q", driver.name, got)
+			}
+			if !slices.Equal(functions, []string{"MY_UPPER"}) {
+				t.Errorf("Expected the string functions to be replaced for %s, got %v", driver.name, functions)
+			}
+		})
+	}
+}
+
+func TestGetObjects_PermissionDenied(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// An access control error, as on backends with per-user grants
+			useFaultyDatabase(server, &faultyDatabase{getObjects: func() (array.RecordReader, error) {
+				return nil, adbc.Error{Code: adbc.StatusUnauthorized, Msg: "permission denied for schema secret"}
+			}})
+
+			ctx := context.Background()
+			checkDenied := func(method string, err error) {
+				if status.Code(err) != codes.PermissionDenied {
+					t.Errorf("Expected PermissionDenied from %s for %s, got %v", method, driver.name, err)
+				} else if msg := status.Convert(err).Message(); msg != "permission denied for schema secret" {
+					t.Errorf("Expected the backend message from %s for %s, got %q", method, driver.name, msg)
+				}
+			}
+
+			_, _, err := server.DoGetCatalogs(ctx)
+			checkDenied("DoGetCatalogs", err)
+			_, _, err = server.DoGetDBSchemas(ctx, &mockGetDBSchemas{})
+			checkDenied("DoGetDBSchemas", err)
+			_, _, err = server.DoGetTables(ctx, &mockGetTables{})
+			checkDenied("DoGetTables", err)
+		})
+	}
+}
+
+func TestDoGetTables_VirtualTables(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			server.cfg.VirtualSchema = "federated"
+			server.cfg.VirtualTables = map[string]string{
+				"recent": "SELECT id, name FROM test_table WHERE id > 1",
+			}
+
+			ctx := context.Background()
+			_, streamCh, err := server.DoGetTables(ctx, &mockGetTables{includeSchema: true})
+			if err != nil {
+				t.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+			}
+
+			var found bool
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				schemaCol := chunk.Data.Column(1).(*array.String)
+				tableCol := chunk.Data.Column(2).(*array.String)
+				typeCol := chunk.Data.Column(3).(*array.String)
+				schemasCol := chunk.Data.Column(4).(*array.Binary)
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					if schemaCol.Value(i) != "federated" || tableCol.Value(i) != "recent" {
+						continue
+					}
+					found = true
+					if typeCol.Value(i) != "VIEW" {
+						t.Errorf("Expected the virtual table to be listed as a VIEW for %s, got %s", driver.name, typeCol.Value(i))
+					}
+					tableSchema, err := flight.DeserializeSchema(schemasCol.Value(i), memory.DefaultAllocator)
+					if err != nil {
+						t.Fatalf("Failed to decode the virtual table schema for %s: %v", driver.name, err)
+					}
+					if tableSchema.NumFields() != 2 || tableSchema.Field(1).Name != "name" {
+						t.Errorf("Expected the columns of the defining query for %s, got %v", driver.name, tableSchema)
+					}
+				}
+				chunk.Data.Release()
+			}
+			if !found {
+				t.Fatalf("Expected federated.recent to be listed for %s", driver.name)
+			}
+
+			// The table resolves to its definition when read
+			_, streamCh, err = server.executeStatement(ctx, server.tableQuery(tableRef{schema: "federated", table: "recent"}))
+			if err != nil {
+				t.Fatalf("Reading the virtual table failed for %s: %v", driver.name, err)
+			}
+			var rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if rows != 2 {
+				t.Errorf("Expected the 2 rows of the defining query for %s, got %d", driver.name, rows)
+			}
+		})
+	}
+}
+
+func TestDoGetTables_StrictTableTypes(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			ctx := context.Background()
+			bogus := &mockGetTables{tableTypes: []string{"TABEL"}}
+
+			_, streamCh, err := server.DoGetTables(ctx, bogus)
+			if err != nil {
+				t.Fatalf("DoGetTables failed for %s: %v", driver.name, err)
+			}
+			rows := 0
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				rows += int(chunk.Data.NumRows())
+				chunk.Data.Release()
+			}
+			if rows != 0 {
+				t.Errorf("Expected no tables for a bogus type for %s, got %d", driver.name, rows)
+			}
+
+			server.cfg.StrictTableTypes = true
+
+			_, _, err = server.DoGetTables(ctx, bogus)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Expected InvalidArgument for a bogus type for %s, got %v", driver.name, err)
+			}
+			if !strings.Contains(err.Error(), "TABEL") {
+				t.Errorf("Expected the error to name the unknown type for %s, got: %v", driver.name, err)
+			}
+
+			// Canonical values are accepted even when the backend spells them differently
+			_, streamCh, err = server.DoGetTables(ctx, &mockGetTables{tableTypes: []string{"TABLE"}})
+			if err != nil {
+				t.Fatalf("DoGetTables failed for a known type for %s: %v", driver.name, err)
+			}
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				chunk.Data.Release()
+			}
+		})
+	}
+}
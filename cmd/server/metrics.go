@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runningStatementsGauge is the number of statements streaming results
+const runningStatementsGauge = "running_statements"
+
+// Metrics receives the server's query metrics, so that embedders can export
+// them to Prometheus, StatsD, OpenTelemetry or anything else. Methods are
+// called concurrently and must not block.
+type Metrics interface {
+	// IncQuery counts a finished query of the Flight SQL method
+	IncQuery(method string)
+	// ObserveLatency records how long a query took, including streaming
+	ObserveLatency(method string, d time.Duration)
+	// IncError counts a failed query by its gRPC status code
+	IncError(method string, code codes.Code)
+	// SetGauge reports the current value of a gauge such as
+	// running_statements
+	SetGauge(name string, value float64)
+}
+
+// noopMetrics is used when no Metrics is configured
+type noopMetrics struct{}
+
+func (noopMetrics) IncQuery(string)                      {}
+func (noopMetrics) ObserveLatency(string, time.Duration) {}
+func (noopMetrics) IncError(string, codes.Code)          {}
+func (noopMetrics) SetGauge(string, float64)             {}
+
+// metrics returns the configured Metrics or a no-op one
+func (s *DummyFlightSQLServer) metrics() Metrics {
+	if s.cfg.Metrics == nil {
+		return noopMetrics{}
+	}
+	return s.cfg.Metrics
+}
+
+// recordQuery reports a finished query to the metrics
+func (s *DummyFlightSQLServer) recordQuery(method string, start time.Time, err error) {
+	m := s.metrics()
+	m.IncQuery(method)
+	m.ObserveLatency(method, time.Since(start))
+	if err != nil {
+		m.IncError(method, status.Code(err))
+	}
+}
+
+// reportRunning updates the running statements gauge. The caller must hold
+// s.mu.
+func (s *DummyFlightSQLServer) reportRunning() {
+	var n int
+	for _, streams := range s.running {
+		n += len(streams)
+	}
+	s.metrics().SetGauge(runningStatementsGauge, float64(n))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backendErrorDomain is the ErrorInfo domain of backend errors
+const backendErrorDomain = "adbc"
+
+// SQLSTATEs reported when the backend doesn't set one
+const (
+	sqlStateIntegrityViolation = "23000"
+	sqlStateUniqueViolation    = "23505"
+)
+
+// adbcStatusCodes maps ADBC status codes to the closest gRPC codes
+var adbcStatusCodes = map[adbc.Status]codes.Code{
+	adbc.StatusNotImplemented:  codes.Unimplemented,
+	adbc.StatusNotFound:        codes.NotFound,
+	adbc.StatusAlreadyExists:   codes.AlreadyExists,
+	adbc.StatusInvalidArgument: codes.InvalidArgument,
+	adbc.StatusInvalidState:    codes.FailedPrecondition,
+	adbc.StatusInvalidData:     codes.InvalidArgument,
+	adbc.StatusIntegrity:       codes.FailedPrecondition,
+	adbc.StatusInternal:        codes.Internal,
+	adbc.StatusIO:              codes.Unavailable,
+	adbc.StatusCancelled:       codes.Canceled,
+	adbc.StatusTimeout:         codes.DeadlineExceeded,
+	adbc.StatusUnauthenticated: codes.Unauthenticated,
+	adbc.StatusUnauthorized:    codes.PermissionDenied,
+}
+
+// uniqueViolationMessages are what SQLite and DuckDB report for duplicate
+// keys without setting a SQLSTATE
+var uniqueViolationMessages = []string{
+	"UNIQUE constraint failed",
+	"PRIMARY KEY constraint failed",
+	"Duplicate key",
+}
+
+// backendError turns an ADBC error in err's chain into a gRPC status whose
+// ErrorInfo detail carries the SQLSTATE and vendor code, so clients can
+// branch on them. Other errors are returned as they are.
+func backendError(err error) error {
+	adbcErr, ok := asADBCError(err)
+	if !ok {
+		return err
+	}
+
+	code, ok := adbcStatusCodes[adbcErr.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	metadata := make(map[string]string)
+	if sqlState := backendSQLState(adbcErr); sqlState != "" {
+		metadata["sql_state"] = sqlState
+	}
+	if adbcErr.VendorCode != 0 {
+		metadata["vendor_code"] = strconv.Itoa(int(adbcErr.VendorCode))
+	}
+
+	st := status.New(code, adbcErr.Msg)
+	withDetails, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   adbcErr.Code.String(),
+		Domain:   backendErrorDomain,
+		Metadata: metadata,
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// backendSQLState returns the SQLSTATE of an ADBC error. SQLite and DuckDB
+// leave it unset for constraint violations, so those get the standard
+// SQLSTATE of their class.
+func backendSQLState(adbcErr adbc.Error) string {
+	if sqlState := strings.TrimRight(string(adbcErr.SqlState[:]), "\x00"); sqlState != "" {
+		return sqlState
+	}
+	for _, message := range uniqueViolationMessages {
+		if strings.Contains(adbcErr.Msg, message) {
+			return sqlStateUniqueViolation
+		}
+	}
+	if adbcErr.Code == adbc.StatusIntegrity {
+		return sqlStateIntegrityViolation
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// getObjects calls GetObjects on conn. Drivers that don't implement it get
+// the catalogs, schemas and tables from information_schema instead, in the
+// same GetObjects layout so the metadata projections work unchanged.
+func (s *DummyFlightSQLServer) getObjects(ctx context.Context, conn adbc.Connection, depth adbc.ObjectDepth, catalog, dbSchema, tableName *string, tableTypes []string) (array.RecordReader, error) {
+	reader, err := conn.GetObjects(ctx, depth, catalog, dbSchema, tableName, nil, tableTypes)
+	if err == nil {
+		return reader, nil
+	}
+	if !isNotImplemented(err) {
+		return nil, metadataError(err)
+	}
+
+	if depth == adbc.ObjectDepthColumns || depth == adbc.ObjectDepthAll {
+		return nil, status.Error(codes.Unimplemented, "backend does not support GetObjects at column depth")
+	}
+
+	reader, err = s.informationSchemaObjects(ctx, conn, depth, catalog, dbSchema, tableName, tableTypes)
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "backend supports neither GetObjects nor information_schema: %v", err)
+	}
+	return reader, nil
+}
+
+// asADBCError returns the ADBC error in err's chain, passed by value or
+// by pointer
+func asADBCError(err error) (adbc.Error, bool) {
+	var adbcErr adbc.Error
+	if errors.As(err, &adbcErr) {
+		return adbcErr, true
+	}
+	var adbcErrPtr *adbc.Error
+	if errors.As(err, &adbcErrPtr) && adbcErrPtr != nil {
+		return *adbcErrPtr, true
+	}
+	return adbc.Error{}, false
+}
+
+// isNotImplemented reports whether err is an ADBC NotImplemented error
+func isNotImplemented(err error) bool {
+	adbcErr, ok := asADBCError(err)
+	return ok && adbcErr.Code == adbc.StatusNotImplemented
+}
+
+// metadataError maps the access control errors of a GetObjects call to
+// their gRPC codes with the backend's message, so that clients can tell
+// them from other failures. Other errors are returned as they are.
+func metadataError(err error) error {
+	adbcErr, ok := asADBCError(err)
+	if !ok {
+		return err
+	}
+	switch adbcErr.Code {
+	case adbc.StatusUnauthorized:
+		return status.Error(codes.PermissionDenied, adbcErr.Msg)
+	case adbc.StatusUnauthenticated:
+		return status.Error(codes.Unauthenticated, adbcErr.Msg)
+	}
+	return err
+}
+
+// informationSchemaObjects builds a GetObjects result from information_schema
+func (s *DummyFlightSQLServer) informationSchemaObjects(ctx context.Context, conn adbc.Connection, depth adbc.ObjectDepth, catalog, dbSchema, tableName *string, tableTypes []string) (array.RecordReader, error) {
+	// schemata names its columns differently from tables
+	from, catalogCol, schemaCol := "information_schema.tables", "table_catalog", "table_schema"
+	columns := "table_catalog, table_schema, table_name, table_type"
+	if depth == adbc.ObjectDepthCatalogs || depth == adbc.ObjectDepthDBSchemas {
+		from, catalogCol, schemaCol = "information_schema.schemata", "catalog_name", "schema_name"
+		columns = "DISTINCT catalog_name, schema_name"
+	}
+
+	var conditions []string
+	if catalog != nil {
+		conditions = append(conditions, catalogCol+" = "+quoteLiteral(*catalog))
+	}
+	if dbSchema != nil {
+		conditions = append(conditions, schemaCol+" LIKE "+quoteLiteral(*dbSchema))
+	}
+	if from == "information_schema.tables" {
+		if tableName != nil {
+			conditions = append(conditions, "table_name LIKE "+quoteLiteral(*tableName))
+		}
+		if len(tableTypes) > 0 {
+			quoted := make([]string, len(tableTypes))
+			for i, tableType := range tableTypes {
+				quoted[i] = quoteLiteral(tableType)
+			}
+			conditions = append(conditions, "table_type IN ("+strings.Join(quoted, ", ")+")")
+		}
+	}
+
+	query := "SELECT " + columns + " FROM " + from
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY 1, 2"
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return nil, err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	bldr := array.NewRecordBuilder(s.Alloc, adbc.GetObjectsSchema)
+	defer bldr.Release()
+
+	catalogB := bldr.Field(0).(*array.StringBuilder)
+	schemasB := bldr.Field(1).(*array.ListBuilder)
+	schemaB := schemasB.ValueBuilder().(*array.StructBuilder)
+	schemaNameB := schemaB.FieldBuilder(0).(*array.StringBuilder)
+	tablesB := schemaB.FieldBuilder(1).(*array.ListBuilder)
+	tableB := tablesB.ValueBuilder().(*array.StructBuilder)
+	tableNameB := tableB.FieldBuilder(0).(*array.StringBuilder)
+	tableTypeB := tableB.FieldBuilder(1).(*array.StringBuilder)
+	columnsB := tableB.FieldBuilder(2).(*array.ListBuilder)
+	constraintsB := tableB.FieldBuilder(3).(*array.ListBuilder)
+
+	var (
+		started             bool
+		lastCatalog, lastDB string
+	)
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		cols, err := asStringColumns(rec.Columns()...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			catalogName, schemaName := cols[0].Value(i), cols[1].Value(i)
+
+			newCatalog := !started || catalogName != lastCatalog
+			if newCatalog {
+				catalogB.Append(catalogName)
+				if depth == adbc.ObjectDepthCatalogs {
+					schemasB.AppendNull()
+				} else {
+					schemasB.Append(true)
+				}
+			}
+			newSchema := newCatalog || schemaName != lastDB
+			started, lastCatalog, lastDB = true, catalogName, schemaName
+
+			if depth == adbc.ObjectDepthCatalogs {
+				continue
+			}
+			if newSchema {
+				schemaB.Append(true)
+				schemaNameB.Append(schemaName)
+				if depth == adbc.ObjectDepthDBSchemas {
+					tablesB.AppendNull()
+				} else {
+					tablesB.Append(true)
+				}
+			}
+			if depth == adbc.ObjectDepthDBSchemas {
+				continue
+			}
+
+			tableB.Append(true)
+			tableNameB.Append(cols[2].Value(i))
+			tableTypeB.Append(cols[3].Value(i))
+			columnsB.AppendNull()
+			constraintsB.AppendNull()
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	return array.NewRecordReader(adbc.GetObjectsSchema, []arrow.RecordBatch{rec})
+}
+
+// quoteLiteral quotes s as a SQL string literal
+func quoteLiteral(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
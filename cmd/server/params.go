@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// sqlToken is a token of a query as far as parameter typing needs it
+type sqlToken struct {
+	text  string
+	ident bool // a keyword or identifier, with quotes removed
+}
+
+// tokenizeSQL splits query into identifiers, placeholders, operators and
+// punctuation. Literals and comments become a single "'" token or vanish.
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\'':
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+			}
+			tokens = append(tokens, sqlToken{text: "'"})
+		case r == '"' || r == '`':
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != r; i++ {
+			}
+			tokens = append(tokens, sqlToken{text: string(runes[start:min(i, len(runes))]), ident: true})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) || runes[i+1] == '_') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{text: string(runes[start : i+1]), ident: true})
+		case strings.ContainsRune("<>!", r) && i+1 < len(runes) && strings.ContainsRune("=>", runes[i+1]):
+			tokens = append(tokens, sqlToken{text: string(runes[i : i+2])})
+			i++
+		default:
+			tokens = append(tokens, sqlToken{text: string(r)})
+		}
+	}
+	return tokens
+}
+
+// isComparison reports whether tok compares a column with a placeholder
+func isComparison(tok sqlToken) bool {
+	switch strings.ToUpper(tok.text) {
+	case "=", "<>", "!=", "<", ">", "<=", ">=", "LIKE", "BETWEEN":
+		return true
+	}
+	return false
+}
+
+// placeholderColumns returns the tables the query reads or writes and, per
+// ? placeholder, the name of the column it is compared with or "" if that
+// can't be told. Columns are found in "col op ?", "? op col",
+// "col IN (?, ...)" and "col BETWEEN ? AND ?".
+func placeholderColumns(query string) (tables []string, columns []string) {
+	tokens := tokenizeSQL(query)
+	// column returns the column name ending at tokens[i], skipping qualifiers
+	column := func(i int) string {
+		if i >= 0 && tokens[i].ident {
+			return tokens[i].text
+		}
+		return ""
+	}
+
+	for i, tok := range tokens {
+		switch {
+		case tok.ident:
+			switch strings.ToUpper(tok.text) {
+			case "FROM", "JOIN", "UPDATE", "INTO":
+				// Qualified names end at the last part
+				j := i + 1
+				for j+2 < len(tokens) && tokens[j].ident && tokens[j+1].text == "." {
+					j += 2
+				}
+				if j < len(tokens) && tokens[j].ident {
+					tables = append(tables, tokens[j].text)
+				}
+			}
+		case tok.text == "?":
+			name := ""
+			switch {
+			case i >= 2 && isComparison(tokens[i-1]):
+				name = column(i - 2)
+			case i >= 4 && strings.EqualFold(tokens[i-1].text, "AND") && strings.EqualFold(tokens[i-3].text, "BETWEEN"):
+				name = column(i - 4)
+			case i+2 < len(tokens) && isComparison(tokens[i+1]):
+				name = column(i + 2)
+			case i >= 1 && (tokens[i-1].text == "(" || tokens[i-1].text == ","):
+				// Walk back over the IN list to its opening parenthesis
+				j := i - 1
+				for j > 0 && tokens[j].text != "(" {
+					j--
+				}
+				if j >= 2 && strings.EqualFold(tokens[j-1].text, "IN") {
+					name = column(j - 2)
+				}
+			}
+			columns = append(columns, name)
+		}
+	}
+	return tables, columns
+}
+
+// parameterSchema returns the parameter schema of a prepared query with a
+// concrete type for every parameter. Types the driver reports are kept;
+// untyped parameters take the type of the column they are compared with in
+// one of the queried tables, and bind as strings otherwise.
+func (s *DummyFlightSQLServer) parameterSchema(ctx context.Context, conn adbc.Connection, stmt adbc.Statement, query string) *arrow.Schema {
+	tables, columns := placeholderColumns(query)
+
+	var fields []arrow.Field
+	// Not every driver can describe its parameters
+	if described, err := stmt.GetParameterSchema(); err == nil && described != nil {
+		fields = described.Fields()
+	} else {
+		for i := range columns {
+			fields = append(fields, arrow.Field{Name: strconv.Itoa(i), Type: arrow.Null, Nullable: true})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var tableSchemas []*arrow.Schema
+	loaded := false
+	for i := range fields {
+		if fields[i].Type.ID() != arrow.NULL {
+			continue
+		}
+		fields[i].Type = arrow.BinaryTypes.String
+		fields[i].Nullable = true
+		// Named or numbered placeholders don't line up with the ? scan
+		if len(fields) != len(columns) || columns[i] == "" {
+			continue
+		}
+
+		if !loaded {
+			for _, table := range tables {
+				if schema, err := conn.GetTableSchema(ctx, nil, nil, table); err == nil {
+					tableSchemas = append(tableSchemas, schema)
+				}
+			}
+			loaded = true
+		}
+		if typ, ok := columnType(tableSchemas, columns[i]); ok {
+			fields[i].Type = typ
+		}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// columnType returns the type of the first column called name
+func columnType(schemas []*arrow.Schema, name string) (arrow.DataType, bool) {
+	for _, schema := range schemas {
+		for _, field := range schema.Fields() {
+			if strings.EqualFold(field.Name, name) && field.Type.ID() != arrow.NULL {
+				return field.Type, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Flight SQL filter patterns use the SQL LIKE wildcards "%" (any sequence)
+// and "_" (any single character). A backslash escapes the next character so
+// that names containing wildcards can be matched literally.
+const likeEscape = '\\'
+
+// escapeLikePattern returns a filter pattern that matches name exactly
+func escapeLikePattern(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '%' || r == '_' || r == likeEscape {
+			b.WriteRune(likeEscape)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isLiteralPattern reports whether pattern has no unescaped wildcards and
+// therefore names a single object
+func isLiteralPattern(pattern string) bool {
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case r == likeEscape:
+			escaped = true
+		case r == '%' || r == '_':
+			return false
+		}
+	}
+	return true
+}
+
+// likePattern is a compiled filter pattern. Literal patterns are compared
+// directly, anything else goes through a regular expression.
+type likePattern struct {
+	literal *string
+	re      *regexp.Regexp
+}
+
+// compileLikePattern builds a matcher for a Flight SQL filter pattern
+func compileLikePattern(pattern string) *likePattern {
+	if isLiteralPattern(pattern) {
+		name := unescapeLikePattern(pattern)
+		return &likePattern{literal: &name}
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == likeEscape:
+			escaped = true
+		case r == '%':
+			b.WriteString("(?s:.*)")
+		case r == '_':
+			b.WriteString("(?s:.)")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		// A trailing escape character matches itself
+		b.WriteString(regexp.QuoteMeta(string(likeEscape)))
+	}
+	b.WriteString("$")
+	return &likePattern{re: regexp.MustCompile(b.String())}
+}
+
+// Match reports whether name matches the pattern
+func (p *likePattern) Match(name string) bool {
+	if p.literal != nil {
+		return name == *p.literal
+	}
+	return p.re.MatchString(name)
+}
+
+// unescapeLikePattern removes the escapes from a literal pattern
+func unescapeLikePattern(pattern string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		if !escaped && r == likeEscape {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	if escaped {
+		b.WriteRune(likeEscape)
+	}
+	return b.String()
+}
+
+// backendFilter prepares a client filter pattern for GetObjects. Backends
+// don't agree on LIKE escaping, so escaped patterns are relaxed to a superset
+// for the backend and returned with a matcher that filters the results
+// precisely. Patterns without escapes are passed through unchanged.
+func backendFilter(pattern *string) (*string, *likePattern) {
+	if pattern == nil || !strings.ContainsRune(*pattern, likeEscape) {
+		return pattern, nil
+	}
+
+	var relaxed strings.Builder
+	escaped := false
+	for _, r := range *pattern {
+		switch {
+		case escaped && (r == '%' || r == '_'):
+			// The wildcard also matches the literal character
+			relaxed.WriteRune(r)
+			escaped = false
+		case escaped:
+			// The backend may or may not treat the escape specially, so
+			// match any character in its place
+			relaxed.WriteRune('_')
+			escaped = false
+		case r == likeEscape:
+			escaped = true
+		default:
+			relaxed.WriteRune(r)
+		}
+	}
+	if escaped {
+		relaxed.WriteRune('_')
+	}
+
+	result := relaxed.String()
+	return &result, compileLikePattern(*pattern)
+}
+
+// Identifier case folding modes for IdentifierCase
+const (
+	identifierCaseUpper = "upper"
+	identifierCaseLower = "lower"
+)
+
+// checkIdentifierCase validates the IdentifierCase setting
+func checkIdentifierCase(mode string) error {
+	switch mode {
+	case "", identifierCaseUpper, identifierCaseLower:
+		return nil
+	}
+	return fmt.Errorf("unknown identifier case %q, expected %q, %q or empty", mode, identifierCaseUpper, identifierCaseLower)
+}
+
+// foldIdentifier applies IdentifierCase to a client catalog name or filter
+// pattern. Nil stays nil.
+func (s *DummyFlightSQLServer) foldIdentifier(name *string) *string {
+	if name == nil {
+		return nil
+	}
+	var folded string
+	switch s.cfg.IdentifierCase {
+	case identifierCaseUpper:
+		folded = strings.ToUpper(*name)
+	case identifierCaseLower:
+		folded = strings.ToLower(*name)
+	default:
+		return name
+	}
+	return &folded
+}
+
+// tableFilter prepares a client table filter for GetObjects like
+// backendFilter, after case folding
+func (s *DummyFlightSQLServer) tableFilter(pattern *string) (*string, *likePattern) {
+	return backendFilter(s.foldIdentifier(pattern))
+}
+
+// reportedSchemaName returns the schema name shown to clients for a backend
+// schema name, applying EmptySchemaName
+func (s *DummyFlightSQLServer) reportedSchemaName(name string) string {
+	if name == "" && s.cfg.EmptySchemaName != "" {
+		return s.cfg.EmptySchemaName
+	}
+	return name
+}
+
+// backendSchemaName maps a schema name shown to clients back to the
+// backend schema name, undoing reportedSchemaName
+func (s *DummyFlightSQLServer) backendSchemaName(name string) string {
+	if name == s.cfg.EmptySchemaName && s.cfg.EmptySchemaName != "" {
+		return ""
+	}
+	return name
+}
+
+// schemaFilter prepares a client schema filter for GetObjects like
+// tableFilter. With EmptySchemaName set the backend can't match the
+// reported name, so the filter is only applied to the results.
+func (s *DummyFlightSQLServer) schemaFilter(pattern *string) (*string, *likePattern) {
+	pattern = s.foldIdentifier(pattern)
+	if pattern == nil || s.cfg.EmptySchemaName == "" {
+		return backendFilter(pattern)
+	}
+	return nil, compileLikePattern(*pattern)
+}
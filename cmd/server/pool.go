@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connPool keeps idle backend connections for reuse. Connections older than
+// maxLifetime or idle for longer than maxIdleTime are closed instead of being
+// handed out again, so fresh ones are opened on the next acquire.
+type connPool struct {
+	open        func(ctx context.Context) (adbc.Connection, error)
+	maxIdle     int
+	maxIdleTime time.Duration // zero keeps idle connections indefinitely
+	maxLifetime time.Duration // zero never retires connections by age
+
+	mu     sync.Mutex
+	idle   []*poolEntry // most recently released last
+	closed bool
+
+	// Counters for stats
+	inUse       int
+	acquires    int64
+	waits       int64 // acquires that had to open a connection
+	acquireTime time.Duration
+}
+
+// poolEntry is a backend connection owned by the pool
+type poolEntry struct {
+	conn     adbc.Connection
+	created  time.Time
+	lastUsed time.Time
+}
+
+func newConnPool(open func(ctx context.Context) (adbc.Connection, error), maxIdle int, maxIdleTime, maxLifetime time.Duration) *connPool {
+	return &connPool{open: open, maxIdle: maxIdle, maxIdleTime: maxIdleTime, maxLifetime: maxLifetime}
+}
+
+// expired reports whether e must be retired at now
+func (p *connPool) expired(e *poolEntry, now time.Time) bool {
+	return (p.maxLifetime > 0 && now.Sub(e.created) >= p.maxLifetime) ||
+		(p.maxIdleTime > 0 && now.Sub(e.lastUsed) >= p.maxIdleTime)
+}
+
+// acquire returns an idle connection that is still fresh, or a new one.
+// Closing the returned connection gives it back to the pool.
+func (p *connPool) acquire(ctx context.Context) (adbc.Connection, error) {
+	now := time.Now()
+
+	var (
+		entry   *poolEntry
+		retired []*poolEntry
+	)
+	p.mu.Lock()
+	for len(p.idle) > 0 && entry == nil {
+		last := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.expired(last, now) {
+			retired = append(retired, last)
+		} else {
+			entry = last
+		}
+	}
+	p.mu.Unlock()
+
+	for _, e := range retired {
+		e.conn.Close()
+	}
+
+	waited := entry == nil
+	if waited {
+		conn, err := p.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		entry = &poolEntry{conn: conn, created: now}
+	}
+
+	p.mu.Lock()
+	p.inUse++
+	p.acquires++
+	if waited {
+		p.waits++
+	}
+	p.acquireTime += time.Since(now)
+	p.mu.Unlock()
+
+	return &pooledConn{Connection: entry.conn, entry: entry, pool: p}, nil
+}
+
+// release returns e to the idle list, or closes it when the pool is full,
+// closed or e is past its lifetime
+func (p *connPool) release(e *poolEntry) error {
+	now := time.Now()
+	e.lastUsed = now
+
+	p.mu.Lock()
+	p.inUse--
+	if !p.closed && len(p.idle) < p.maxIdle && !p.expired(e, now) {
+		p.idle = append(p.idle, e)
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+
+	return e.conn.Close()
+}
+
+// poolStats is a snapshot of the pool's counters
+type poolStats struct {
+	inUse, idle int
+	acquires    int64
+	waits       int64
+	acquireTime time.Duration
+}
+
+// stats returns the current counters of the pool
+func (p *connPool) stats() poolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return poolStats{inUse: p.inUse, idle: len(p.idle), acquires: p.acquires, waits: p.waits, acquireTime: p.acquireTime}
+}
+
+// close closes the idle connections. Connections in use are closed when
+// they are released.
+func (p *connPool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mu.Unlock()
+
+	for _, e := range idle {
+		e.conn.Close()
+	}
+}
+
+// pooledConn is a connection borrowed from a connPool
+type pooledConn struct {
+	adbc.Connection
+	entry *poolEntry
+	pool  *connPool
+	once  sync.Once
+}
+
+// Close gives the connection back to the pool instead of closing it
+func (c *pooledConn) Close() error {
+	var err error
+	c.once.Do(func() { err = c.pool.release(c.entry) })
+	return err
+}
+
+// unwrapConnection returns the backend connection behind a pooled one, for
+// optional interfaces like adbc.GetSetOptions the wrapper doesn't forward
+func unwrapConnection(conn adbc.Connection) adbc.Connection {
+	if pooled, ok := conn.(*pooledConn); ok {
+		return pooled.Connection
+	}
+	return conn
+}
+
+// poolStatsSchema is the result schema of the PoolStats action. Waits
+// counts the acquires that found no idle connection and waited for a new
+// one to open.
+var poolStatsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "total", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "idle", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "in_use", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "acquires", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "waits", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "avg_acquire_ms", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+// poolStats returns the live counters of the connection pool
+func (s *DummyFlightSQLServer) poolStats(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.pool == nil {
+		return nil, status.Error(codes.FailedPrecondition, "connection pooling is disabled")
+	}
+	stats := s.pool.stats()
+
+	var avg float64
+	if stats.acquires > 0 {
+		avg = float64(stats.acquireTime.Microseconds()) / 1000 / float64(stats.acquires)
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, poolStatsSchema)
+	defer bldr.Release()
+
+	bldr.Field(0).(*array.Int64Builder).Append(int64(stats.inUse + stats.idle))
+	bldr.Field(1).(*array.Int64Builder).Append(int64(stats.idle))
+	bldr.Field(2).(*array.Int64Builder).Append(int64(stats.inUse))
+	bldr.Field(3).(*array.Int64Builder).Append(stats.acquires)
+	bldr.Field(4).(*array.Int64Builder).Append(stats.waits)
+	bldr.Field(5).(*array.Float64Builder).Append(avg)
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
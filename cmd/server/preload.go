@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// preloadTokenPrefix marks statement handles that fetch a preloaded result
+const preloadTokenPrefix = "preload:"
+
+// preloadRequest is the JSON body of the Preload action
+type preloadRequest struct {
+	Query string `json:"query"`
+	// TTLSeconds shortens how long the result is kept, PreloadTTL at most
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+var preloadSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "token", Type: arrow.BinaryTypes.String},
+	{Name: "expires", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+	{Name: "rows", Type: arrow.PrimitiveTypes.Int64},
+}, nil)
+
+// preload runs the query through the statement handlers and keeps its
+// materialized result under a fresh token. A statement ticket carrying the
+// token as handle streams the kept batches until the result expires.
+func (s *DummyFlightSQLServer) preload(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.preloads == nil {
+		return nil, status.Error(codes.FailedPrecondition, "preloading is disabled")
+	}
+
+	var req preloadRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, `expected a JSON body with a "query" field`)
+	}
+	if req.TTLSeconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl_seconds must not be negative, got %d", req.TTLSeconds)
+	}
+	ttl := s.cfg.PreloadTTL
+	if requested := time.Duration(req.TTLSeconds) * time.Second; requested > 0 && requested < ttl {
+		ttl = requested
+	}
+
+	schema, ch, err := s.executeStatement(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		batches []arrow.RecordBatch
+		size    int64
+		rows    int64
+	)
+	// Keep draining after an error so the streaming goroutine can finish
+	for chunk := range ch {
+		if chunk.Err != nil && err == nil {
+			err = chunk.Err
+		}
+		if chunk.Data == nil {
+			continue
+		}
+		if err != nil || chunk.Data.NumRows() == 0 {
+			chunk.Data.Release()
+			continue
+		}
+		batches = append(batches, chunk.Data)
+		size += util.TotalRecordSize(chunk.Data)
+		rows += chunk.Data.NumRows()
+	}
+	if err != nil {
+		releaseBatches(batches)
+		return nil, err
+	}
+	if limit := s.preloads.maxBytes; limit > 0 && size > limit {
+		releaseBatches(batches)
+		return nil, status.Errorf(codes.ResourceExhausted, "preloaded result of %d bytes exceeds PreloadMaxBytes of %d", size, limit)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		releaseBatches(batches)
+		return nil, err
+	}
+	token := preloadTokenPrefix + hex.EncodeToString(tokenBytes)
+	expires := time.Now().Add(ttl)
+	s.preloads.putFor(token, schema, batches, size, ttl)
+
+	bldr := array.NewRecordBuilder(s.Alloc, preloadSchema)
+	defer bldr.Release()
+
+	bldr.Field(0).(*array.StringBuilder).Append(token)
+	bldr.Field(1).(*array.TimestampBuilder).Append(arrow.Timestamp(expires.UnixMilli()))
+	bldr.Field(2).(*array.Int64Builder).Append(rows)
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// preparedStatement is a query created by CreatePreparedStatement together
+// with the parameter batches last bound to it
+type preparedStatement struct {
+	handle  string
+	query   string
+	created time.Time
+
+	// params holds the bound parameter batches as uploaded, one execution
+	// per row. Nil runs the query once without parameters.
+	params []arrow.RecordBatch
+}
+
+// release drops the bound parameter batches
+func (p *preparedStatement) release() {
+	for _, rec := range p.params {
+		rec.Release()
+	}
+	p.params = nil
+}
+
+// closePrepared drops every prepared statement, returning how many there
+// were
+func (s *DummyFlightSQLServer) closePrepared() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.prepared)
+	for handle, prepared := range s.prepared {
+		prepared.release()
+		delete(s.prepared, handle)
+	}
+	return n
+}
+
+// lookupPrepared returns the prepared statement registered under handle
+func (s *DummyFlightSQLServer) lookupPrepared(handle []byte) (*preparedStatement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prepared, ok := s.prepared[string(handle)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown prepared statement handle: %s", handle)
+	}
+	return prepared, nil
+}
+
+func (s *DummyFlightSQLServer) CreatePreparedStatement(ctx context.Context, req flightsql.ActionCreatePreparedStatementRequest) (flightsql.ActionCreatePreparedStatementResult, error) {
+	var result flightsql.ActionCreatePreparedStatementResult
+
+	if len(req.GetTransactionId()) > 0 {
+		return result, status.Error(codes.Unimplemented, "prepared statements are not supported in transactions")
+	}
+
+	query, err := s.rewriteQuery(ctx, req.GetQuery())
+	if err != nil {
+		return result, err
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return result, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return result, err
+	}
+	if err := stmt.Prepare(ctx); err != nil {
+		return result, status.Errorf(codes.InvalidArgument, "failed to prepare statement: %v", err)
+	}
+
+	result.ParameterSchema = s.parameterSchema(ctx, conn, stmt, query)
+
+	handleBytes := make([]byte, 16)
+	if _, err := rand.Read(handleBytes); err != nil {
+		return result, err
+	}
+	handle := hex.EncodeToString(handleBytes)
+
+	s.mu.Lock()
+	s.prepared[handle] = &preparedStatement{handle: handle, query: query, created: time.Now()}
+	s.mu.Unlock()
+
+	result.Handle = []byte(handle)
+	return result, nil
+}
+
+func (s *DummyFlightSQLServer) ClosePreparedStatement(ctx context.Context, req flightsql.ActionClosePreparedStatementRequest) error {
+	handle := string(req.GetPreparedStatementHandle())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prepared, ok := s.prepared[handle]
+	if !ok {
+		return status.Errorf(codes.NotFound, "unknown prepared statement handle: %s", handle)
+	}
+	delete(s.prepared, handle)
+	prepared.release()
+	return nil
+}
+
+// DoPutPreparedStatementQuery keeps the uploaded parameter batches for the
+// executions, replacing any earlier binding. The batches are retained as
+// they arrive; streamPrepared binds their rows one at a time.
+func (s *DummyFlightSQLServer) DoPutPreparedStatementQuery(ctx context.Context, cmd flightsql.PreparedStatementQuery, reader flight.MessageReader, writer flight.MetadataWriter) ([]byte, error) {
+	prepared, err := s.lookupPrepared(cmd.GetPreparedStatementHandle())
+	if err != nil {
+		return nil, err
+	}
+
+	var params []arrow.RecordBatch
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		params = append(params, rec)
+	}
+	if err := reader.Err(); err != nil {
+		for _, rec := range params {
+			rec.Release()
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	prepared.release()
+	prepared.params = params
+	s.mu.Unlock()
+
+	return cmd.GetPreparedStatementHandle(), nil
+}
+
+// DoPutPreparedStatementUpdate executes the statement once per uploaded
+// parameter row, or once without parameters when none are uploaded. Each
+// batch is bound and executed as it arrives, so only one is held at a time
+// however many rows the client streams.
+func (s *DummyFlightSQLServer) DoPutPreparedStatementUpdate(ctx context.Context, cmd flightsql.PreparedStatementUpdate, reader flight.MessageReader) (int64, error) {
+	prepared, err := s.lookupPrepared(cmd.GetPreparedStatementHandle())
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	affected, err := s.executePreparedUpdate(ctx, prepared.query, reader)
+	err = backendError(err)
+	s.audit(ctx, "DoPutPreparedStatementUpdate", prepared.query, start, affected, err)
+	return affected, err
+}
+
+// executePreparedUpdate runs query for every row of the parameter batches
+// read from params and returns the total affected row count
+func (s *DummyFlightSQLServer) executePreparedUpdate(ctx context.Context, query string, params array.RecordReader) (int64, error) {
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return 0, err
+	}
+	if s.cache != nil {
+		// The statement may change any cached result, even if it fails
+		// after some batches
+		defer s.cache.invalidate()
+	}
+
+	var (
+		affected int64
+		bound    bool
+		unknown  = !isDMLQuery(query)
+	)
+	for params.Next() {
+		rec := params.RecordBatch()
+		bound = true
+		if rec.NumRows() == 0 {
+			continue
+		}
+		if err := stmt.Bind(ctx, rec); err != nil {
+			return 0, err
+		}
+		n, err := stmt.ExecuteUpdate(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if n < 0 {
+			unknown = true
+		}
+		affected += n
+	}
+	if err := params.Err(); err != nil {
+		return 0, err
+	}
+
+	if !bound {
+		if affected, err = stmt.ExecuteUpdate(ctx); err != nil {
+			return 0, err
+		}
+		unknown = unknown || affected < 0
+	}
+	if unknown {
+		return unknownRowCount, nil
+	}
+	return affected, nil
+}
+
+func (s *DummyFlightSQLServer) GetFlightInfoPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if _, err := s.lookupPrepared(cmd.GetPreparedStatementHandle()); err != nil {
+		return nil, err
+	}
+
+	// The result schema depends on the bound parameters for some backends,
+	// so clients take it from the stream
+	return &flight.FlightInfo{
+		Endpoint: []*flight.FlightEndpoint{{
+			Ticket: &flight.Ticket{Ticket: desc.Cmd},
+		}},
+		FlightDescriptor: desc,
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+// DoGetPreparedStatement executes the statement once per bound parameter
+// row and streams the results of each execution in order. Every execution
+// ends on a batch boundary, so a batch never mixes rows of two bindings.
+func (s *DummyFlightSQLServer) DoGetPreparedStatement(ctx context.Context, cmd flightsql.PreparedStatementQuery) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	prepared, err := s.lookupPrepared(cmd.GetPreparedStatementHandle())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	schema, ch, err := s.streamPrepared(ctx, prepared)
+	return schema, s.auditStream(ctx, "DoGetPreparedStatement", prepared.query, start, ch, err), err
+}
+
+// streamPrepared runs the executions of a prepared statement. Their results
+// are redacted, zoned and reordered like those of plain statements, and the
+// row cap applies to all executions together.
+func (s *DummyFlightSQLServer) streamPrepared(ctx context.Context, prepared *preparedStatement) (*arrow.Schema, <-chan flight.StreamChunk, error) {
+	maxRows, err := maxRowsFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	query, bound := prepared.query, prepared.params != nil
+	// The batches are retained so that a new binding can replace them
+	// while the executions run
+	params := slices.Clone(prepared.params)
+	for _, rec := range params {
+		rec.Retain()
+	}
+	s.mu.Unlock()
+
+	releaseParams := func() {
+		for _, rec := range params {
+			rec.Release()
+		}
+	}
+
+	// nextBinding slices the next parameter row off the batches, returning
+	// nil once every row was bound
+	var batch, row int64
+	nextBinding := func() arrow.RecordBatch {
+		for batch < int64(len(params)) && row >= params[batch].NumRows() {
+			batch, row = batch+1, 0
+		}
+		if batch == int64(len(params)) {
+			return nil
+		}
+		row++
+		return params[batch].NewSlice(row-1, row)
+	}
+
+	first := nextBinding()
+	if bound && first == nil {
+		releaseParams()
+		return nil, nil, status.Error(codes.InvalidArgument, "no parameter rows bound to the prepared statement")
+	}
+	releaseFirst := func() {
+		if first != nil {
+			first.Release()
+		}
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		releaseFirst()
+		releaseParams()
+		return nil, nil, err
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		releaseFirst()
+		releaseParams()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	execute := func(binding arrow.RecordBatch) (array.RecordReader, error) {
+		if binding != nil {
+			defer binding.Release()
+			if err := stmt.Bind(ctx, binding); err != nil {
+				return nil, err
+			}
+		}
+		reader, _, err := stmt.ExecuteQuery(ctx)
+		return reader, err
+	}
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		releaseFirst()
+		releaseParams()
+		stmt.Close()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	// The first execution runs up front to learn the result schema
+	reader, err := execute(first)
+	if err != nil {
+		releaseParams()
+		stmt.Close()
+		conn.Close()
+		return nil, nil, err
+	}
+	backendSchema := reader.Schema()
+
+	schema := backendSchema
+	order := s.newColumnOrder(schema)
+	redactor := s.newColumnRedactor(query, schema)
+	if redactor != nil {
+		schema = redactor.schema
+	}
+	zoner := s.newTimestampZoner(schema)
+	if zoner != nil {
+		schema = zoner.schema
+	}
+	schema = order.schema(schema)
+
+	ch := make(chan flight.StreamChunk)
+
+	go func() {
+		defer close(ch)
+		defer s.recoverStream(ch)
+		defer conn.Close()
+		defer stmt.Close()
+
+		defer releaseParams()
+
+		var sent int64
+		for {
+			for reader.Next() {
+				rec := reader.RecordBatch()
+				if err := checkBatchSchema(rec, backendSchema); err != nil {
+					reader.Release()
+					ch <- flight.StreamChunk{Err: err}
+					return
+				}
+
+				if maxRows > 0 && sent+rec.NumRows() > maxRows {
+					rec = rec.NewSlice(0, maxRows-sent)
+				} else {
+					rec.Retain()
+				}
+				if redactor != nil {
+					rec = redactor.apply(rec)
+				}
+				if zoner != nil {
+					rec = zoner.apply(rec)
+				}
+				rec = order.apply(rec)
+
+				sent += rec.NumRows()
+				ch <- flight.StreamChunk{Data: rec}
+
+				// Stop reading from the backend once the cap is reached
+				if maxRows > 0 && sent >= maxRows {
+					break
+				}
+			}
+			err := reader.Err()
+			reader.Release()
+			if err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+
+			if maxRows > 0 && sent >= maxRows {
+				return
+			}
+			binding := nextBinding()
+			if binding == nil {
+				return
+			}
+			if reader, err = execute(binding); err != nil {
+				ch <- flight.StreamChunk{Err: err}
+				return
+			}
+		}
+	}()
+
+	return schema, ch, nil
+}
+
+// explainPrefixes are the per-vendor statements that describe a query plan
+// instead of running the query
+var explainPrefixes = map[string]string{
+	vendorDuckDB: "EXPLAIN ",
+	vendorSQLite: "EXPLAIN QUERY PLAN ",
+}
+
+// describePreparedPlan returns the backend's plan for the prepared statement
+// whose handle is the action body, one line per plan row. The first bound
+// parameter row, if any, is bound to the explained query.
+func (s *DummyFlightSQLServer) describePreparedPlan(ctx context.Context, body []byte) ([][]byte, error) {
+	prepared, err := s.lookupPrepared(body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var binding arrow.RecordBatch
+	if len(prepared.params) > 0 && prepared.params[0].NumRows() > 0 {
+		binding = prepared.params[0].NewSlice(0, 1)
+	}
+	s.mu.Unlock()
+	if binding != nil {
+		defer binding.Release()
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	prefix, ok := explainPrefixes[vendor]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "query plans are not supported by %s", vendor)
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(prefix + prepared.query); err != nil {
+		return nil, err
+	}
+	if binding != nil {
+		if err := stmt.Bind(ctx, binding); err != nil {
+			return nil, err
+		}
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to explain prepared statement: %v", err)
+	}
+	defer reader.Release()
+
+	plan, err := readPlan(reader)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{[]byte(strings.Join(plan, "\n"))}, nil
+}
+
+// readPlan returns the rows of an explain result. Both backends put the
+// plan text in the last column: SQLite's detail, DuckDB's explain_value.
+func readPlan(reader array.RecordReader) ([]string, error) {
+	var plan []string
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		col, err := asStringColumn(rec.Column(int(rec.NumCols()) - 1))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < col.Len(); i++ {
+			if !col.IsNull(i) {
+				plan = append(plan, col.Value(i))
+			}
+		}
+	}
+	return plan, reader.Err()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/arrow/util"
+)
+
+// progressMetadata is attached as app metadata to result batches to report
+// how many rows have been streamed so far, and at the end of the stream any
+// warnings the backend raised and the stream statistics
+type progressMetadata struct {
+	RowsSent int64        `json:"rows_sent"`
+	Warnings []string     `json:"warnings,omitempty"`
+	Stats    *streamStats `json:"stats,omitempty"`
+}
+
+// streamStats describes a completed result stream
+type streamStats struct {
+	Rows      int64   `json:"rows"`
+	Batches   int     `json:"batches"`
+	Bytes     int64   `json:"bytes"`
+	ElapsedMs float64 `json:"elapsed_ms"` // since the statement started
+}
+
+// progressTracker counts streamed batches and rows and decides which chunks
+// carry progress metadata
+type progressTracker struct {
+	interval int // emit progress every interval batches, 0 disables
+	batches  int
+	rows     int64
+	reported int64    // rows in the last progress update
+	warnings []string // attached to the final chunk
+
+	// stats adds streamStats to the final chunk, timed from start
+	stats bool
+	start time.Time
+	bytes int64
+}
+
+// chunk wraps a record batch in a stream chunk, attaching progress metadata
+// every interval batches
+func (p *progressTracker) chunk(rec arrow.RecordBatch) flight.StreamChunk {
+	p.batches++
+	p.rows += rec.NumRows()
+	if p.stats {
+		p.bytes += util.TotalRecordSize(rec)
+	}
+
+	chunk := flight.StreamChunk{Data: rec}
+	if p.interval > 0 && p.batches%p.interval == 0 {
+		chunk.AppMetadata, _ = json.Marshal(progressMetadata{RowsSent: p.rows})
+		p.reported = p.rows
+	}
+	return chunk
+}
+
+// final returns a trailing empty batch carrying the last progress update,
+// any warnings and the stream statistics, so the final value matches the
+// total row count without holding back the last data batch. ok is false
+// when nothing is left to report.
+func (p *progressTracker) final(schema *arrow.Schema, mem memory.Allocator) (chunk flight.StreamChunk, ok bool) {
+	pendingProgress := p.interval > 0 && p.rows != p.reported
+	if !pendingProgress && len(p.warnings) == 0 && !p.stats {
+		return flight.StreamChunk{}, false
+	}
+
+	bldr := array.NewRecordBuilder(mem, schema)
+	defer bldr.Release()
+
+	metadata := progressMetadata{RowsSent: p.rows, Warnings: p.warnings}
+	if p.stats {
+		metadata.Stats = &streamStats{
+			Rows:      p.rows,
+			Batches:   p.batches,
+			Bytes:     p.bytes,
+			ElapsedMs: float64(time.Since(p.start)) / float64(time.Millisecond),
+		}
+	}
+	chunk.Data = bldr.NewRecordBatch()
+	chunk.AppMetadata, _ = json.Marshal(metadata)
+	p.reported = p.rows
+	return chunk, true
+}
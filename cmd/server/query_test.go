@@ -1,20 +1,35 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow-adbc/go/adbc"
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/flight"
 	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
 	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // Mock StatementQuery implementation
 type mockStatementQuery struct {
-	query string
+	query         string
+	transactionID []byte
 }
 
 func (m *mockStatementQuery) GetQuery() string {
@@ -22,7 +37,7 @@ func (m *mockStatementQuery) GetQuery() string {
 }
 
 func (m *mockStatementQuery) GetTransactionId() []byte {
-	return nil
+	return m.transactionID
 }
 
 func TestDoGetStatement(t *testing.T) {
@@ -233,6 +248,45 @@ func TestGetSchemaStatement_SelectStar(t *testing.T) {
 	}
 }
 
+func TestGetSchemaStatement_TrailingSemicolon(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: 0, Cmd: []byte("test-command")}
+
+			for _, query := range []string{
+				"SELECT id, name FROM test_table;",
+				"SELECT id, name FROM test_table ;\n",
+				"SELECT id, name FROM test_table -- trailing comment",
+			} {
+				schemaResult, err := server.GetSchemaStatement(ctx, &mockStatementQuery{query: query}, desc)
+				if err != nil {
+					t.Fatalf("GetSchemaStatement failed for %q on %s: %v", query, driver.name, err)
+				}
+
+				schema, err := flight.DeserializeSchema(schemaResult.Schema, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatalf("Failed to deserialize schema for %s: %v", driver.name, err)
+				}
+				if schema.NumFields() != 2 {
+					t.Errorf("Expected 2 fields for %q on %s, got %d", query, driver.name, schema.NumFields())
+				}
+
+				if _, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc); err != nil {
+					t.Errorf("GetFlightInfoStatement failed for %q on %s: %v", query, driver.name, err)
+				}
+			}
+		})
+	}
+}
+
 func TestGetSchemaStatement_WithOrderBy(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -290,6 +344,98 @@ func TestGetSchemaStatement_WithOrderBy(t *testing.T) {
 	}
 }
 
+func TestGetSchemaStatement_MatchesFlightInfo(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			server.cfg.SortColumns = true
+			server.cfg.RedactColumns = map[string]ColumnRedaction{"test_table.value": {Mask: "***"}}
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			cmd := &mockStatementQuery{query: "SELECT value, name, id FROM test_table"}
+
+			schemaResult, err := server.GetSchemaStatement(context.Background(), cmd, desc)
+			if err != nil {
+				t.Fatalf("GetSchemaStatement failed for %s: %v", driver.name, err)
+			}
+			schema, err := flight.DeserializeSchema(schemaResult.Schema, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to deserialize schema for %s: %v", driver.name, err)
+			}
+
+			info, err := server.GetFlightInfoStatement(context.Background(), cmd, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			advertised, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to decode the advertised schema for %s: %v", driver.name, err)
+			}
+
+			if !schema.Equal(advertised) {
+				t.Errorf("Expected the schema of GetFlightInfoStatement for %s, got %s and %s", driver.name, schema, advertised)
+			}
+			if schema.Field(0).Name != "id" {
+				t.Errorf("Expected the columns sorted for %s, got %s", driver.name, schema)
+			}
+		})
+	}
+}
+
+func TestGetSchemaStatement_DecimalPrecisionScale(t *testing.T) {
+	for _, driver := range getTestDrivers(t) {
+		// SQLite has no fixed point type, DECIMAL columns come back as numbers
+		if driver.driverName != "duckdb" {
+			continue
+		}
+
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			execTestSQL(t, server,
+				"CREATE TABLE prices (amount DECIMAL(18,4))",
+				"INSERT INTO prices VALUES (12345.6789)")
+
+			ctx := context.Background()
+			cmd := &mockStatementQuery{query: "SELECT amount FROM prices"}
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+
+			schemaResult, err := server.GetSchemaStatement(ctx, cmd, desc)
+			if err != nil {
+				t.Fatalf("GetSchemaStatement failed for %s: %v", driver.name, err)
+			}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, cmd, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			for name, serialized := range map[string][]byte{
+				"GetSchemaStatement":     schemaResult.Schema,
+				"GetFlightInfoStatement": flightInfo.Schema,
+			} {
+				schema, err := flight.DeserializeSchema(serialized, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatalf("Failed to deserialize the %s schema for %s: %v", name, driver.name, err)
+				}
+				decimal, ok := schema.Field(0).Type.(*arrow.Decimal128Type)
+				if !ok {
+					t.Fatalf("Expected a decimal128 column from %s for %s, got %s", name, driver.name, schema.Field(0).Type)
+				}
+				if decimal.GetPrecision() != 18 || decimal.GetScale() != 4 {
+					t.Errorf("Expected DECIMAL(18,4) from %s for %s, got precision %d and scale %d",
+						name, driver.name, decimal.GetPrecision(), decimal.GetScale())
+				}
+			}
+		})
+	}
+}
+
 func TestGetSchemaStatement_ErrorHandling(t *testing.T) {
 	drivers := getTestDrivers(t)
 
@@ -343,7 +489,7 @@ func TestGetSchemaStatement_ErrorHandling(t *testing.T) {
 	t.Run("NilDatabase", func(t *testing.T) {
 		server := &DummyFlightSQLServer{
 			db:      nil,
-			queries: make(map[string]string),
+			queries: make(map[string]statementHandle),
 		}
 		server.Alloc = memory.DefaultAllocator
 
@@ -463,7 +609,7 @@ func TestDoGetStatement_ErrorHandling(t *testing.T) {
 	t.Run("NilDatabase", func(t *testing.T) {
 		server := &DummyFlightSQLServer{
 			db:      nil,
-			queries: map[string]string{"test-handle": "SELECT 1"},
+			queries: map[string]statementHandle{"test-handle": {handle: "test-handle", query: "SELECT 1"}},
 		}
 		server.Alloc = memory.DefaultAllocator
 
@@ -492,3 +638,2780 @@ func TestDoGetStatement_ErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+// multiBatchQuery generates enough rows to span several record batches on
+// both SQLite (1024 rows per batch) and DuckDB (2048 rows per batch)
+const multiBatchQuery = "WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < 5000) SELECT x FROM seq"
+
+func TestDoGetStatement_BatchRows(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	batchSizes := func(t *testing.T, server *DummyFlightSQLServer, ctx context.Context, ticket flightsql.StatementQueryTicket) []int64 {
+		t.Helper()
+		_, streamCh, err := server.DoGetStatement(ctx, ticket)
+		if err != nil {
+			t.Fatalf("DoGetStatement failed: %v", err)
+		}
+		var sizes []int64
+		for chunk := range streamCh {
+			if chunk.Err != nil {
+				t.Fatalf("Stream error: %v", chunk.Err)
+			}
+			sizes = append(sizes, chunk.Data.NumRows())
+			chunk.Data.Release()
+		}
+		return sizes
+	}
+
+	for _, driver := range drivers {
+		t.Run(driver.name+"_Header", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(batchRowsHeader, "50"))
+			query := "WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < 200) SELECT x FROM seq"
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			// The hint travels with the ticket, so DoGet needs no header
+			sizes := batchSizes(t, server, context.Background(), statementTicket)
+			if !slices.Equal(sizes, []int64{50, 50, 50, 50}) {
+				t.Errorf("Expected four 50-row batches for %s, got %v", driver.name, sizes)
+			}
+		})
+
+		t.Run(driver.name+"_Default", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// Larger than the backend batches, which are concatenated
+			server.cfg.BatchRows = 3000
+			server.storeStatement(statementHandle{handle: "batched", query: multiBatchQuery})
+
+			ticketBytes, err := flightsql.CreateStatementQueryTicket([]byte("batched"))
+			if err != nil {
+				t.Fatalf("Failed to create test ticket for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: ticketBytes})
+			if err != nil {
+				t.Fatalf("Failed to parse test ticket for %s: %v", driver.name, err)
+			}
+
+			sizes := batchSizes(t, server, context.Background(), statementTicket)
+			if !slices.Equal(sizes, []int64{3000, 2000}) {
+				t.Errorf("Expected batches of 3000 and 2000 rows for %s, got %v", driver.name, sizes)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_Coalesce(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(coalesceHeader, "true"))
+
+			_, streamCh, err := server.DoGetStatement(ctx, prepareStatementTicket(t, server, multiBatchQuery))
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			var sizes []int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				sizes = append(sizes, chunk.Data.NumRows())
+				chunk.Data.Release()
+			}
+			if !slices.Equal(sizes, []int64{5000}) {
+				t.Errorf("Expected a single batch of 5000 rows for %s, got %v", driver.name, sizes)
+			}
+
+			server.cfg.CoalesceMaxRows = 1000
+			_, streamCh, err = server.DoGetStatement(ctx, prepareStatementTicket(t, server, multiBatchQuery))
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			var streamErr error
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				t.Errorf("Expected no data above the coalesce cap for %s, got %d rows", driver.name, chunk.Data.NumRows())
+				chunk.Data.Release()
+			}
+			if status.Code(streamErr) != codes.ResourceExhausted {
+				t.Errorf("Expected ResourceExhausted above the coalesce cap for %s, got %v", driver.name, streamErr)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_ProgressMetadata(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			server.cfg.ProgressInterval = 2
+
+			ctx := context.Background()
+
+			cmd := &mockStatementQuery{query: multiBatchQuery}
+			desc := &flight.FlightDescriptor{
+				Type: 0,
+				Cmd:  []byte("test-command"),
+			}
+
+			flightInfo, err := server.GetFlightInfoStatement(ctx, cmd, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var batches int
+			var totalRows int64
+			var progress []int64
+			for chunk := range streamCh {
+				if chunk.Data == nil {
+					continue
+				}
+				batches++
+				totalRows += chunk.Data.NumRows()
+
+				if chunk.AppMetadata != nil {
+					var md progressMetadata
+					if err := json.Unmarshal(chunk.AppMetadata, &md); err != nil {
+						t.Fatalf("Failed to decode progress metadata for %s: %v", driver.name, err)
+					}
+					if md.RowsSent != totalRows {
+						t.Errorf("Expected progress of %d rows after batch %d for %s, got %d", totalRows, batches, driver.name, md.RowsSent)
+					}
+					progress = append(progress, md.RowsSent)
+				}
+				chunk.Data.Release()
+			}
+
+			if batches < 2 {
+				t.Fatalf("Expected a multi-batch result for %s, got %d batches", driver.name, batches)
+			}
+			if totalRows != 5000 {
+				t.Errorf("Expected 5000 rows for %s, got %d", driver.name, totalRows)
+			}
+			if len(progress) == 0 {
+				t.Fatalf("Expected progress metadata for %s, got none", driver.name)
+			}
+
+			for i := 1; i < len(progress); i++ {
+				if progress[i] <= progress[i-1] {
+					t.Errorf("Expected progress to increase monotonically for %s, got %v", driver.name, progress)
+					break
+				}
+			}
+
+			if last := progress[len(progress)-1]; last != totalRows {
+				t.Errorf("Expected final progress to match row count %d for %s, got %d", totalRows, driver.name, last)
+			}
+
+			t.Logf("Progress updates for %s over %d batches: %v", driver.name, batches, progress)
+		})
+	}
+}
+
+func TestDoGetStatement_StatelessTickets(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			key := []byte("test-signing-key")
+
+			// Two independent servers that only share the signing key
+			planner, cleanupPlanner := setupTestServer(t, driver)
+			defer cleanupPlanner()
+			planner.cfg = ServerConfig{StatelessTickets: true, TicketSigningKey: key}
+
+			executor, cleanupExecutor := setupTestServer(t, driver)
+			defer cleanupExecutor()
+			executor.cfg = ServerConfig{StatelessTickets: true, TicketSigningKey: key}
+
+			ctx := context.Background()
+
+			query := "SELECT 42 AS answer"
+			desc := &flight.FlightDescriptor{
+				Type: 0, // CMD type
+				Cmd:  []byte("test-command"),
+			}
+
+			flightInfo, err := planner.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			if len(planner.queries) != 0 {
+				t.Errorf("Expected no stored queries for %s, got %d", driver.name, len(planner.queries))
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := executor.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement on second server failed for %s: %v", driver.name, err)
+			}
+
+			var totalRows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if chunk.Data != nil {
+					totalRows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				}
+			}
+
+			if totalRows != 1 {
+				t.Errorf("Expected 1 row for %s, got %d", driver.name, totalRows)
+			}
+
+			// A ticket signed with a different key must be rejected
+			forged, err := encodeStatelessHandle([]byte("other-key"), query)
+			if err != nil {
+				t.Fatalf("Failed to encode handle for %s: %v", driver.name, err)
+			}
+
+			ticketBytes, err := flightsql.CreateStatementQueryTicket(forged)
+			if err != nil {
+				t.Fatalf("Failed to create forged ticket for %s: %v", driver.name, err)
+			}
+
+			forgedTicket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: ticketBytes})
+			if err != nil {
+				t.Fatalf("Failed to parse forged ticket for %s: %v", driver.name, err)
+			}
+
+			_, _, err = executor.DoGetStatement(ctx, forgedTicket)
+			if err == nil {
+				t.Errorf("Expected error for forged ticket in %s", driver.name)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_NullValues(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			setupNullTestData(t, server)
+
+			ctx := context.Background()
+
+			// Read the result through a real Flight SQL client
+			client := openFlightSQLClient(t, startTestFlightServer(t, server))
+
+			stmt, err := client.NewStatement()
+			if err != nil {
+				t.Fatalf("Failed to create statement for %s: %v", driver.name, err)
+			}
+			defer stmt.Close()
+
+			if err := stmt.SetSqlQuery("SELECT id, name, value FROM test_table ORDER BY id"); err != nil {
+				t.Fatalf("Failed to set query for %s: %v", driver.name, err)
+			}
+
+			reader, _, err := stmt.ExecuteQuery(ctx)
+			if err != nil {
+				t.Fatalf("ExecuteQuery failed for %s: %v", driver.name, err)
+			}
+			defer reader.Release()
+
+			var names, values []bool
+			for reader.Next() {
+				rec := reader.RecordBatch()
+				for i := 0; i < int(rec.NumRows()); i++ {
+					names = append(names, rec.Column(1).IsValid(i))
+					values = append(values, rec.Column(2).IsValid(i))
+				}
+			}
+			if err := reader.Err(); err != nil {
+				t.Fatalf("Failed to read result for %s: %v", driver.name, err)
+			}
+
+			if len(names) != 4 {
+				t.Fatalf("Expected 4 rows for %s, got %d", driver.name, len(names))
+			}
+
+			for row := 0; row < 3; row++ {
+				if !names[row] || !values[row] {
+					t.Errorf("Expected row %d to have valid name and value for %s", row, driver.name)
+				}
+			}
+
+			// The row inserted with NULLs must arrive as nulls, not as "" or 0
+			if names[3] {
+				t.Errorf("Expected name to be NULL in row 3 for %s", driver.name)
+			}
+			if values[3] {
+				t.Errorf("Expected value to be NULL in row 3 for %s", driver.name)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_Columns(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(columnsHeader, "id"))
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name, value FROM test_table"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			schema, streamCh, err := server.DoGetStatement(context.Background(), statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			if schema.NumFields() != 1 || schema.Field(0).Name != "id" {
+				t.Errorf("Expected only the id column for %s, got %v", driver.name, schema)
+			}
+
+			var totalRows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if n := chunk.Data.NumCols(); n != 1 {
+					t.Errorf("Expected batches with exactly one column for %s, got %d", driver.name, n)
+				}
+				totalRows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if totalRows != 3 {
+				t.Errorf("Expected 3 rows for %s, got %d", driver.name, totalRows)
+			}
+
+			empty := metadata.NewIncomingContext(context.Background(), metadata.Pairs(columnsHeader, "id,"))
+			if _, err := server.GetFlightInfoStatement(empty, &mockStatementQuery{query: "SELECT id FROM test_table"}, desc); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for an empty column name for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_MaxRows(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name+"_Header", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(maxRowsHeader, "2"))
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name FROM test_table ORDER BY id"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var totalRows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				totalRows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if totalRows != 2 {
+				t.Errorf("Expected 2 rows for %s, got %d", driver.name, totalRows)
+			}
+		})
+
+		t.Run(driver.name+"_EarlyStop", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// Register the statement without the LIMIT pushdown so only the
+			// streaming cap applies
+			server.storeStatement(statementHandle{handle: "capped", query: multiBatchQuery, maxRows: 10})
+
+			ticketBytes, err := flightsql.CreateStatementQueryTicket([]byte("capped"))
+			if err != nil {
+				t.Fatalf("Failed to create test ticket for %s: %v", driver.name, err)
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: ticketBytes})
+			if err != nil {
+				t.Fatalf("Failed to parse test ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(context.Background(), statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var batches int
+			var totalRows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				batches++
+				totalRows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if totalRows != 10 {
+				t.Errorf("Expected 10 rows for %s, got %d", driver.name, totalRows)
+			}
+
+			// The backend produces several batches, only the first is read
+			if batches != 1 {
+				t.Errorf("Expected streaming to stop after 1 batch for %s, got %d", driver.name, batches)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_QueryRewriter(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			var rewrittenQueries []string
+			server.cfg.QueryRewriter = QueryRewriterFunc(func(ctx context.Context, query string) (string, error) {
+				rewritten := query + " LIMIT 1"
+				rewrittenQueries = append(rewrittenQueries, rewritten)
+				return rewritten, nil
+			})
+
+			ctx := context.Background()
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name FROM test_table ORDER BY id"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			if len(rewrittenQueries) != 1 {
+				t.Fatalf("Expected the rewriter to run once for %s, got %d", driver.name, len(rewrittenQueries))
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var totalRows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				totalRows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			// test_table holds 3 rows, the rewritten query only returns 1
+			if totalRows != 1 {
+				t.Errorf("Expected 1 row from the rewritten query for %s, got %d", driver.name, totalRows)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_Warnings(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			warning := "implicit cast from DOUBLE to VARCHAR"
+			useFaultyDatabase(server, &faultyDatabase{warnings: []string{warning}})
+
+			ctx := context.Background()
+
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name FROM test_table ORDER BY id"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var totalRows int64
+			var warnings []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				totalRows += chunk.Data.NumRows()
+				chunk.Data.Release()
+
+				if chunk.AppMetadata != nil {
+					var md progressMetadata
+					if err := json.Unmarshal(chunk.AppMetadata, &md); err != nil {
+						t.Fatalf("Failed to decode app metadata for %s: %v", driver.name, err)
+					}
+					warnings = append(warnings, md.Warnings...)
+				}
+			}
+
+			if totalRows != 3 {
+				t.Errorf("Expected 3 rows for %s, got %d", driver.name, totalRows)
+			}
+			if len(warnings) != 1 || warnings[0] != warning {
+				t.Errorf("Expected warning %q for %s, got %v", warning, driver.name, warnings)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_DefaultSchema(t *testing.T) {
+	driver := getTestDrivers(t)[1] // DuckDB supports schemas
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	setupTestData(t, server)
+	setupTestSchemas(t, server, driver)
+	execTestSQL(t, server, `INSERT INTO test_schema.test_table (id, name) VALUES (42, 'from_test_schema')`)
+
+	server.cfg.DefaultSchema = "test_schema"
+
+	ctx := context.Background()
+
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+	flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT * FROM test_table"}, desc)
+	if err != nil {
+		t.Fatalf("GetFlightInfoStatement failed: %v", err)
+	}
+
+	statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+	if err != nil {
+		t.Fatalf("Failed to parse statement ticket: %v", err)
+	}
+
+	_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+	if err != nil {
+		t.Fatalf("DoGetStatement failed: %v", err)
+	}
+
+	var names []string
+	for chunk := range streamCh {
+		if chunk.Err != nil {
+			t.Fatalf("Stream error: %v", chunk.Err)
+		}
+		nameCol := chunk.Data.Column(1).(*array.String)
+		for i := 0; i < nameCol.Len(); i++ {
+			names = append(names, nameCol.Value(i))
+		}
+		chunk.Data.Release()
+	}
+
+	// main.test_table holds test1..test3, test_schema.test_table only one row
+	if len(names) != 1 || names[0] != "from_test_schema" {
+		t.Errorf("Expected the unqualified query to read test_schema.test_table, got %v", names)
+	}
+}
+
+// batchTracker counts the result batches that are alive at the same time
+type batchTracker struct {
+	mu   sync.Mutex
+	live int
+	peak int
+}
+
+func (t *batchTracker) track(rec arrow.RecordBatch) *trackedBatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.live++
+	t.peak = max(t.peak, t.live)
+
+	b := &trackedBatch{RecordBatch: rec, tracker: t}
+	b.refs.Store(1)
+	return b
+}
+
+func (t *batchTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.live--
+}
+
+// trackedBatch reports to its tracker once the last reference is released
+type trackedBatch struct {
+	arrow.RecordBatch
+	refs    atomic.Int64
+	tracker *batchTracker
+}
+
+func (b *trackedBatch) Retain() {
+	b.refs.Add(1)
+}
+
+func (b *trackedBatch) Release() {
+	if b.refs.Add(-1) == 0 {
+		b.tracker.done()
+		b.RecordBatch.Release()
+	}
+}
+
+// trackingReader wraps every batch of a backend reader in a trackedBatch
+type trackingReader struct {
+	array.RecordReader
+	tracker *batchTracker
+	cur     *trackedBatch
+}
+
+func (r *trackingReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if !r.RecordReader.Next() {
+		return false
+	}
+
+	rec := r.RecordReader.RecordBatch()
+	rec.Retain()
+	r.cur = r.tracker.track(rec)
+	return true
+}
+
+func (r *trackingReader) RecordBatch() arrow.RecordBatch {
+	return r.cur
+}
+
+func (r *trackingReader) Record() arrow.RecordBatch {
+	return r.cur
+}
+
+func (r *trackingReader) Release() {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	r.RecordReader.Release()
+}
+
+// binaryColumn covers the binary array types a backend may return for BLOBs
+type binaryColumn interface {
+	Len() int
+	ValueLen(i int) int
+}
+
+// blobExpr returns a SQL expression producing a BLOB of size bytes
+func blobExpr(driver testDriver, size int) string {
+	if driver.driverName == "duckdb" {
+		return fmt.Sprintf("repeat('a', %d)::BLOB", size)
+	}
+	return fmt.Sprintf("zeroblob(%d)", size)
+}
+
+// runStatement plans and starts streaming query on server
+func runStatement(t *testing.T, server *DummyFlightSQLServer, query string) <-chan flight.StreamChunk {
+	return runStatementContext(t, context.Background(), server, query)
+}
+
+// runStatementContext is runStatement with the given request context
+func runStatementContext(t *testing.T, ctx context.Context, server *DummyFlightSQLServer, query string) <-chan flight.StreamChunk {
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+	flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+	if err != nil {
+		t.Fatalf("GetFlightInfoStatement failed: %v", err)
+	}
+
+	statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+	if err != nil {
+		t.Fatalf("Failed to parse statement ticket: %v", err)
+	}
+
+	_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+	if err != nil {
+		t.Fatalf("DoGetStatement failed: %v", err)
+	}
+	return streamCh
+}
+
+func TestDoGetStatement_LargeBinary(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name+"_Bounded", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			tracker := &batchTracker{}
+			useFaultyDatabase(server, &faultyDatabase{tracker: tracker})
+
+			// 5000 rows of 2KB each, about 10MB of BLOB data over several batches
+			query := fmt.Sprintf("WITH RECURSIVE seq(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM seq WHERE x < 5000) SELECT x, %s AS data FROM seq", blobExpr(driver, 2048))
+
+			var batches int
+			var totalBytes int64
+			for chunk := range runStatement(t, server, query) {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				batches++
+				data := chunk.Data.Column(1).(binaryColumn)
+				for i := 0; i < data.Len(); i++ {
+					totalBytes += int64(data.ValueLen(i))
+				}
+				chunk.Data.Release()
+			}
+
+			if totalBytes != 5000*2048 {
+				t.Errorf("Expected %d BLOB bytes for %s, got %d", 5000*2048, driver.name, totalBytes)
+			}
+			if batches < 2 {
+				t.Fatalf("Expected a multi-batch result for %s, got %d batches", driver.name, batches)
+			}
+
+			// One batch in flight to the client and at most the next one read
+			// from the backend
+			if tracker.peak > 2 {
+				t.Errorf("Expected at most 2 live batches for %s, got %d", driver.name, tracker.peak)
+			}
+			if tracker.live != 0 {
+				t.Errorf("Expected all batches to be released for %s, %d still live", driver.name, tracker.live)
+			}
+		})
+
+		t.Run(driver.name+"_MaxCellBytes", func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			query := fmt.Sprintf("SELECT %s AS data", blobExpr(driver, 10<<20))
+
+			// Without a limit the whole 10MB value is streamed
+			var size int
+			for chunk := range runStatement(t, server, query) {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				size += chunk.Data.Column(0).(binaryColumn).ValueLen(0)
+				chunk.Data.Release()
+			}
+			if size != 10<<20 {
+				t.Errorf("Expected a %d byte BLOB for %s, got %d", 10<<20, driver.name, size)
+			}
+
+			// With a limit the stream fails instead
+			server.cfg.MaxCellBytes = 1 << 20
+
+			var streamErr error
+			for chunk := range runStatement(t, server, query) {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				chunk.Data.Release()
+			}
+			if streamErr == nil || !strings.Contains(streamErr.Error(), "maximum cell size") {
+				t.Errorf("Expected a maximum cell size error for %s, got %v", driver.name, streamErr)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_ResultCache(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			query := "SELECT id, name FROM test_table ORDER BY id"
+			counting := &faultyDatabase{}
+			useFaultyDatabase(server, counting)
+			server.cache = newResultCache(time.Minute, 1<<20)
+
+			fetch := func(query string) []string {
+				var names []string
+				for chunk := range runStatement(t, server, query) {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					nameCol := chunk.Data.Column(1).(*array.String)
+					for i := 0; i < nameCol.Len(); i++ {
+						names = append(names, nameCol.Value(i))
+					}
+					chunk.Data.Release()
+				}
+				return names
+			}
+
+			first := fetch(query)
+			// Reformatting the query doesn't change the cache key
+			second := fetch("SELECT id,  name\n FROM test_table ORDER BY id;")
+
+			if strings.Join(first, ",") != "test1,test2,test3" || strings.Join(second, ",") != strings.Join(first, ",") {
+				t.Errorf("Expected identical results for %s, got %v and %v", driver.name, first, second)
+			}
+			if n := counting.executed.Load(); n != 1 {
+				t.Errorf("Expected the backend to execute the query once for %s, got %d", driver.name, n)
+			}
+
+			if _, err := server.refreshMetadata(context.Background(), nil); err != nil {
+				t.Fatalf("RefreshMetadata failed for %s: %v", driver.name, err)
+			}
+			fetch(query)
+			if n := counting.executed.Load(); n != 2 {
+				t.Errorf("Expected RefreshMetadata to invalidate the cache for %s, got %d executions", driver.name, n)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_StatementOptions(t *testing.T) {
+	driver := getTestDrivers(t)[0] // SQLite
+
+	server, cleanup := setupTestServer(t, driver)
+	defer cleanup()
+
+	setupTestData(t, server)
+	server.cfg.AllowedStatementOptions = knownStatementOptions
+
+	query := "SELECT id, name FROM test_table ORDER BY id"
+
+	// batchSizes returns the row count of each non-empty batch
+	batchSizes := func(ctx context.Context) []int64 {
+		var sizes []int64
+		for chunk := range runStatementContext(t, ctx, server, query) {
+			if chunk.Err != nil {
+				t.Fatalf("Stream error: %v", chunk.Err)
+			}
+			if chunk.Data.NumRows() > 0 {
+				sizes = append(sizes, chunk.Data.NumRows())
+			}
+			chunk.Data.Release()
+		}
+		return sizes
+	}
+
+	if sizes := batchSizes(context.Background()); len(sizes) != 1 || sizes[0] != 3 {
+		t.Fatalf("Expected a single batch of 3 rows by default, got %v", sizes)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(statementOptionHeaderPrefix+"adbc.sqlite.query.batch_rows", "1"))
+	if sizes := batchSizes(ctx); len(sizes) != 3 {
+		t.Errorf("Expected batch_rows=1 to split the result into 3 batches, got %v", sizes)
+	}
+
+	unknown := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(statementOptionHeaderPrefix+"adbc.sqlite.unknown", "1"))
+	if sizes := batchSizes(unknown); len(sizes) != 1 {
+		t.Errorf("Expected unknown options to be ignored, got batches %v", sizes)
+	}
+
+	server.cfg.RejectUnknownStatementOptions = true
+	desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+	flightInfo, err := server.GetFlightInfoStatement(unknown, &mockStatementQuery{query: query}, desc)
+	if err != nil {
+		t.Fatalf("GetFlightInfoStatement failed: %v", err)
+	}
+	statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+	if err != nil {
+		t.Fatalf("Failed to parse statement ticket: %v", err)
+	}
+	_, _, err = server.DoGetStatement(unknown, statementTicket)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown option, got %v", err)
+	}
+}
+
+type mockEndTransaction struct {
+	transactionID []byte
+	action        flightsql.EndTransactionRequestType
+}
+
+func (m *mockEndTransaction) GetTransactionId() []byte {
+	return m.transactionID
+}
+
+func (m *mockEndTransaction) GetAction() flightsql.EndTransactionRequestType {
+	return m.action
+}
+
+func TestClose_CleansUpTransactionsAndPreparedStatements(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			faulty := &faultyDatabase{}
+			useFaultyDatabase(server, faulty)
+
+			ctx := context.Background()
+
+			prepared, err := server.CreatePreparedStatement(ctx, &pb.ActionCreatePreparedStatementRequest{Query: "SELECT * FROM test_table"})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+			txnID, err := server.BeginTransaction(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTransaction failed for %s: %v", driver.name, err)
+			}
+			txn, err := server.lookupTransaction(txnID)
+			if err != nil {
+				t.Fatalf("Transaction not found for %s: %v", driver.name, err)
+			}
+			if err := execOnConnection(ctx, txn.conn, "INSERT INTO test_table (id, name) VALUES (4, 'uncommitted')"); err != nil {
+				t.Fatalf("Insert failed for %s: %v", driver.name, err)
+			}
+
+			if err := server.Close(); err != nil {
+				t.Fatalf("Close failed for %s: %v", driver.name, err)
+			}
+
+			if n := faulty.rollbacks.Load(); n != 1 {
+				t.Errorf("Expected the open transaction to be rolled back for %s, got %d rollbacks", driver.name, n)
+			}
+			if n := faulty.open.Load(); n != 0 {
+				t.Errorf("Expected every connection to be closed for %s, %d still open", driver.name, n)
+			}
+			if _, err := server.lookupTransaction(txnID); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected the transaction to be gone for %s, got %v", driver.name, err)
+			}
+			if _, err := server.lookupPrepared(prepared.Handle); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected the prepared statement to be gone for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_TransactionRollbackCancelsRead(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			faulty := &faultyDatabase{}
+			useFaultyDatabase(server, faulty)
+
+			ctx := context.Background()
+
+			txnID, err := server.BeginTransaction(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTransaction failed for %s: %v", driver.name, err)
+			}
+
+			// Enough rows for many batches, so the read is still streaming
+			// when the transaction is rolled back
+			query := "WITH RECURSIVE r(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM r WHERE i < 1000000) SELECT i FROM r"
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query, transactionID: txnID}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, statementTicket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			first := <-streamCh
+			if first.Err != nil {
+				t.Fatalf("Stream error for %s: %v", driver.name, first.Err)
+			}
+			first.Data.Release()
+
+			err = server.EndTransaction(ctx, &mockEndTransaction{transactionID: txnID, action: flightsql.EndTransactionRollback})
+			if err != nil {
+				t.Fatalf("Rollback failed for %s: %v", driver.name, err)
+			}
+
+			var streamErr error
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				chunk.Data.Release()
+			}
+			if status.Code(streamErr) != codes.Aborted {
+				t.Errorf("Expected the read to be aborted for %s, got %v", driver.name, streamErr)
+			}
+
+			if n := faulty.open.Load(); n != 0 {
+				t.Errorf("Expected every connection to be closed for %s, %d still open", driver.name, n)
+			}
+
+			if _, _, err := server.DoGetStatement(ctx, statementTicket); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected the ended transaction to be gone for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestEndTransaction_CommitAndRollback(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			faulty := &faultyDatabase{}
+			useFaultyDatabase(server, faulty)
+
+			ctx := context.Background()
+
+			insert := func(id int, name string) []byte {
+				txnID, err := server.BeginTransaction(ctx, nil)
+				if err != nil {
+					t.Fatalf("BeginTransaction failed for %s: %v", driver.name, err)
+				}
+				txn, err := server.lookupTransaction(txnID)
+				if err != nil {
+					t.Fatalf("Transaction not found for %s: %v", driver.name, err)
+				}
+				query := fmt.Sprintf("INSERT INTO test_table (id, name) VALUES (%d, '%s')", id, name)
+				if err := execOnConnection(ctx, txn.conn, query); err != nil {
+					t.Fatalf("Insert failed for %s: %v", driver.name, err)
+				}
+				return txnID
+			}
+
+			committed := insert(4, "committed")
+			err := server.EndTransaction(ctx, &mockEndTransaction{transactionID: committed, action: flightsql.EndTransactionCommit})
+			if err != nil {
+				t.Fatalf("Commit failed for %s: %v", driver.name, err)
+			}
+
+			rolledBack := insert(5, "rolled_back")
+			err = server.EndTransaction(ctx, &mockEndTransaction{transactionID: rolledBack, action: flightsql.EndTransactionUnspecified})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for an unspecified action for %s, got %v", driver.name, err)
+			}
+			err = server.EndTransaction(ctx, &mockEndTransaction{transactionID: rolledBack, action: flightsql.EndTransactionRollback})
+			if err != nil {
+				t.Fatalf("Rollback failed for %s: %v", driver.name, err)
+			}
+
+			if n := faulty.open.Load(); n != 0 {
+				t.Errorf("Expected both transactions to release their connections for %s, %d still open", driver.name, n)
+			}
+			for _, txnID := range [][]byte{committed, rolledBack} {
+				err := server.EndTransaction(ctx, &mockEndTransaction{transactionID: txnID, action: flightsql.EndTransactionCommit})
+				if status.Code(err) != codes.NotFound {
+					t.Errorf("Expected the ended transaction to be gone for %s, got %v", driver.name, err)
+				}
+			}
+
+			var names []string
+			for chunk := range runStatement(t, server, "SELECT name FROM test_table WHERE id > 3 ORDER BY id") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					names = append(names, chunk.Data.Column(0).ValueStr(i))
+				}
+				chunk.Data.Release()
+			}
+			if strings.Join(names, ",") != "committed" {
+				t.Errorf("Expected only the committed insert to persist for %s, got %v", driver.name, names)
+			}
+		})
+	}
+}
+
+// mismatchedReader advertises the schema of the backend reader but returns
+// batches with a different one
+type mismatchedReader struct {
+	array.RecordReader
+	rec arrow.RecordBatch
+}
+
+func (r *mismatchedReader) RecordBatch() arrow.RecordBatch {
+	if r.rec == nil {
+		schema := arrow.NewSchema([]arrow.Field{{Name: "unexpected", Type: arrow.BinaryTypes.String}}, nil)
+		bldr := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+		defer bldr.Release()
+		bldr.Field(0).(*array.StringBuilder).Append("x")
+		r.rec = bldr.NewRecordBatch()
+	}
+	return r.rec
+}
+
+func (r *mismatchedReader) Release() {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	r.RecordReader.Release()
+}
+
+func TestDoGetStatement_SchemaMismatch(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			useFaultyDatabase(server, &faultyDatabase{mismatchedBatches: true})
+
+			var streamErr error
+			for chunk := range runStatement(t, server, "SELECT id, name FROM test_table") {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				chunk.Data.Release()
+				t.Errorf("Expected no data to be sent for %s", driver.name)
+			}
+
+			if status.Code(streamErr) != codes.Internal {
+				t.Fatalf("Expected an Internal error for %s, got %v", driver.name, streamErr)
+			}
+			if !strings.Contains(streamErr.Error(), "unexpected") {
+				t.Errorf("Expected the error to describe the mismatched schema for %s, got %v", driver.name, streamErr)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_HandleEviction(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			server.cfg.MaxStatementHandles = 2
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+
+			var tickets []flightsql.StatementQueryTicket
+			for id := 1; id <= 3; id++ {
+				query := fmt.Sprintf("SELECT name FROM test_table WHERE id = %d", id)
+				flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+				ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+				}
+				tickets = append(tickets, ticket)
+			}
+
+			if got := len(server.activeStatements()); got != 2 {
+				t.Errorf("Expected 2 live handles for %s, got %d", driver.name, got)
+			}
+
+			// The oldest handle was evicted to make room for the third
+			_, _, err := server.DoGetStatement(ctx, tickets[0])
+			if status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound for the evicted handle for %s, got %v", driver.name, err)
+			}
+
+			for i, ticket := range tickets[1:] {
+				_, streamCh, err := server.DoGetStatement(ctx, ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement failed for recent handle %d for %s: %v", i+1, driver.name, err)
+				}
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					chunk.Data.Release()
+				}
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_RetryBeforeData(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			flaky := &faultyDatabase{}
+			flaky.failExecutions.Store(1)
+			useFaultyDatabase(server, flaky)
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT name FROM test_table ORDER BY id"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			// The first attempt fails before any data flows
+			if _, _, err := server.DoGetStatement(ctx, ticket); err == nil || !strings.Contains(err.Error(), errInjected.Error()) {
+				t.Fatalf("Expected the first fetch to fail with the injected fault for %s, got %v", driver.name, err)
+			}
+
+			_, streamCh, err := server.DoGetStatement(ctx, ticket)
+			if err != nil {
+				t.Fatalf("Expected the retried fetch to succeed for %s, got: %v", driver.name, err)
+			}
+			var names []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					names = append(names, chunk.Data.Column(0).ValueStr(i))
+				}
+				chunk.Data.Release()
+			}
+			if strings.Join(names, ",") != "test1,test2,test3" {
+				t.Errorf("Expected all rows from the retried fetch for %s, got %v", driver.name, names)
+			}
+
+			// Once results were delivered the handle is consumed
+			if _, _, err := server.DoGetStatement(ctx, ticket); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound after the results were delivered for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestConnectionPool_MaxLifetime(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			faulty := &faultyDatabase{}
+			useFaultyDatabase(server, faulty)
+			server.pool = newConnPool(server.newConnection, 1, 0, 100*time.Millisecond)
+			defer server.pool.close()
+
+			ctx := context.Background()
+			borrow := func() adbc.Connection {
+				conn, err := server.openConnection(ctx)
+				if err != nil {
+					t.Fatalf("Failed to acquire connection for %s: %v", driver.name, err)
+				}
+				backend := unwrapConnection(conn)
+				conn.Close()
+				return backend
+			}
+
+			first := borrow()
+			if second := borrow(); second != first {
+				t.Errorf("Expected the idle connection to be reused for %s", driver.name)
+			}
+
+			time.Sleep(150 * time.Millisecond)
+			if third := borrow(); third == first {
+				t.Errorf("Expected the connection to be replaced after its lifetime for %s", driver.name)
+			}
+			if n := faulty.open.Load(); n != 1 {
+				t.Errorf("Expected the retired connection to be closed for %s, got %d open", driver.name, n)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_AdvertisedSchemaMismatch(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+
+			useFaultyDatabase(server, &faultyDatabase{driftingProbes: true})
+			server.cfg.ValidateResultSchema = true
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name FROM test_table"}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			_, _, err = server.DoGetStatement(ctx, ticket)
+			if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "advertised as id utf8") {
+				t.Fatalf("Expected the changed id column to be reported for %s, got %v", driver.name, err)
+			}
+
+			// Without validation the data streams with its actual schema
+			server.cfg.ValidateResultSchema = false
+			_, streamCh, err := server.DoGetStatement(ctx, ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed without validation for %s: %v", driver.name, err)
+			}
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				chunk.Data.Release()
+			}
+		})
+	}
+}
+
+type mockBeginSavepoint struct {
+	transactionID []byte
+	name          string
+}
+
+func (m *mockBeginSavepoint) GetTransactionId() []byte {
+	return m.transactionID
+}
+
+func (m *mockBeginSavepoint) GetName() string {
+	return m.name
+}
+
+type mockEndSavepoint struct {
+	savepointID []byte
+	action      flightsql.EndSavepointRequestType
+}
+
+func (m *mockEndSavepoint) GetSavepointId() []byte {
+	return m.savepointID
+}
+
+func (m *mockEndSavepoint) GetAction() flightsql.EndSavepointRequestType {
+	return m.action
+}
+
+func TestSavepoint_RollbackTo(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			ctx := context.Background()
+
+			txnID, err := server.BeginTransaction(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTransaction failed for %s: %v", driver.name, err)
+			}
+			txn, err := server.lookupTransaction(txnID)
+			if err != nil {
+				t.Fatalf("Transaction not found for %s: %v", driver.name, err)
+			}
+
+			if err := execOnConnection(ctx, txn.conn, "INSERT INTO test_table (id, name) VALUES (4, 'kept')"); err != nil {
+				t.Fatalf("Insert before the savepoint failed for %s: %v", driver.name, err)
+			}
+
+			savepointID, err := server.BeginSavepoint(ctx, &mockBeginSavepoint{transactionID: txnID, name: "before_undone"})
+			if driver.name == "DuckDB" {
+				if status.Code(err) != codes.Unimplemented {
+					t.Errorf("Expected Unimplemented for DuckDB savepoints, got %v", err)
+				}
+				server.EndTransaction(ctx, &mockEndTransaction{transactionID: txnID, action: flightsql.EndTransactionRollback})
+				return
+			}
+			if err != nil {
+				t.Fatalf("BeginSavepoint failed for %s: %v", driver.name, err)
+			}
+
+			if err := execOnConnection(ctx, txn.conn, "INSERT INTO test_table (id, name) VALUES (5, 'undone')"); err != nil {
+				t.Fatalf("Insert after the savepoint failed for %s: %v", driver.name, err)
+			}
+
+			rollback := &mockEndSavepoint{savepointID: savepointID, action: flightsql.EndSavepointRollback}
+			if err := server.EndSavepoint(ctx, rollback); err != nil {
+				t.Fatalf("Rollback to savepoint failed for %s: %v", driver.name, err)
+			}
+			if err := server.EndSavepoint(ctx, rollback); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected the ended savepoint to be gone for %s, got %v", driver.name, err)
+			}
+
+			err = server.EndTransaction(ctx, &mockEndTransaction{transactionID: txnID, action: flightsql.EndTransactionCommit})
+			if err != nil {
+				t.Fatalf("Commit failed for %s: %v", driver.name, err)
+			}
+
+			var names []string
+			for chunk := range runStatement(t, server, "SELECT name FROM test_table WHERE id > 3 ORDER BY id") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					names = append(names, chunk.Data.Column(0).ValueStr(i))
+				}
+				chunk.Data.Release()
+			}
+			if strings.Join(names, ",") != "kept" {
+				t.Errorf("Expected only the insert before the savepoint to persist for %s, got %v", driver.name, names)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_RedactColumns(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			server.cfg.RedactColumns = map[string]ColumnRedaction{"test_table.name": {Mask: "***"}}
+
+			var ids, names []string
+			for chunk := range runStatement(t, server, "SELECT id, name FROM test_table ORDER BY id") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					ids = append(ids, chunk.Data.Column(0).ValueStr(i))
+					names = append(names, chunk.Data.Column(1).ValueStr(i))
+				}
+				chunk.Data.Release()
+			}
+			if strings.Join(ids, ",") != "1,2,3" {
+				t.Errorf("Expected the id column intact for %s, got %v", driver.name, ids)
+			}
+			if strings.Join(names, ",") != "***,***,***" {
+				t.Errorf("Expected the name column masked for %s, got %v", driver.name, names)
+			}
+
+			// Queries not reading the table keep their name columns
+			for chunk := range runStatement(t, server, "SELECT 'visible' AS name") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if got := chunk.Data.Column(0).ValueStr(0); got != "visible" {
+					t.Errorf("Expected unrelated queries unredacted for %s, got %q", driver.name, got)
+				}
+				chunk.Data.Release()
+			}
+		})
+	}
+}
+
+func TestDoGetPreparedStatement_RedactColumns(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			server.cfg.RedactColumns = map[string]ColumnRedaction{"test_table.name": {Mask: "***"}}
+
+			prepared, err := server.CreatePreparedStatement(context.Background(), &pb.ActionCreatePreparedStatementRequest{Query: "SELECT id, name FROM test_table ORDER BY id"})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+			defer server.ClosePreparedStatement(context.Background(), &pb.ActionClosePreparedStatementRequest{PreparedStatementHandle: prepared.Handle})
+
+			// The row cap applies to prepared statements as well
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(maxRowsHeader, "2"))
+			_, streamCh, err := server.DoGetPreparedStatement(ctx, &pb.CommandPreparedStatementQuery{PreparedStatementHandle: prepared.Handle})
+			if err != nil {
+				t.Fatalf("DoGetPreparedStatement failed for %s: %v", driver.name, err)
+			}
+
+			var ids, names []string
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					ids = append(ids, chunk.Data.Column(0).ValueStr(i))
+					names = append(names, chunk.Data.Column(1).ValueStr(i))
+				}
+				chunk.Data.Release()
+			}
+			if strings.Join(ids, ",") != "1,2" {
+				t.Errorf("Expected the first two ids for %s, got %v", driver.name, ids)
+			}
+			if strings.Join(names, ",") != "***,***" {
+				t.Errorf("Expected the name column masked for %s, got %v", driver.name, names)
+			}
+		})
+	}
+}
+
+// memoryAuditSink keeps audit records in memory
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (m *memoryAuditSink) Audit(record AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *memoryAuditSink) all() []AuditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.records)
+}
+
+func TestAuditSink_Statements(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			sink := &memoryAuditSink{}
+			server.cfg.AuditSink = sink
+
+			const okQuery = "SELECT id FROM test_table"
+			for chunk := range runStatement(t, server, okQuery) {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				chunk.Data.Release()
+			}
+
+			ticket := prepareStatementTicket(t, server, multiBatchQuery)
+			useFaultyDatabase(server, &faultyDatabase{failAfterBatches: 1})
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			for chunk := range streamCh {
+				if chunk.Data != nil {
+					chunk.Data.Release()
+				}
+			}
+
+			records := sink.all()
+			if len(records) != 2 {
+				t.Fatalf("Expected 2 audit records for %s, got %d: %+v", driver.name, len(records), records)
+			}
+
+			ok := records[0]
+			if ok.Query != okQuery || ok.Method != "DoGetStatement" || ok.Error != "" || ok.Rows != 3 {
+				t.Errorf("Expected the successful query audited with 3 rows for %s, got %+v", driver.name, ok)
+			}
+
+			failed := records[1]
+			if failed.Query != multiBatchQuery || !strings.Contains(failed.Error, errInjected.Error()) {
+				t.Errorf("Expected the failed query audited with its error for %s, got %+v", driver.name, failed)
+			}
+			if failed.Time.IsZero() || failed.Time.Before(ok.Time) {
+				t.Errorf("Expected audit records in execution order for %s, got %v then %v", driver.name, ok.Time, failed.Time)
+			}
+		})
+	}
+}
+
+type memoryMetrics struct {
+	mu        sync.Mutex
+	queries   map[string]int
+	latencies map[string][]time.Duration
+	errors    map[codes.Code]int
+	gauges    map[string]float64
+}
+
+func newMemoryMetrics() *memoryMetrics {
+	return &memoryMetrics{
+		queries:   make(map[string]int),
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[codes.Code]int),
+		gauges:    make(map[string]float64),
+	}
+}
+
+func (m *memoryMetrics) IncQuery(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries[method]++
+}
+
+func (m *memoryMetrics) ObserveLatency(method string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies[method] = append(m.latencies[method], d)
+}
+
+func (m *memoryMetrics) IncError(method string, code codes.Code) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[code]++
+}
+
+func (m *memoryMetrics) SetGauge(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = value
+}
+
+func TestMetrics_Statements(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			metrics := newMemoryMetrics()
+			server.cfg.Metrics = metrics
+
+			for chunk := range runStatement(t, server, "SELECT id FROM test_table") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				chunk.Data.Release()
+			}
+
+			metrics.mu.Lock()
+			if metrics.queries["DoGetStatement"] != 1 || len(metrics.latencies["DoGetStatement"]) != 1 {
+				t.Errorf("Expected one query with its latency for %s, got %v and %v", driver.name, metrics.queries, metrics.latencies)
+			}
+			if len(metrics.errors) != 0 {
+				t.Errorf("Expected no errors for %s, got %v", driver.name, metrics.errors)
+			}
+			if running, ok := metrics.gauges[runningStatementsGauge]; !ok || running != 0 {
+				t.Errorf("Expected no running statements once the stream ended for %s, got %v (reported %v)", driver.name, running, ok)
+			}
+			metrics.mu.Unlock()
+
+			// Registered directly, since GetFlightInfoStatement would reject it
+			server.storeStatement(statementHandle{handle: "broken", query: "SELECT * FROM missing_table"})
+			ticketBytes, err := flightsql.CreateStatementQueryTicket([]byte("broken"))
+			if err != nil {
+				t.Fatalf("Failed to create test ticket for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: ticketBytes})
+			if err != nil {
+				t.Fatalf("Failed to parse test ticket for %s: %v", driver.name, err)
+			}
+			if _, _, err := server.DoGetStatement(context.Background(), statementTicket); err == nil {
+				t.Fatalf("Expected DoGetStatement to fail for a missing table for %s", driver.name)
+			}
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			if metrics.queries["DoGetStatement"] != 2 {
+				t.Errorf("Expected the failed query to be counted for %s, got %v", driver.name, metrics.queries)
+			}
+			failures := 0
+			for _, n := range metrics.errors {
+				failures += n
+			}
+			if failures != 1 {
+				t.Errorf("Expected one error for %s, got %v", driver.name, metrics.errors)
+			}
+		})
+	}
+}
+
+// connectionTracker numbers the connections opened on a backend and records
+// which of them every batch of query was read from
+type connectionTracker struct {
+	adbc.Database
+	query string
+
+	mu       sync.Mutex
+	opened   int
+	readFrom []int
+}
+
+func (d *connectionTracker) Open(ctx context.Context) (adbc.Connection, error) {
+	conn, err := d.Database.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opened++
+	return &trackedConnection{Connection: conn, tracker: d, id: d.opened}, nil
+}
+
+type trackedConnection struct {
+	adbc.Connection
+	tracker *connectionTracker
+	id      int
+}
+
+func (c *trackedConnection) NewStatement() (adbc.Statement, error) {
+	stmt, err := c.Connection.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &trackedStatement{Statement: stmt, conn: c}, nil
+}
+
+type trackedStatement struct {
+	adbc.Statement
+	conn  *trackedConnection
+	query string
+}
+
+func (s *trackedStatement) SetSqlQuery(query string) error {
+	s.query = query
+	return s.Statement.SetSqlQuery(query)
+}
+
+func (s *trackedStatement) ExecuteQuery(ctx context.Context) (array.RecordReader, int64, error) {
+	reader, n, err := s.Statement.ExecuteQuery(ctx)
+	if err != nil || s.query != s.conn.tracker.query {
+		return reader, n, err
+	}
+	return &trackedReader{RecordReader: reader, conn: s.conn}, n, nil
+}
+
+type trackedReader struct {
+	array.RecordReader
+	conn *trackedConnection
+}
+
+func (r *trackedReader) Next() bool {
+	if !r.RecordReader.Next() {
+		return false
+	}
+	tracker := r.conn.tracker
+	tracker.mu.Lock()
+	tracker.readFrom = append(tracker.readFrom, r.conn.id)
+	tracker.mu.Unlock()
+	return true
+}
+
+func TestDoGetStatement_PagedCursor(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			execTestSQL(t, server, "INSERT INTO test_table VALUES (4, 'test4', 4.5), (5, 'test5', 5.5), (6, 'test6', 6.5), (7, 'test7', 7.5), (8, 'test8', 8.5), (9, 'test9', 9.5), (10, 'test10', 10.5)")
+
+			const query = "SELECT id FROM test_table ORDER BY id"
+			tracker := &connectionTracker{Database: *server.db, query: query}
+			var db adbc.Database = tracker
+			server.db = &db
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(pageSizeHeader, "4"))
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+
+			var ids []string
+			for page := 1; page <= 3; page++ {
+				_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement of page %d failed for %s: %v", page, driver.name, err)
+				}
+
+				var rows int64
+				var meta pageMetadata
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error on page %d for %s: %v", page, driver.name, chunk.Err)
+					}
+					rows += chunk.Data.NumRows()
+					for i := 0; i < int(chunk.Data.NumRows()); i++ {
+						ids = append(ids, chunk.Data.Column(0).ValueStr(i))
+					}
+					if chunk.AppMetadata != nil {
+						if err := json.Unmarshal(chunk.AppMetadata, &meta); err != nil {
+							t.Fatalf("Invalid page metadata for %s: %v", driver.name, err)
+						}
+					}
+					chunk.Data.Release()
+				}
+
+				if want := []int64{4, 4, 2}[page-1]; rows != want {
+					t.Errorf("Expected %d rows on page %d for %s, got %d", want, page, driver.name, rows)
+				}
+				if meta.Page != int64(page) || meta.More != (page < 3) {
+					t.Errorf("Unexpected metadata on page %d for %s: %+v", page, driver.name, meta)
+				}
+
+				// Other statements meanwhile run on connections of their own
+				for chunk := range runStatement(t, server, "SELECT COUNT(*) FROM test_table") {
+					if chunk.Data != nil {
+						chunk.Data.Release()
+					}
+				}
+			}
+
+			want := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+			if !slices.Equal(ids, want) {
+				t.Errorf("Expected ids %v across the pages for %s, got %v", want, driver.name, ids)
+			}
+
+			tracker.mu.Lock()
+			readFrom := slices.Compact(slices.Clone(tracker.readFrom))
+			opened := tracker.opened
+			tracker.mu.Unlock()
+			if len(readFrom) != 1 {
+				t.Errorf("Expected every page to be read from one pinned connection for %s, got connections %v", driver.name, readFrom)
+			}
+			if opened < 2 {
+				t.Errorf("Expected the other statements to open connections of their own for %s, got %d connections", driver.name, opened)
+			}
+
+			server.mu.Lock()
+			cursors := len(server.cursors)
+			server.mu.Unlock()
+			if cursors != 0 {
+				t.Errorf("Expected the exhausted cursor to be released for %s, got %d open", driver.name, cursors)
+			}
+			if _, _, err := server.DoGetStatement(context.Background(), ticket); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound after the last page for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestGetFlightInfoStatement_DeferSchema(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		for _, mode := range []string{"Config", "Header"} {
+			t.Run(driver.name+"_"+mode, func(t *testing.T) {
+				server, cleanup := setupTestServer(t, driver)
+				defer cleanup()
+
+				setupTestData(t, server)
+
+				const query = "SELECT id, name FROM test_table ORDER BY id"
+				tracker := &connectionTracker{Database: *server.db, query: query}
+				var db adbc.Database = tracker
+				server.db = &db
+
+				ctx := context.Background()
+				if mode == "Config" {
+					server.cfg.DeferResultSchema = true
+				} else {
+					ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(deferSchemaHeader, "true"))
+				}
+
+				desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+				flightInfo, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+				if len(flightInfo.Schema) != 0 {
+					t.Errorf("Expected the FlightInfo to omit the schema for %s, got %d bytes", driver.name, len(flightInfo.Schema))
+				}
+
+				tracker.mu.Lock()
+				opened := tracker.opened
+				tracker.mu.Unlock()
+				if opened != 0 {
+					t.Errorf("Expected GetFlightInfoStatement not to touch the backend for %s, opened %d connections", driver.name, opened)
+				}
+
+				ticket, err := flightsql.GetStatementQueryTicket(flightInfo.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+				}
+				schema, streamCh, err := server.DoGetStatement(ctx, ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+				}
+				var rows int64
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					rows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				}
+				if rows != 3 || schema.NumFields() != 2 {
+					t.Errorf("Expected 3 rows of 2 columns from DoGet for %s, got %d rows of %s", driver.name, rows, schema)
+				}
+
+				tracker.mu.Lock()
+				opened = tracker.opened
+				tracker.mu.Unlock()
+				if opened != 1 {
+					t.Errorf("Expected the backend to be hit once, during DoGet, for %s, opened %d connections", driver.name, opened)
+				}
+			})
+		}
+	}
+}
+
+func TestDoGetStatement_NoResultSet(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// Register the statement directly, as a ticket issued elsewhere
+			// would be, so that DoGetStatement sees it
+			query := "CREATE TABLE ddl_table (id INTEGER)"
+			server.storeStatement(statementHandle{handle: "ddl", query: query})
+
+			ticketBytes, err := flightsql.CreateStatementQueryTicket([]byte("ddl"))
+			if err != nil {
+				t.Fatalf("Failed to create test ticket for %s: %v", driver.name, err)
+			}
+			statementTicket, err := flightsql.GetStatementQueryTicket(&flight.Ticket{Ticket: ticketBytes})
+			if err != nil {
+				t.Fatalf("Failed to parse test ticket for %s: %v", driver.name, err)
+			}
+
+			_, _, err = server.DoGetStatement(context.Background(), statementTicket)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Expected InvalidArgument for %s, got %v", driver.name, err)
+			}
+			if !strings.Contains(err.Error(), "CommandStatementUpdate") {
+				t.Errorf("Expected the error to point to the update path for %s, got %v", driver.name, err)
+			}
+
+			// The statement was rejected before it ran
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			if _, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: "SELECT * FROM ddl_table"}, desc); err == nil {
+				t.Errorf("Expected ddl_table not to exist for %s", driver.name)
+			}
+
+			// GetFlightInfoStatement rejects it the same way
+			if _, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: query}, desc); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument from GetFlightInfoStatement for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestTableQuery_QuotedIdentifiers(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			// SQLite's schemas are attached databases, which don't outlive
+			// the connection, so it only gets the reserved table name
+			table := tableRef{schema: "main", table: "order by"}
+			if driver.driverName == "duckdb" {
+				table.schema = "my schema"
+				execTestSQL(t, server, `CREATE SCHEMA "my schema"`)
+			}
+			name := server.qualifiedName(table.schema, table.table)
+			execTestSQL(t, server,
+				"CREATE TABLE "+name+` (id INTEGER, "select" TEXT)`,
+				"INSERT INTO "+name+` VALUES (1, 'a'), (2, 'b')`)
+
+			query := server.tableQuery(table)
+			if want := `SELECT * FROM "` + table.schema + `"."order by"`; query != want {
+				t.Errorf("Expected %s for %s, got %s", want, driver.name, query)
+			}
+
+			var rows int64
+			for chunk := range runStatement(t, server, query) {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if rows != 2 {
+				t.Errorf("Expected 2 rows for %s, got %d", driver.name, rows)
+			}
+		})
+	}
+}
+
+// paramStream generates parameter batches of sequential ids on demand and
+// releases each one once the next is read, as a DoPut stream does. Only
+// the RecordReader methods of the embedded MessageReader are implemented.
+type paramStream struct {
+	flight.MessageReader
+	schema  *arrow.Schema
+	tracker *batchTracker
+	batches int
+	rows    int
+	read    int
+	cur     arrow.RecordBatch
+}
+
+func (p *paramStream) Schema() *arrow.Schema { return p.schema }
+func (p *paramStream) Err() error            { return nil }
+
+func (p *paramStream) Next() bool {
+	p.Release()
+	if p.read == p.batches {
+		return false
+	}
+
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, p.schema)
+	defer bldr.Release()
+	for i := 0; i < p.rows; i++ {
+		bldr.Field(0).(*array.Int64Builder).Append(int64(p.read*p.rows + i))
+	}
+	p.read++
+	p.cur = p.tracker.track(bldr.NewRecordBatch())
+	return true
+}
+
+func (p *paramStream) RecordBatch() arrow.RecordBatch { return p.cur }
+func (p *paramStream) Record() arrow.RecordBatch      { return p.cur }
+
+func (p *paramStream) Release() {
+	if p.cur != nil {
+		p.cur.Release()
+		p.cur = nil
+	}
+}
+
+func TestDoPutPreparedStatementUpdate_StreamedParameters(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			execTestSQL(t, server, "CREATE TABLE bulk (id BIGINT)")
+
+			ctx := context.Background()
+			prepared, err := server.CreatePreparedStatement(ctx, &pb.ActionCreatePreparedStatementRequest{Query: "INSERT INTO bulk VALUES (?)"})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+
+			tracker := &batchTracker{}
+			params := &paramStream{
+				schema:  arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil),
+				tracker: tracker,
+				batches: 10,
+				rows:    1000,
+			}
+			cmd := &pb.CommandPreparedStatementUpdate{PreparedStatementHandle: prepared.Handle}
+			affected, err := server.DoPutPreparedStatementUpdate(ctx, cmd, params)
+			params.Release()
+			if err != nil {
+				t.Fatalf("DoPutPreparedStatementUpdate failed for %s: %v", driver.name, err)
+			}
+			if affected != 10000 {
+				t.Errorf("Expected 10000 affected rows for %s, got %d", driver.name, affected)
+			}
+
+			var count int64
+			for chunk := range runStatement(t, server, "SELECT COUNT(*) FROM bulk") {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if chunk.Data.NumRows() > 0 {
+					count = chunk.Data.Column(0).(*array.Int64).Value(0)
+				}
+				chunk.Data.Release()
+			}
+			if count != 10000 {
+				t.Errorf("Expected 10000 rows in the table for %s, got %d", driver.name, count)
+			}
+
+			// The batch being executed and at most the one the backend still
+			// holds from the previous binding
+			if tracker.peak > 2 {
+				t.Errorf("Expected at most 2 live parameter batches for %s, got %d", driver.name, tracker.peak)
+			}
+			if tracker.live != 0 {
+				t.Errorf("Expected all parameter batches to be released for %s, %d still live", driver.name, tracker.live)
+			}
+		})
+	}
+}
+
+func TestDoPutPreparedStatementQuery_StreamedParameters(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+			prepared, err := server.CreatePreparedStatement(ctx, &pb.ActionCreatePreparedStatementRequest{Query: "SELECT ? AS x"})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+
+			tracker := &batchTracker{}
+			params := &paramStream{
+				schema:  arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil),
+				tracker: tracker,
+				batches: 10,
+				rows:    1000,
+			}
+			cmd := &pb.CommandPreparedStatementQuery{PreparedStatementHandle: prepared.Handle}
+			_, err = server.DoPutPreparedStatementQuery(ctx, cmd, params, nil)
+			params.Release()
+			if err != nil {
+				t.Fatalf("DoPutPreparedStatementQuery failed for %s: %v", driver.name, err)
+			}
+
+			// The uploaded batches are kept as they are, not copied per row
+			if tracker.live != 10 {
+				t.Errorf("Expected the 10 uploaded batches to be kept for %s, got %d", driver.name, tracker.live)
+			}
+
+			_, streamCh, err := server.DoGetPreparedStatement(ctx, cmd)
+			if err != nil {
+				t.Fatalf("DoGetPreparedStatement failed for %s: %v", driver.name, err)
+			}
+			var rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				for i := 0; i < int(chunk.Data.NumRows()); i++ {
+					if got := chunk.Data.Column(0).ValueStr(i); got != strconv.FormatInt(rows, 10) {
+						t.Fatalf("Expected row %d to hold its parameter for %s, got %s", rows, driver.name, got)
+					}
+					rows++
+				}
+				chunk.Data.Release()
+			}
+			if rows != 10000 {
+				t.Errorf("Expected one row per parameter row for %s, got %d", driver.name, rows)
+			}
+
+			if err := server.ClosePreparedStatement(ctx, &pb.ActionClosePreparedStatementRequest{PreparedStatementHandle: prepared.Handle}); err != nil {
+				t.Fatalf("ClosePreparedStatement failed for %s: %v", driver.name, err)
+			}
+			if tracker.live != 0 {
+				t.Errorf("Expected all parameter batches to be released for %s, %d still live", driver.name, tracker.live)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_ColumnOrder(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			execTestSQL(t, server,
+				"CREATE TABLE unordered (zeta BIGINT, alpha TEXT, mid REAL)",
+				"INSERT INTO unordered VALUES (1, 'a', 1.5)")
+
+			// columns returns the column names of the FlightInfo schema and of
+			// every streamed batch, which must agree
+			columns := func(query string) []string {
+				desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+				info, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: query}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+				advertised, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+				if err != nil {
+					t.Fatalf("Failed to decode the advertised schema for %s: %v", driver.name, err)
+				}
+				var names []string
+				for _, field := range advertised.Fields() {
+					names = append(names, field.Name)
+				}
+
+				ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+				}
+				_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+				}
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					for i, field := range chunk.Data.Schema().Fields() {
+						if field.Name != names[i] {
+							t.Errorf("Expected streamed column %d to be %s for %s, got %s", i, names[i], driver.name, field.Name)
+						}
+					}
+					if chunk.Data.NumRows() > 0 {
+						if zeta := chunk.Data.Column(slices.Index(names, "zeta")).(*array.Int64); zeta.Value(0) != 1 {
+							t.Errorf("Expected the zeta column to keep its values for %s, got %d", driver.name, zeta.Value(0))
+						}
+					}
+					chunk.Data.Release()
+				}
+				return names
+			}
+
+			server.cfg.SortColumns = true
+			if got := columns("SELECT * FROM unordered"); !slices.Equal(got, []string{"alpha", "mid", "zeta"}) {
+				t.Errorf("Expected sorted columns for %s, got %v", driver.name, got)
+			}
+
+			server.cfg.ColumnOrder = []string{"zeta"}
+			if got := columns("SELECT * FROM unordered"); !slices.Equal(got, []string{"zeta", "alpha", "mid"}) {
+				t.Errorf("Expected zeta first, then sorted columns for %s, got %v", driver.name, got)
+			}
+
+			// Table flights advertise the same order as statements
+			tableSchema, err := server.tableSchema(context.Background(), tableRef{table: "unordered"})
+			if err != nil {
+				t.Fatalf("tableSchema failed for %s: %v", driver.name, err)
+			}
+			var tableColumns []string
+			for _, field := range tableSchema.Fields() {
+				tableColumns = append(tableColumns, field.Name)
+			}
+			if !slices.Equal(tableColumns, []string{"zeta", "alpha", "mid"}) {
+				t.Errorf("Expected the table flight to order columns like statements for %s, got %v", driver.name, tableColumns)
+			}
+		})
+	}
+}
+
+func TestGetSchemaStatement_WideSchema(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	columns := make([]string, 2000)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("%d AS c%d", i, i)
+	}
+	query := "SELECT " + strings.Join(columns, ", ")
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+
+			// Without limits the wide schema is serialized as it is
+			result, err := server.GetSchemaStatement(ctx, &mockStatementQuery{query: query}, desc)
+			if err != nil {
+				t.Fatalf("GetSchemaStatement failed for %s: %v", driver.name, err)
+			}
+			schema, err := flight.DeserializeSchema(result.Schema, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to decode the schema for %s: %v", driver.name, err)
+			}
+			if schema.NumFields() != 2000 {
+				t.Errorf("Expected 2000 columns for %s, got %d", driver.name, schema.NumFields())
+			}
+
+			server.cfg.MaxSchemaColumns = 1000
+			if _, err := server.GetSchemaStatement(ctx, &mockStatementQuery{query: query}, desc); status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("Expected ResourceExhausted from GetSchemaStatement for %s, got %v", driver.name, err)
+			}
+			if _, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: query}, desc); status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("Expected ResourceExhausted from GetFlightInfoStatement for %s, got %v", driver.name, err)
+			}
+			if len(server.queries) != 0 {
+				t.Errorf("Expected no statement handle to be kept for %s, got %d", driver.name, len(server.queries))
+			}
+
+			server.cfg.MaxSchemaColumns = 0
+			server.cfg.MaxSchemaBytes = len(result.Schema) - 1
+			if _, err := server.GetSchemaStatement(ctx, &mockStatementQuery{query: query}, desc); status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("Expected ResourceExhausted for the encoded size for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_DefaultTimezone(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			// SQLite returns timestamps as text
+			if driver.name == "SQLite" {
+				t.Skip("SQLite has no timestamp columns")
+			}
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			server.cfg.DefaultTimezone = "America/New_York"
+
+			query := "SELECT TIMESTAMP '2024-01-01 12:00:00' AS ts"
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			info, err := server.GetFlightInfoStatement(context.Background(), &mockStatementQuery{query: query}, desc)
+			if err != nil {
+				t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+			}
+			advertised, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+			if err != nil {
+				t.Fatalf("Failed to decode the advertised schema for %s: %v", driver.name, err)
+			}
+			if ts, ok := advertised.Field(0).Type.(*arrow.TimestampType); !ok || ts.TimeZone != "America/New_York" {
+				t.Errorf("Expected the advertised ts column to be zoned for %s, got %s", driver.name, advertised.Field(0).Type)
+			}
+
+			ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+			if err != nil {
+				t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+			}
+			schema, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+			if ts, ok := schema.Field(0).Type.(*arrow.TimestampType); !ok || ts.TimeZone != "America/New_York" {
+				t.Errorf("Expected the streamed ts column to be zoned for %s, got %s", driver.name, schema.Field(0).Type)
+			}
+
+			var rows int64
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if chunk.Data.NumRows() > 0 {
+					col := chunk.Data.Column(0).(*array.Timestamp)
+					ts := col.DataType().(*arrow.TimestampType)
+					if ts.TimeZone != "America/New_York" {
+						t.Errorf("Expected the batch ts column to be zoned for %s, got %s", driver.name, ts)
+					}
+					// Noon in New York is 17:00 UTC in winter
+					toTime, _ := ts.GetToTimeFunc()
+					want := time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)
+					if got := toTime(col.Value(0)); !got.Equal(want) {
+						t.Errorf("Expected %s for %s, got %s", want, driver.name, got)
+					}
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+			if rows != 1 {
+				t.Errorf("Expected 1 row for %s, got %d", driver.name, rows)
+			}
+
+			// Table flights advertise the zoned schema as well
+			execTestSQL(t, server, "CREATE TABLE events (ts TIMESTAMP)")
+			tableSchema, err := server.tableSchema(context.Background(), tableRef{table: "events"})
+			if err != nil {
+				t.Fatalf("tableSchema failed for %s: %v", driver.name, err)
+			}
+			if ts, ok := tableSchema.Field(0).Type.(*arrow.TimestampType); !ok || ts.TimeZone != "America/New_York" {
+				t.Errorf("Expected the table flight ts column to be zoned for %s, got %s", driver.name, tableSchema.Field(0).Type)
+			}
+		})
+	}
+}
+
+func TestCreatePreparedStatement_ParameterSchema(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			query := "SELECT * FROM test_table WHERE id = ? AND name = ?"
+			prepared, err := server.CreatePreparedStatement(context.Background(), &pb.ActionCreatePreparedStatementRequest{Query: query})
+			if err != nil {
+				t.Fatalf("CreatePreparedStatement failed for %s: %v", driver.name, err)
+			}
+			defer server.ClosePreparedStatement(context.Background(), &pb.ActionClosePreparedStatementRequest{PreparedStatementHandle: prepared.Handle})
+
+			params := prepared.ParameterSchema
+			if params == nil || params.NumFields() != 2 {
+				t.Fatalf("Expected 2 parameters for %s, got %v", driver.name, params)
+			}
+			if typ := params.Field(0).Type; !arrow.IsInteger(typ.ID()) {
+				t.Errorf("Expected an integer id parameter for %s, got %s", driver.name, typ)
+			}
+			if typ := params.Field(1).Type; typ.ID() != arrow.STRING && typ.ID() != arrow.LARGE_STRING && typ.ID() != arrow.STRING_VIEW {
+				t.Errorf("Expected a string name parameter for %s, got %s", driver.name, typ)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_RepeatFetch(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+
+			ctx := context.Background()
+			// plan returns the ticket of a new statement reading test_table
+			plan := func() flightsql.StatementQueryTicket {
+				desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+				info, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: "SELECT id, name FROM test_table ORDER BY id"}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+				ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+				}
+				return ticket
+			}
+			// fetch returns the row count and the serialized batches of a
+			// DoGet of ticket
+			fetch := func(ticket flightsql.StatementQueryTicket) (int64, []byte, error) {
+				_, streamCh, err := server.DoGetStatement(ctx, ticket)
+				if err != nil {
+					return 0, nil, err
+				}
+				var (
+					rows    int64
+					encoded []byte
+				)
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+					}
+					if chunk.Data.NumRows() > 0 {
+						serialized, err := serializeRecord(chunk.Data)
+						if err != nil {
+							t.Fatalf("Failed to serialize a batch for %s: %v", driver.name, err)
+						}
+						encoded = append(encoded, serialized...)
+					}
+					rows += chunk.Data.NumRows()
+					chunk.Data.Release()
+				}
+				return rows, encoded, nil
+			}
+			nextID := 100
+			insertRow := func() {
+				nextID++
+				execTestSQL(t, server, fmt.Sprintf("INSERT INTO test_table (id, name, value) VALUES (%d, 'added', 1.0)", nextID))
+			}
+
+			t.Run("single-use", func(t *testing.T) {
+				server.cfg.RepeatFetch = repeatFetchSingleUse
+				ticket := plan()
+				if _, _, err := fetch(ticket); err != nil {
+					t.Fatalf("First fetch failed for %s: %v", driver.name, err)
+				}
+				if _, _, err := fetch(ticket); status.Code(err) != codes.NotFound {
+					t.Errorf("Expected NotFound on the repeated fetch for %s, got %v", driver.name, err)
+				}
+			})
+
+			t.Run("reexecute", func(t *testing.T) {
+				server.cfg.RepeatFetch = repeatFetchReexecute
+				ticket := plan()
+				first, _, err := fetch(ticket)
+				if err != nil {
+					t.Fatalf("First fetch failed for %s: %v", driver.name, err)
+				}
+				insertRow()
+				second, _, err := fetch(ticket)
+				if err != nil {
+					t.Fatalf("Repeated fetch failed for %s: %v", driver.name, err)
+				}
+				if second != first+1 {
+					t.Errorf("Expected the repeated fetch to see the new row for %s, got %d rows after %d", driver.name, second, first)
+				}
+			})
+
+			t.Run("cache-replay", func(t *testing.T) {
+				server.cfg.RepeatFetch = repeatFetchCacheReplay
+				server.replays = newResultCache(time.Minute, 0)
+				ticket := plan()
+				first, firstBytes, err := fetch(ticket)
+				if err != nil {
+					t.Fatalf("First fetch failed for %s: %v", driver.name, err)
+				}
+				insertRow()
+				second, secondBytes, err := fetch(ticket)
+				if err != nil {
+					t.Fatalf("Repeated fetch failed for %s: %v", driver.name, err)
+				}
+				if second != first || !bytes.Equal(firstBytes, secondBytes) {
+					t.Errorf("Expected the repeated fetch to replay identical bytes for %s, got %d rows after %d", driver.name, second, first)
+				}
+			})
+		})
+	}
+}
+
+func TestCancelFlightInfo(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			ctx := context.Background()
+			desc := &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte("test-command")}
+			// run plans the query and starts streaming it
+			run := func() (*flight.FlightInfo, <-chan flight.StreamChunk) {
+				info, err := server.GetFlightInfoStatement(ctx, &mockStatementQuery{query: multiBatchQuery}, desc)
+				if err != nil {
+					t.Fatalf("GetFlightInfoStatement failed for %s: %v", driver.name, err)
+				}
+				ticket, err := flightsql.GetStatementQueryTicket(info.Endpoint[0].Ticket)
+				if err != nil {
+					t.Fatalf("Failed to parse statement ticket for %s: %v", driver.name, err)
+				}
+				_, streamCh, err := server.DoGetStatement(ctx, ticket)
+				if err != nil {
+					t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+				}
+				return info, streamCh
+			}
+			// drain reads the rest of the stream and returns its error
+			drain := func(streamCh <-chan flight.StreamChunk) error {
+				var streamErr error
+				for chunk := range streamCh {
+					if chunk.Err != nil {
+						streamErr = chunk.Err
+						continue
+					}
+					chunk.Data.Release()
+				}
+				return streamErr
+			}
+
+			// A running query, one batch in
+			info, streamCh := run()
+			first := <-streamCh
+			if first.Err != nil {
+				t.Fatalf("Stream error for %s: %v", driver.name, first.Err)
+			}
+			first.Data.Release()
+
+			result, err := server.CancelFlightInfo(ctx, &flight.CancelFlightInfoRequest{Info: info})
+			if err != nil {
+				t.Fatalf("CancelFlightInfo failed for %s: %v", driver.name, err)
+			}
+			if result.Status != flight.CancelStatusCancelled {
+				t.Errorf("Expected CANCELLED for a running query for %s, got %s", driver.name, result.Status)
+			}
+			if err := drain(streamCh); status.Code(err) != codes.Canceled {
+				t.Errorf("Expected the stream to end with Canceled for %s, got %v", driver.name, err)
+			}
+
+			// A query whose results were delivered
+			info, streamCh = run()
+			if err := drain(streamCh); err != nil {
+				t.Fatalf("Stream error for %s: %v", driver.name, err)
+			}
+			if _, err := server.CancelFlightInfo(ctx, &flight.CancelFlightInfoRequest{Info: info}); status.Code(err) != codes.NotFound {
+				t.Errorf("Expected NotFound for a finished query for %s, got %v", driver.name, err)
+			}
+		})
+	}
+}
+
+func TestDoGetStatement_StreamStats(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+			setupTestData(t, server)
+			server.cfg.StreamStats = true
+
+			ticket := prepareStatementTicket(t, server, "SELECT * FROM test_table")
+			_, streamCh, err := server.DoGetStatement(context.Background(), ticket)
+			if err != nil {
+				t.Fatalf("DoGetStatement failed for %s: %v", driver.name, err)
+			}
+
+			var (
+				rows    int64
+				batches int
+				stats   *streamStats
+			)
+			for chunk := range streamCh {
+				if chunk.Err != nil {
+					t.Fatalf("Stream error for %s: %v", driver.name, chunk.Err)
+				}
+				if stats != nil {
+					t.Errorf("Expected the stats to come last for %s", driver.name)
+				}
+				if len(chunk.AppMetadata) > 0 {
+					var md progressMetadata
+					if err := json.Unmarshal(chunk.AppMetadata, &md); err != nil {
+						t.Fatalf("Failed to decode app metadata for %s: %v", driver.name, err)
+					}
+					stats = md.Stats
+				} else {
+					batches++
+				}
+				rows += chunk.Data.NumRows()
+				chunk.Data.Release()
+			}
+
+			if stats == nil {
+				t.Fatalf("Expected trailing stream stats for %s", driver.name)
+			}
+			if stats.Rows != 3 || stats.Rows != rows {
+				t.Errorf("Expected stats for 3 rows for %s, got %d (streamed %d)", driver.name, stats.Rows, rows)
+			}
+			if stats.Batches != batches || stats.Bytes <= 0 {
+				t.Errorf("Expected %d batches and a positive size for %s, got %d batches of %d bytes", batches, driver.name, stats.Batches, stats.Bytes)
+			}
+			if stats.ElapsedMs <= 0 {
+				t.Errorf("Expected a positive elapsed time for %s, got %v", driver.name, stats.ElapsedMs)
+			}
+		})
+	}
+}
+
+func TestDoPutCommandStatementUpdate_NativeErrorCodes(t *testing.T) {
+	drivers := getTestDrivers(t)
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			server, cleanup := setupTestServer(t, driver)
+			defer cleanup()
+
+			setupTestData(t, server)
+			ctx := context.Background()
+
+			cmd := &pb.CommandStatementUpdate{Query: "INSERT INTO test_table (id, name) VALUES (1, 'duplicate')"}
+			_, err := server.DoPutCommandStatementUpdate(ctx, cmd)
+			if err == nil {
+				t.Fatalf("Expected a duplicate primary key to fail for %s", driver.name)
+			}
+
+			st := status.Convert(err)
+			if st.Code() == codes.Unknown {
+				t.Errorf("Expected the backend error to map to a gRPC code for %s, got %v", driver.name, err)
+			}
+			var info *errdetails.ErrorInfo
+			for _, detail := range st.Details() {
+				if i, ok := detail.(*errdetails.ErrorInfo); ok {
+					info = i
+				}
+			}
+			if info == nil {
+				t.Fatalf("Expected an ErrorInfo detail for %s, got %v", driver.name, st.Details())
+			}
+			if info.Domain != backendErrorDomain || info.Metadata["sql_state"] != sqlStateUniqueViolation {
+				t.Errorf("Expected the unique violation SQLSTATE for %s, got %+v", driver.name, info)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimiter is a token bucket allowing perSecond requests per second with
+// bursts of up to one second's worth
+type rateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	tokens    float64
+	last      time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{perSecond: perSecond, tokens: max(perSecond, 1), last: time.Now()}
+}
+
+// allow takes a token from the bucket, reporting false when it is empty
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.perSecond, max(l.perSecond, 1))
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// checkRateLimit fails with ResourceExhausted when the current rate limit
+// has been reached. The limiter is looked up per call so that ReloadConfig
+// takes effect for new requests immediately.
+func (s *DummyFlightSQLServer) checkRateLimit(method string) error {
+	limiter := s.runtimeSettings().limiter
+	if limiter != nil && !limiter.allow() {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", method)
+	}
+	return nil
+}
+
+func (s *DummyFlightSQLServer) rateLimitUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.checkRateLimit(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *DummyFlightSQLServer) rateLimitStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.checkRateLimit(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
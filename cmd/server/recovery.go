@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// panicError logs a recovered panic with its stack and returns the error
+// reported to the client
+func (s *DummyFlightSQLServer) panicError(method string, r any) error {
+	s.log().Error("panic", "method", method, "panic", r, "stack", string(debug.Stack()))
+	return status.Errorf(codes.Internal, "internal error in %s", method)
+}
+
+// recoveryUnaryInterceptor turns panics in unary handlers into Internal errors
+func (s *DummyFlightSQLServer) recoveryUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.panicError(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoveryStreamInterceptor turns panics in streaming handlers into Internal errors
+func (s *DummyFlightSQLServer) recoveryStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.panicError(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recoverStream is deferred in the goroutines feeding DoGet streams, which
+// run outside of the gRPC handler and so aren't covered by the interceptors.
+// A panic is sent to the client as an error chunk. It must be deferred after
+// close(ch) so that it runs first.
+func (s *DummyFlightSQLServer) recoverStream(ch chan<- flight.StreamChunk) {
+	if r := recover(); r != nil {
+		ch <- flight.StreamChunk{Err: s.panicError("DoGet stream", r)}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ColumnRedaction is how the values of a redacted column are replaced
+type ColumnRedaction struct {
+	// Mask replaces the non-null values of string columns. Without a mask,
+	// and for columns of other types, values are replaced with nulls.
+	Mask string
+}
+
+// queryWords matches the bare words of a query, including the ones inside
+// quoted identifiers
+var queryWords = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*`)
+
+// checkRedactColumns validates the "table.column" keys of RedactColumns
+func checkRedactColumns(columns map[string]ColumnRedaction) error {
+	for key := range columns {
+		table, column, ok := strings.Cut(key, ".")
+		if !ok || table == "" || column == "" || strings.Contains(column, ".") {
+			return fmt.Errorf("redacted column %q must be given as table.column", key)
+		}
+	}
+	return nil
+}
+
+// columnRedactor replaces the values of the redacted columns of a result
+type columnRedactor struct {
+	alloc   memory.Allocator
+	schema  *arrow.Schema // result schema, redacted columns made nullable
+	columns map[int]ColumnRedaction
+}
+
+// newColumnRedactor returns the redactor for the results of query, or nil
+// when no column needs redacting. A result column is redacted when its
+// name matches a configured column whose table the query mentions, which
+// errs on the side of redacting but misses aliased columns.
+func (s *DummyFlightSQLServer) newColumnRedactor(query string, schema *arrow.Schema) *columnRedactor {
+	if len(s.cfg.RedactColumns) == 0 {
+		return nil
+	}
+
+	mentioned := make(map[string]bool)
+	for _, word := range queryWords.FindAllString(query, -1) {
+		mentioned[strings.ToLower(word)] = true
+	}
+
+	redactions := make(map[string]ColumnRedaction)
+	for key, redaction := range s.cfg.RedactColumns {
+		table, column, _ := strings.Cut(key, ".")
+		if mentioned[strings.ToLower(table)] {
+			redactions[strings.ToLower(column)] = redaction
+		}
+	}
+	if len(redactions) == 0 {
+		return nil
+	}
+
+	columns := make(map[int]ColumnRedaction)
+	fields := schema.Fields()
+	for i, field := range fields {
+		if redaction, ok := redactions[strings.ToLower(field.Name)]; ok {
+			columns[i] = redaction
+			fields[i].Nullable = true
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	metadata := schema.Metadata()
+	return &columnRedactor{alloc: s.Alloc, schema: arrow.NewSchema(fields, &metadata), columns: columns}
+}
+
+// apply returns rec with the redacted columns replaced, taking over the
+// caller's reference to rec
+func (r *columnRedactor) apply(rec arrow.RecordBatch) arrow.RecordBatch {
+	defer rec.Release()
+
+	cols := make([]arrow.Array, rec.NumCols())
+	for i, col := range rec.Columns() {
+		if redaction, ok := r.columns[i]; ok {
+			cols[i] = redactColumn(r.alloc, col, redaction)
+			defer cols[i].Release()
+		} else {
+			cols[i] = col
+		}
+	}
+	return array.NewRecordBatch(r.schema, cols, rec.NumRows())
+}
+
+// redactColumn returns col with every value masked or nulled
+func redactColumn(alloc memory.Allocator, col arrow.Array, redaction ColumnRedaction) arrow.Array {
+	if redaction.Mask == "" {
+		return array.MakeArrayOfNull(alloc, col.DataType(), col.Len())
+	}
+
+	bldr := array.NewBuilder(alloc, col.DataType())
+	defer bldr.Release()
+
+	strs, ok := bldr.(interface{ Append(string) })
+	if !ok {
+		return array.MakeArrayOfNull(alloc, col.DataType(), col.Len())
+	}
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			bldr.AppendNull()
+		} else {
+			strs.Append(redaction.Mask)
+		}
+	}
+	return bldr.NewArray()
+}
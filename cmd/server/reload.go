@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fileConfig is the JSON configuration file read at startup and by the
+// ReloadConfig action. Settings missing from the file keep their
+// ServerConfig value.
+type fileConfig struct {
+	LogLevel    string   `json:"log_level"`
+	AdminTokens []string `json:"admin_tokens"`
+	RateLimit   *float64 `json:"rate_limit"`
+}
+
+// reloadableSettings are the hot-reloadable settings in effect. They are
+// replaced as a whole so that requests never see a partially applied reload.
+type reloadableSettings struct {
+	adminTokens []string
+	limiter     *rateLimiter // nil when requests are not rate limited
+}
+
+// runtimeSettings returns the settings in effect. Servers that never
+// applied a configuration use the tokens from ServerConfig without a rate
+// limit.
+func (s *DummyFlightSQLServer) runtimeSettings() *reloadableSettings {
+	if current := s.settings.Load(); current != nil {
+		return current
+	}
+	if s.cfg.AdminToken == "" {
+		return &reloadableSettings{}
+	}
+	return &reloadableSettings{adminTokens: []string{s.cfg.AdminToken}}
+}
+
+// newLogger returns a text logger writing to w that filters by level
+func newLogger(w io.Writer, level *slog.LevelVar) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// log returns the server logger, whose level follows ReloadConfig
+func (s *DummyFlightSQLServer) log() *slog.Logger {
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
+
+// parseLogLevel accepts debug, info, warn and error
+func parseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// readConfigFile parses the JSON configuration file at path
+func readConfigFile(path string) (fileConfig, error) {
+	var fc fileConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fc, err
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fc, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// applyConfig validates the ServerConfig settings overridden by fc and
+// swaps them in. Nothing changes when validation fails.
+func (s *DummyFlightSQLServer) applyConfig(fc fileConfig) error {
+	levelName := s.cfg.LogLevel
+	if fc.LogLevel != "" {
+		levelName = fc.LogLevel
+	}
+	level := slog.LevelInfo
+	if levelName != "" {
+		var err error
+		if level, err = parseLogLevel(levelName); err != nil {
+			return err
+		}
+	}
+
+	next := &reloadableSettings{adminTokens: fc.AdminTokens}
+	if next.adminTokens == nil && s.cfg.AdminToken != "" {
+		next.adminTokens = []string{s.cfg.AdminToken}
+	}
+
+	rateLimit := s.cfg.RateLimit
+	if fc.RateLimit != nil {
+		rateLimit = *fc.RateLimit
+	}
+	if rateLimit < 0 {
+		return fmt.Errorf("rate limit must not be negative, got %v", rateLimit)
+	}
+	if rateLimit > 0 {
+		next.limiter = newRateLimiter(rateLimit)
+	}
+
+	s.logLevel.Set(level)
+	s.settings.Store(next)
+	return nil
+}
+
+// loadConfig applies the ServerConfig settings and, when ConfigFile is set,
+// the overrides read from it
+func (s *DummyFlightSQLServer) loadConfig() error {
+	var fc fileConfig
+	if s.cfg.ConfigFile != "" {
+		var err error
+		if fc, err = readConfigFile(s.cfg.ConfigFile); err != nil {
+			return err
+		}
+	}
+	return s.applyConfig(fc)
+}
+
+// reloadConfig re-reads ConfigFile and applies its hot-reloadable settings.
+// Everything else in ServerConfig, like the listen addresses, is unchanged.
+func (s *DummyFlightSQLServer) reloadConfig(ctx context.Context, body []byte) ([][]byte, error) {
+	if s.cfg.ConfigFile == "" {
+		return nil, status.Error(codes.FailedPrecondition, "no configuration file to reload")
+	}
+
+	if err := s.loadConfig(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to reload configuration: %v", err)
+	}
+
+	s.log().Info("configuration reloaded", "file", s.cfg.ConfigFile, "log_level", s.logLevel.Level())
+	return [][]byte{[]byte("configuration reloaded")}, nil
+}
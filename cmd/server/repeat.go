@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/util"
+)
+
+// Values of ServerConfig.RepeatFetch
+const (
+	repeatFetchSingleUse   = "single-use"
+	repeatFetchReexecute   = "reexecute"
+	repeatFetchCacheReplay = "cache-replay"
+)
+
+// defaultReplayTTL is how long cache-replay keeps a result when
+// ReplayTTL is not set
+const defaultReplayTTL = 10 * time.Minute
+
+// checkRepeatFetch validates RepeatFetch
+func checkRepeatFetch(mode string) error {
+	switch mode {
+	case "", repeatFetchSingleUse, repeatFetchReexecute, repeatFetchCacheReplay:
+		return nil
+	}
+	return fmt.Errorf("unsupported repeat fetch mode %q, supported values are: %s, %s, %s",
+		mode, repeatFetchSingleUse, repeatFetchReexecute, repeatFetchCacheReplay)
+}
+
+// recordReplay passes the chunks of a statement stream on and keeps its data
+// batches under handle once the stream completed, so repeated fetches of
+// the handle replay them. stored is called when they were kept.
+// Results larger than ReplayMaxBytes are not kept.
+func (s *DummyFlightSQLServer) recordReplay(handle string, schema *arrow.Schema, ch <-chan flight.StreamChunk, stored func()) <-chan flight.StreamChunk {
+	out := make(chan flight.StreamChunk)
+	go func() {
+		defer close(out)
+
+		var (
+			batches []arrow.RecordBatch
+			size    int64
+			failed  bool
+		)
+		for chunk := range ch {
+			if chunk.Err != nil {
+				failed = true
+			} else if chunk.Data != nil && chunk.Data.NumRows() > 0 {
+				// Heartbeats and progress reports are empty and not replayed
+				chunk.Data.Retain()
+				batches = append(batches, chunk.Data)
+				size += util.TotalRecordSize(chunk.Data)
+			}
+			out <- chunk
+		}
+		// Results too large to keep are run again on the next fetch
+		if failed || (s.replays.maxBytes > 0 && size > s.replays.maxBytes) {
+			releaseBatches(batches)
+			return
+		}
+		s.replays.put(handle, schema, batches, size)
+		stored()
+	}()
+	return out
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+)
+
+// defaultRetryStatuses are retried when RetryStatuses is empty
+var defaultRetryStatuses = []adbc.Status{adbc.StatusIO, adbc.StatusTimeout}
+
+// isTransient reports whether err is an ADBC error with a retried status
+func (s *DummyFlightSQLServer) isTransient(err error) bool {
+	var adbcErr adbc.Error
+	if !errors.As(err, &adbcErr) {
+		return false
+	}
+	statuses := s.cfg.RetryStatuses
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	return slices.Contains(statuses, adbcErr.Code)
+}
+
+// retryTransient runs execute, and runs it again up to ReadRetries times
+// while it fails with a transient error. The wait starts at RetryBackoff and
+// doubles after every attempt. execute must not have sent any data to the
+// client when it fails, since the results would be sent twice.
+func (s *DummyFlightSQLServer) retryTransient(ctx context.Context, method string, execute func() error) error {
+	backoff := s.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := execute()
+		if err == nil || attempt > s.cfg.ReadRetries || !s.isTransient(err) {
+			return err
+		}
+
+		s.logFor(ctx).Warn("retrying after transient backend error", "method", method, "attempt", attempt, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "context"
+
+// QueryRewriter transforms client queries before they reach the backend,
+// e.g. to inject mandatory row-level filters. The context carries the
+// incoming request metadata of the calling client.
+type QueryRewriter interface {
+	RewriteQuery(ctx context.Context, query string) (string, error)
+}
+
+// QueryRewriterFunc adapts an ordinary function to a QueryRewriter
+type QueryRewriterFunc func(ctx context.Context, query string) (string, error)
+
+func (f QueryRewriterFunc) RewriteQuery(ctx context.Context, query string) (string, error) {
+	return f(ctx, query)
+}
+
+// noopRewriter returns queries unchanged
+type noopRewriter struct{}
+
+func (noopRewriter) RewriteQuery(_ context.Context, query string) (string, error) {
+	return query, nil
+}
+
+// rewriteQuery applies the configured rewriter to query
+func (s *DummyFlightSQLServer) rewriteQuery(ctx context.Context, query string) (string, error) {
+	rewriter := s.cfg.QueryRewriter
+	if rewriter == nil {
+		rewriter = noopRewriter{}
+	}
+	return rewriter.RewriteQuery(ctx, query)
+}
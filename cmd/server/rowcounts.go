@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rowCountsSchema is the result schema of the GetTableRowCounts action.
+// approximate is set when the count comes from engine statistics.
+var rowCountsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "catalog_name", Type: arrow.BinaryTypes.String},
+	{Name: "db_schema_name", Type: arrow.BinaryTypes.String},
+	{Name: "table_name", Type: arrow.BinaryTypes.String},
+	{Name: "row_count", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "approximate", Type: arrow.FixedWidthTypes.Boolean},
+}, nil)
+
+// tableRowCount is one row of the GetTableRowCounts result
+type tableRowCount struct {
+	catalog, schema, table string
+	rows                   int64
+	approximate            bool
+}
+
+// tableRowCounts returns the row count of every table visible to the
+// client, or only of the table named in the body. DuckDB reports the
+// estimate from its catalog statistics, other backends run COUNT(*) per
+// table, which is why the action has to be enabled with TableRowCounts.
+func (s *DummyFlightSQLServer) tableRowCounts(ctx context.Context, body []byte) ([][]byte, error) {
+	if !s.cfg.TableRowCounts {
+		return nil, status.Error(codes.FailedPrecondition, "table row counts are disabled")
+	}
+	only := strings.TrimSpace(string(body))
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []tableRowCount
+	if vendor == vendorDuckDB {
+		counts, err = duckDBRowEstimates(ctx, conn)
+	} else {
+		counts, err = s.countTableRows(ctx, conn, only)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filter := s.metadataFilter()
+	bldr := array.NewRecordBuilder(s.Alloc, rowCountsSchema)
+	defer bldr.Release()
+
+	for _, count := range counts {
+		if only != "" && count.table != only {
+			continue
+		}
+		// The filter sees the schema name clients see
+		schema := s.reportedSchemaName(count.schema)
+		if !filter.AllowCatalog(ctx, count.catalog) || !filter.AllowSchema(ctx, count.catalog, schema) ||
+			!filter.AllowTable(ctx, count.catalog, schema, count.table) {
+			continue
+		}
+		bldr.Field(0).(*array.StringBuilder).Append(count.catalog)
+		bldr.Field(1).(*array.StringBuilder).Append(schema)
+		bldr.Field(2).(*array.StringBuilder).Append(count.table)
+		bldr.Field(3).(*array.Int64Builder).Append(count.rows)
+		bldr.Field(4).(*array.BooleanBuilder).Append(count.approximate)
+	}
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
+
+// duckDBRowEstimates reads the estimated table sizes from DuckDB's catalog
+func duckDBRowEstimates(ctx context.Context, conn adbc.Connection) ([]tableRowCount, error) {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery("SELECT database_name, schema_name, table_name, estimated_size FROM duckdb_tables() WHERE NOT internal ORDER BY database_name, schema_name, table_name"); err != nil {
+		return nil, err
+	}
+
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	var counts []tableRowCount
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		names, err := asStringColumns(rec.Column(0), rec.Column(1), rec.Column(2))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(rec.NumRows()); i++ {
+			rows, err := int64Value(rec.Column(3), i)
+			if err != nil {
+				return nil, err
+			}
+			counts = append(counts, tableRowCount{
+				catalog:     names[0].Value(i),
+				schema:      names[1].Value(i),
+				table:       names[2].Value(i),
+				rows:        rows,
+				approximate: true,
+			})
+		}
+	}
+	return counts, reader.Err()
+}
+
+// countTableRows runs an exact COUNT(*) for every base table listed by
+// GetObjects, or only for the table named only
+func (s *DummyFlightSQLServer) countTableRows(ctx context.Context, conn adbc.Connection, only string) ([]tableRowCount, error) {
+	tables, err := tableDefinitions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var counts []tableRowCount
+	for _, table := range tables {
+		if normalizeTableType(table.tableType) != "TABLE" || (only != "" && table.name != only) {
+			continue
+		}
+
+		name := s.qualifiedName(table.catalog, table.schema, table.name)
+		if err := stmt.SetSqlQuery("SELECT COUNT(*) FROM " + name); err != nil {
+			return nil, err
+		}
+
+		rows, err := queryCount(ctx, stmt)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, tableRowCount{catalog: table.catalog, schema: table.schema, table: table.name, rows: rows})
+	}
+	return counts, nil
+}
+
+// queryCount executes stmt and returns the integer in its first cell
+func queryCount(ctx context.Context, stmt adbc.Statement) (int64, error) {
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Release()
+
+	var count int64
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		if rec.NumRows() > 0 && rec.NumCols() > 0 {
+			if count, err = int64Value(rec.Column(0), 0); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return count, reader.Err()
+}
+
+// int64Value reads an integer cell regardless of the backend's integer type
+func int64Value(col arrow.Array, i int) (int64, error) {
+	if col.IsNull(i) {
+		return 0, nil
+	}
+	if ints, ok := col.(*array.Int64); ok {
+		return ints.Value(i), nil
+	}
+	return strconv.ParseInt(col.ValueStr(i), 10, 64)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// savepointName is the backend name of the savepoint with the given id.
+// Client supplied names may repeat, ids don't.
+func savepointName(id string) string {
+	return quoteIdentifier("sp_" + id)
+}
+
+// execOnConnection runs a statement without a result on conn
+func execOnConnection(ctx context.Context, conn adbc.Connection, query string) error {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return err
+	}
+	_, err = stmt.ExecuteUpdate(ctx)
+	return err
+}
+
+// checkSavepointSupport rejects backends without SAVEPOINT. DuckDB has
+// transactions but no savepoints.
+func checkSavepointSupport(ctx context.Context, conn adbc.Connection) error {
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if vendor == vendorDuckDB {
+		return status.Error(codes.Unimplemented, "savepoints are not supported by the DuckDB backend")
+	}
+	return nil
+}
+
+// BeginSavepoint issues SAVEPOINT on the transaction's connection
+func (s *DummyFlightSQLServer) BeginSavepoint(ctx context.Context, req flightsql.ActionBeginSavepointRequest) ([]byte, error) {
+	txn, err := s.lookupTransaction(req.GetTransactionId())
+	if err != nil {
+		return nil, err
+	}
+	ctx, done, err := txn.startRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if err := checkSavepointSupport(ctx, txn.conn); err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+
+	if err := execOnConnection(ctx, txn.conn, "SAVEPOINT "+savepointName(id)); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint %q: %w", req.GetName(), err)
+	}
+
+	txn.mu.Lock()
+	txn.savepoints = append(txn.savepoints, id)
+	txn.mu.Unlock()
+
+	s.mu.Lock()
+	s.savepoints[id] = txn
+	s.mu.Unlock()
+
+	return []byte(id), nil
+}
+
+// EndSavepoint releases the savepoint or rolls back to it. Either way the
+// savepoint and the ones created after it can't be used anymore.
+func (s *DummyFlightSQLServer) EndSavepoint(ctx context.Context, req flightsql.ActionEndSavepointRequest) error {
+	id := string(req.GetSavepointId())
+
+	s.mu.Lock()
+	txn, ok := s.savepoints[id]
+	s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "unknown savepoint: %s", id)
+	}
+
+	var query string
+	switch req.GetAction() {
+	case flightsql.EndSavepointRelease:
+		query = "RELEASE SAVEPOINT " + savepointName(id)
+	case flightsql.EndSavepointRollback:
+		query = "ROLLBACK TO SAVEPOINT " + savepointName(id)
+	default:
+		return status.Errorf(codes.InvalidArgument, "unsupported end savepoint action: %s", req.GetAction())
+	}
+
+	ctx, done, err := txn.startRead(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if err := execOnConnection(ctx, txn.conn, query); err != nil {
+		return err
+	}
+
+	txn.mu.Lock()
+	var ended []string
+	if i := slices.Index(txn.savepoints, id); i >= 0 {
+		ended = txn.savepoints[i:]
+		txn.savepoints = txn.savepoints[:i]
+	}
+	txn.mu.Unlock()
+
+	s.forgetSavepoints(ended)
+	return nil
+}
+
+// forgetSavepoints drops the ids of savepoints that ended
+func (s *DummyFlightSQLServer) forgetSavepoints(ids []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.savepoints, id)
+	}
+}
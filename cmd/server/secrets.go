@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// secretReference matches the ${NAME} references resolved in option values
+var secretReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// readSecretsFile parses the JSON object of secret values at path
+func readSecretsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return secrets, nil
+}
+
+// resolveDatabaseOptions returns DatabaseOptions with every ${NAME} in the
+// values replaced by NAME from SecretsFile or else the environment, so that
+// credentials don't have to be written into the configuration. A reference
+// that resolves nowhere is an error.
+func (c ServerConfig) resolveDatabaseOptions() (map[string]string, error) {
+	var secrets map[string]string
+	if c.SecretsFile != "" {
+		var err error
+		if secrets, err = readSecretsFile(c.SecretsFile); err != nil {
+			return nil, fmt.Errorf("failed to read secrets file: %w", err)
+		}
+	}
+
+	resolved := make(map[string]string, len(c.DatabaseOptions))
+	for key, value := range c.DatabaseOptions {
+		var missing string
+		resolved[key] = secretReference.ReplaceAllStringFunc(value, func(ref string) string {
+			name := secretReference.FindStringSubmatch(ref)[1]
+			if secret, ok := secrets[name]; ok {
+				return secret
+			}
+			if env, ok := os.LookupEnv(name); ok {
+				return env
+			}
+			if missing == "" {
+				missing = name
+			}
+			return ref
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("database option %q references ${%s}, which is neither set in the environment nor in the secrets file", key, missing)
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/session"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Serve listens on listen and serves Flight SQL, and the JSON endpoint when
+// HTTPAddr is set, until Shutdown is called
+func (s *DummyFlightSQLServer) Serve(listen string) error {
+	srv, err := s.listen(listen)
+	s.readyOnce.Do(func() { close(s.ready) })
+	if err != nil {
+		return err
+	}
+
+	s.log().Info("Flight SQL server listening", "addr", srv.Addr())
+	return srv.Serve()
+}
+
+// listen opens the Flight SQL listener and starts the JSON endpoint
+func (s *DummyFlightSQLServer) listen(listen string) (flight.Server, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flightServer != nil {
+		return nil, fmt.Errorf("server is already serving on %s", s.flightServer.Addr())
+	}
+
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+
+	if addr := s.cfg.HTTPAddr; addr != "" {
+		httpLis, err := net.Listen("tcp", addr)
+		if err != nil {
+			lis.Close()
+			return nil, fmt.Errorf("failed to start JSON query endpoint: %w", err)
+		}
+		// The endpoint shares the Flight SQL certificate, and with a
+		// client CA also requires client certificates
+		if s.tlsConfig != nil {
+			httpLis = tls.NewListener(httpLis, s.tlsConfig)
+		}
+		s.httpServer = &http.Server{Handler: s.httpHandler()}
+		go func(srv *http.Server) {
+			if err := srv.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.log().Error("JSON query endpoint failed", "error", err)
+			}
+		}(s.httpServer)
+		s.log().Info("JSON query endpoint listening", "addr", httpLis.Addr())
+	}
+
+	srv := s.newFlightServer()
+	srv.InitListener(lis)
+
+	s.flightServer = srv
+	return srv, nil
+}
+
+// newFlightServer returns a gRPC server with the Flight SQL, health and,
+// when EnableReflection is set, reflection services registered. With
+// EnableSessions every call is tied to a cookie based session.
+func (s *DummyFlightSQLServer) newFlightServer() flight.Server {
+	var middleware []flight.ServerMiddleware
+	if s.cfg.EnableSessions {
+		middleware = append(middleware, flight.CreateServerMiddleware(session.NewServerSessionMiddleware(nil)))
+	}
+	srv := flight.NewServerWithMiddleware(middleware, s.grpcServerOptions()...)
+	srv.RegisterFlightService(newFlightService(s))
+	healthpb.RegisterHealthServer(srv, newHealthService(s))
+	if s.cfg.EnableReflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
+
+// Addr waits for Serve to start listening and returns the Flight SQL
+// address. It returns nil when Serve failed to listen.
+func (s *DummyFlightSQLServer) Addr() net.Addr {
+	<-s.ready
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flightServer == nil {
+		return nil
+	}
+	return s.flightServer.Addr()
+}
+
+// Shutdown stops the Flight SQL and JSON endpoints, waiting for in-flight
+// requests to finish. The backend database stays open until Close.
+func (s *DummyFlightSQLServer) Shutdown() {
+	s.mu.Lock()
+	flightServer, httpServer := s.flightServer, s.httpServer
+	s.mu.Unlock()
+
+	if httpServer != nil {
+		httpServer.Shutdown(context.Background())
+	}
+	if flightServer != nil {
+		flightServer.Shutdown()
+	}
+}
+
+// Close rolls back open transactions, drops prepared statements and
+// releases open cursors, the pooled connections and the backend database
+func (s *DummyFlightSQLServer) Close() error {
+	transactions := s.closeTransactions()
+	prepared := s.closePrepared()
+	if transactions > 0 || prepared > 0 {
+		s.log().Info("cleaned up on close", "transactions", transactions, "prepared_statements", prepared)
+	}
+	s.closeCursors()
+	if s.pool != nil {
+		s.pool.close()
+	}
+	if sink, ok := s.cfg.AuditSink.(*fileAuditSink); ok {
+		sink.Close()
+	}
+	return (*s.db).Close()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/session"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Session options accepted by SetSessionOptions. They override
+// DefaultCatalog and DefaultSchema for the connections of the session.
+const (
+	sessionOptionCatalog = "catalog"
+	sessionOptionSchema  = "schema"
+)
+
+// namespaceSchema is the result schema of the GetCurrentNamespace action
+var namespaceSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "catalog_name", Type: arrow.BinaryTypes.String},
+	{Name: "db_schema_name", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// sessionFromContext returns the caller's session. It fails with
+// FailedPrecondition when sessions are disabled.
+func sessionFromContext(ctx context.Context) (session.ServerSession, error) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "sessions are disabled")
+	}
+	return sess, nil
+}
+
+// sessionNamespace returns the catalog and schema set on the caller's
+// session, empty when unset or when sessions are disabled
+func sessionNamespace(ctx context.Context) (catalog, schema string) {
+	sess, err := session.GetSessionFromContext(ctx)
+	if err != nil {
+		return "", ""
+	}
+	return sess.GetSessionOption(sessionOptionCatalog).GetStringValue(),
+		sess.GetSessionOption(sessionOptionSchema).GetStringValue()
+}
+
+// hasSessionNamespace reports whether the caller's session overrides the
+// default catalog or schema
+func hasSessionNamespace(ctx context.Context) bool {
+	catalog, schema := sessionNamespace(ctx)
+	return catalog != "" || schema != ""
+}
+
+// hasSessionState reports whether the caller's session changes how its
+// connections behave, with a namespace or backend settings
+func hasSessionState(ctx context.Context) bool {
+	return hasSessionNamespace(ctx) || len(sessionBackendOptions(ctx)) > 0
+}
+
+// connectionNamespace returns the catalog and schema connections opened
+// for ctx start in: the session's, falling back to the configured defaults
+func (s *DummyFlightSQLServer) connectionNamespace(ctx context.Context) (catalog, schema string) {
+	catalog, schema = sessionNamespace(ctx)
+	if catalog == "" {
+		catalog = s.cfg.DefaultCatalog
+	}
+	if schema == "" {
+		schema = s.cfg.DefaultSchema
+	}
+	return catalog, schema
+}
+
+// SetSessionOptions stores the catalog and schema options on the caller's
+// session. An option without a value erases it. Other names and non-string
+// values are reported back as errors and leave the session unchanged.
+func (s *DummyFlightSQLServer) SetSessionOptions(ctx context.Context, req *flight.SetSessionOptionsRequest) (*flight.SetSessionOptionsResult, error) {
+	sess, err := sessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &flight.SetSessionOptionsResult{Errors: make(map[string]*flight.SetSessionOptionsResultError)}
+	for name, value := range req.GetSessionOptions() {
+		if name != sessionOptionCatalog && name != sessionOptionSchema {
+			result.Errors[name] = &flight.SetSessionOptionsResultError{Value: flight.SetSessionOptionsResultErrorInvalidName}
+			continue
+		}
+		switch value.GetOptionValue().(type) {
+		case nil:
+			sess.EraseSessionOption(name)
+		case *pb.SessionOptionValue_StringValue:
+			sess.SetSessionOption(name, value)
+		default:
+			result.Errors[name] = &flight.SetSessionOptionsResultError{Value: flight.SetSessionOptionsResultErrorInvalidValue}
+		}
+	}
+	return result, nil
+}
+
+func (s *DummyFlightSQLServer) GetSessionOptions(ctx context.Context, req *flight.GetSessionOptionsRequest) (*flight.GetSessionOptionsResult, error) {
+	sess, err := sessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &flight.GetSessionOptionsResult{SessionOptions: sess.GetSessionOptions()}, nil
+}
+
+func (s *DummyFlightSQLServer) CloseSession(ctx context.Context, req *flight.CloseSessionRequest) (*flight.CloseSessionResult, error) {
+	sess, err := sessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.Close(); err != nil {
+		return nil, err
+	}
+	return &flight.CloseSessionResult{Status: flight.CloseSessionResultClosed}, nil
+}
+
+// currentNamespace returns the catalog and schema unqualified names resolve
+// against on a connection opened for the caller: the attached database
+// (main) for SQLite, current_catalog() and current_schema() for DuckDB
+func (s *DummyFlightSQLServer) currentNamespace(ctx context.Context, body []byte) ([][]byte, error) {
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	catalog, err := currentCatalog(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := currentSchema(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, namespaceSchema)
+	defer bldr.Release()
+	bldr.Field(0).(*array.StringBuilder).Append(catalog)
+	bldr.Field(1).(*array.StringBuilder).Append(s.reportedSchemaName(schema))
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	data, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{data}, nil
+}
+
+// currentSchema returns the schema unqualified names resolve against. SQLite
+// has no schemas and reports "".
+func currentSchema(ctx context.Context, conn adbc.Connection) (string, error) {
+	if opts, ok := unwrapConnection(conn).(adbc.GetSetOptions); ok {
+		if name, err := opts.GetOption(adbc.OptionKeyCurrentDbSchema); err == nil && name != "" {
+			return name, nil
+		}
+	}
+
+	vendor, err := backendVendor(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	if vendor == vendorSQLite {
+		return "", nil
+	}
+	values, err := queryStrings(ctx, conn, "SELECT current_schema()")
+	if err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("backend did not report a current schema")
+	}
+	return values[0], nil
+}
@@ -18,7 +18,7 @@ type testDriver struct {
 	cleanup    func()
 }
 
-func getTestDrivers(t *testing.T) []testDriver {
+func getTestDrivers(t testing.TB) []testDriver {
 	tmpDir := t.TempDir()
 
 	drivers := []testDriver{
@@ -39,31 +39,40 @@ func getTestDrivers(t *testing.T) []testDriver {
 	return drivers
 }
 
-func setupTestServer(t *testing.T, driver testDriver) (*DummyFlightSQLServer, func()) {
-	drv := &drivermgr.Driver{}
-
-	var dbOptions map[string]string
+// testDatabaseOptions returns the driver manager options for a test driver
+func testDatabaseOptions(driver testDriver) map[string]string {
 	if driver.driverName == "duckdb" {
-		dbOptions = map[string]string{
+		return map[string]string{
 			"driver":     driver.driverName,
 			"entrypoint": "duckdb_adbc_init",
 			"path":       driver.uri,
 		}
-	} else {
-		dbOptions = map[string]string{
-			"driver":          driver.driverName,
-			adbc.OptionKeyURI: driver.uri,
-		}
 	}
 
-	db, err := drv.NewDatabase(dbOptions)
+	return map[string]string{
+		"driver":          driver.driverName,
+		adbc.OptionKeyURI: driver.uri,
+	}
+}
+
+func setupTestServer(t testing.TB, driver testDriver) (*DummyFlightSQLServer, func()) {
+	drv := &drivermgr.Driver{}
+
+	db, err := drv.NewDatabase(testDatabaseOptions(driver))
 	if err != nil {
 		t.Fatalf("Failed to create test database for %s: %v", driver.name, err)
 	}
 
 	server := &DummyFlightSQLServer{
-		db:      &db,
-		queries: make(map[string]string),
+		db:           &db,
+		queries:      make(map[string]statementHandle),
+		prepared:     make(map[string]*preparedStatement),
+		transactions: make(map[string]*transaction),
+		savepoints:   make(map[string]*transaction),
+		ingestGroups: make(map[string]*ingestGroup),
+		cursors:      make(map[string]*cursor),
+		running:      make(map[string]map[*runningStatement]struct{}),
+		ready:        make(chan struct{}),
 	}
 	server.Alloc = memory.DefaultAllocator
 
@@ -78,7 +87,7 @@ func setupTestServer(t *testing.T, driver testDriver) (*DummyFlightSQLServer, fu
 	return server, cleanup
 }
 
-func setupTestData(t *testing.T, server *DummyFlightSQLServer) {
+func setupTestData(t testing.TB, server *DummyFlightSQLServer) {
 	ctx := context.Background()
 
 	if server.db == nil {
@@ -136,6 +145,37 @@ func setupTestData(t *testing.T, server *DummyFlightSQLServer) {
 	}
 }
 
+// execTestSQL runs each statement against the server's backend
+func execTestSQL(tb testing.TB, server *DummyFlightSQLServer, statements ...string) {
+	ctx := context.Background()
+
+	conn, err := (*server.db).Open(ctx)
+	if err != nil {
+		tb.Fatalf("Failed to open database connection: %v", err)
+	}
+	defer conn.Close()
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		tb.Fatalf("Failed to create statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, sql := range statements {
+		if err := stmt.SetSqlQuery(sql); err != nil {
+			tb.Fatalf("Failed to set query %q: %v", sql, err)
+		}
+		if _, err := stmt.ExecuteUpdate(ctx); err != nil {
+			tb.Fatalf("Failed to execute %q: %v", sql, err)
+		}
+	}
+}
+
+// setupNullTestData adds a row with NULL name and value to test_table
+func setupNullTestData(t testing.TB, server *DummyFlightSQLServer) {
+	execTestSQL(t, server, `INSERT INTO test_table (id, name, value) VALUES (4, NULL, NULL)`)
+}
+
 func setupTestSchemas(t *testing.T, server *DummyFlightSQLServer, driver testDriver) {
 	ctx := context.Background()
 
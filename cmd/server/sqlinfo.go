@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+)
+
+// sqliteKeywords are SQLite's reserved words, also used for backends that
+// can't report their own
+var sqliteKeywords = []string{
+	"ABORT", "ACTION", "ADD", "AFTER", "ALL", "ALTER", "ALWAYS", "ANALYZE", "AND", "AS", "ASC",
+	"ATTACH", "AUTOINCREMENT", "BEFORE", "BEGIN", "BETWEEN", "BY", "CASCADE", "CASE", "CAST",
+	"CHECK", "COLLATE", "COLUMN", "COMMIT", "CONFLICT", "CONSTRAINT", "CREATE", "CROSS",
+	"CURRENT", "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP", "DATABASE", "DEFAULT",
+	"DEFERRABLE", "DEFERRED", "DELETE", "DESC", "DETACH", "DISTINCT", "DO", "DROP", "EACH",
+	"ELSE", "END", "ESCAPE", "EXCEPT", "EXCLUDE", "EXCLUSIVE", "EXISTS", "EXPLAIN", "FAIL",
+	"FILTER", "FIRST", "FOLLOWING", "FOR", "FOREIGN", "FROM", "FULL", "GENERATED", "GLOB",
+	"GROUP", "GROUPS", "HAVING", "IF", "IGNORE", "IMMEDIATE", "IN", "INDEX", "INDEXED",
+	"INITIALLY", "INNER", "INSERT", "INSTEAD", "INTERSECT", "INTO", "IS", "ISNULL", "JOIN",
+	"KEY", "LAST", "LEFT", "LIKE", "LIMIT", "MATCH", "MATERIALIZED", "NATURAL", "NO", "NOT",
+	"NOTHING", "NOTNULL", "NULL", "NULLS", "OF", "OFFSET", "ON", "OR", "ORDER", "OTHERS",
+	"OUTER", "OVER", "PARTITION", "PLAN", "PRAGMA", "PRECEDING", "PRIMARY", "QUERY", "RAISE",
+	"RANGE", "RECURSIVE", "REFERENCES", "REGEXP", "REINDEX", "RELEASE", "RENAME", "REPLACE",
+	"RESTRICT", "RETURNING", "RIGHT", "ROLLBACK", "ROW", "ROWS", "SAVEPOINT", "SELECT", "SET",
+	"TABLE", "TEMP", "TEMPORARY", "THEN", "TIES", "TO", "TRANSACTION", "TRIGGER", "UNBOUNDED",
+	"UNION", "UNIQUE", "UPDATE", "USING", "VACUUM", "VALUES", "VIEW", "VIRTUAL", "WHEN",
+	"WHERE", "WINDOW", "WITH", "WITHOUT",
+}
+
+// functionLists are the built-in functions reported per backend vendor. The
+// "" entry holds the SQL standard functions used for unknown backends.
+var functionLists = map[string]map[flightsql.SqlInfo][]string{
+	vendorSQLite: {
+		flightsql.SqlInfoNumericFunctions: {"ABS", "ACOS", "ASIN", "ATAN", "ATAN2", "CEIL", "CEILING", "COS", "DEGREES",
+			"EXP", "FLOOR", "LN", "LOG", "LOG10", "LOG2", "MAX", "MIN", "MOD", "PI", "POW", "POWER", "RADIANS",
+			"RANDOM", "ROUND", "SIGN", "SIN", "SQRT", "TAN", "TRUNC"},
+		flightsql.SqlInfoStringFunctions: {"CHAR", "CONCAT", "CONCAT_WS", "FORMAT", "GLOB", "HEX", "INSTR", "LENGTH",
+			"LIKE", "LOWER", "LTRIM", "OCTET_LENGTH", "PRINTF", "QUOTE", "REPLACE", "RTRIM", "SUBSTR", "SUBSTRING",
+			"TRIM", "UNICODE", "UPPER"},
+		flightsql.SqlInfoDateTimeFunctions: {"DATE", "DATETIME", "JULIANDAY", "STRFTIME", "TIME", "TIMEDIFF", "UNIXEPOCH"},
+		flightsql.SqlInfoSystemFunctions: {"CHANGES", "COALESCE", "IFNULL", "IIF", "LAST_INSERT_ROWID", "NULLIF",
+			"SQLITE_VERSION", "TOTAL_CHANGES", "TYPEOF"},
+	},
+	vendorDuckDB: {
+		flightsql.SqlInfoNumericFunctions: {"ABS", "CBRT", "CEIL", "COS", "DEGREES", "EVEN", "EXP", "FACTORIAL", "FLOOR",
+			"GCD", "GREATEST", "LCM", "LEAST", "LN", "LOG", "LOG10", "LOG2", "PI", "POW", "POWER", "RADIANS", "RANDOM",
+			"ROUND", "SIGN", "SIN", "SQRT", "TAN", "TRUNC"},
+		flightsql.SqlInfoStringFunctions: {"CONCAT", "CONCAT_WS", "CONTAINS", "FORMAT", "LEFT", "LENGTH", "LOWER", "LPAD",
+			"LTRIM", "PRINTF", "REGEXP_MATCHES", "REGEXP_REPLACE", "REPEAT", "REPLACE", "REVERSE", "RIGHT", "RPAD",
+			"RTRIM", "SPLIT_PART", "STARTS_WITH", "STRING_SPLIT", "STRPOS", "SUBSTRING", "TRIM", "UPPER"},
+		flightsql.SqlInfoDateTimeFunctions: {"AGE", "CURRENT_DATE", "DATE_ADD", "DATE_DIFF", "DATE_PART", "DATE_TRUNC",
+			"DAYNAME", "EPOCH", "EXTRACT", "LAST_DAY", "MAKE_DATE", "MAKE_TIMESTAMP", "MONTHNAME", "NOW", "STRFTIME",
+			"STRPTIME", "TO_TIMESTAMP"},
+		flightsql.SqlInfoSystemFunctions: {"COALESCE", "CURRENT_DATABASE", "CURRENT_SCHEMA", "CURRENT_SETTING",
+			"IFNULL", "NULLIF", "TYPEOF", "VERSION"},
+	},
+	"": {
+		flightsql.SqlInfoNumericFunctions:  {"ABS", "CEILING", "FLOOR", "MOD", "POWER", "ROUND", "SQRT"},
+		flightsql.SqlInfoStringFunctions:   {"CHAR_LENGTH", "LOWER", "OVERLAY", "POSITION", "SUBSTRING", "TRIM", "UPPER"},
+		flightsql.SqlInfoDateTimeFunctions: {"CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP", "EXTRACT"},
+		flightsql.SqlInfoSystemFunctions:   {"COALESCE", "NULLIF"},
+	},
+}
+
+// SqlDialect holds SqlInfo values that replace the ones derived from the
+// backend. Empty fields keep the backend's values.
+type SqlDialect struct {
+	IdentifierQuoteChar string
+	CatalogTerm         string
+	SchemaTerm          string
+
+	NumericFunctions  []string
+	StringFunctions   []string
+	DateTimeFunctions []string
+	SystemFunctions   []string
+}
+
+func (d SqlDialect) validate() error {
+	if n := utf8.RuneCountInString(d.IdentifierQuoteChar); n > 1 {
+		return fmt.Errorf("identifier quote char must be a single character, got %q", d.IdentifierQuoteChar)
+	}
+	return nil
+}
+
+// register publishes the overridden values, replacing any registered
+// before
+func (d SqlDialect) register(s *DummyFlightSQLServer) error {
+	values := map[flightsql.SqlInfo]any{}
+	for info, value := range map[flightsql.SqlInfo]string{
+		flightsql.SqlInfoIdentifierQuoteChar: d.IdentifierQuoteChar,
+		flightsql.SqlInfoCatalogTerm:         d.CatalogTerm,
+		flightsql.SqlInfoSchemaTerm:          d.SchemaTerm,
+	} {
+		if value != "" {
+			values[info] = value
+		}
+	}
+	for info, names := range map[flightsql.SqlInfo][]string{
+		flightsql.SqlInfoNumericFunctions:  d.NumericFunctions,
+		flightsql.SqlInfoStringFunctions:   d.StringFunctions,
+		flightsql.SqlInfoDateTimeFunctions: d.DateTimeFunctions,
+		flightsql.SqlInfoSystemFunctions:   d.SystemFunctions,
+	} {
+		if len(names) > 0 {
+			values[info] = names
+		}
+	}
+
+	for info, value := range values {
+		if err := s.RegisterSqlInfo(info, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerSqlInfo publishes the backend's keywords and built-in functions
+// through SqlInfo for client autocomplete. Lists come from the backend where
+// it can report them, otherwise from the per-vendor defaults. The configured
+// Dialect takes precedence over both.
+func (s *DummyFlightSQLServer) registerSqlInfo(ctx context.Context) error {
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// An unknown vendor just gets the generic lists
+	vendor, _ := backendVendor(ctx, conn)
+
+	keywords := sqliteKeywords
+	if vendor == vendorDuckDB {
+		if native, err := queryStrings(ctx, conn, "SELECT upper(keyword_name) FROM duckdb_keywords() ORDER BY 1"); err == nil && len(native) > 0 {
+			keywords = native
+		}
+	}
+	if err := s.RegisterSqlInfo(flightsql.SqlInfoKeywords, keywords); err != nil {
+		return err
+	}
+
+	functions, ok := functionLists[vendor]
+	if !ok {
+		functions = functionLists[""]
+	}
+	for info, names := range functions {
+		if err := s.RegisterSqlInfo(info, names); err != nil {
+			return err
+		}
+	}
+	return s.cfg.Dialect.register(s)
+}
+
+// queryStrings returns the first column of query's result
+func queryStrings(ctx context.Context, conn adbc.Connection, query string) ([]string, error) {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return nil, err
+	}
+	reader, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	var values []string
+	for reader.Next() {
+		col, err := asStringColumn(reader.RecordBatch().Column(0))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < col.Len(); i++ {
+			if !col.IsNull(i) {
+				values = append(values, strings.TrimSpace(col.Value(i)))
+			}
+		}
+	}
+	return values, reader.Err()
+}
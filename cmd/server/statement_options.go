@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// statementOptionHeaderPrefix prefixes the request headers that carry ADBC
+// statement options, e.g. "x-statement-option-adbc.sqlite.query.batch_rows"
+const statementOptionHeaderPrefix = "x-statement-option-"
+
+// knownStatementOptions are the driver statement options clients may set
+// with the default configuration
+var knownStatementOptions = []string{
+	"adbc.sqlite.query.batch_rows",
+}
+
+// statementOptionsFromContext returns the statement options sent by the
+// client. Options outside AllowedStatementOptions are dropped, or rejected
+// when RejectUnknownStatementOptions is set.
+func (s *DummyFlightSQLServer) statementOptionsFromContext(ctx context.Context) (map[string]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var options map[string]string
+	for header, values := range md {
+		key, ok := strings.CutPrefix(header, statementOptionHeaderPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		allowed := false
+		for _, option := range s.cfg.AllowedStatementOptions {
+			if strings.EqualFold(option, key) {
+				key, allowed = option, true
+				break
+			}
+		}
+		if !allowed {
+			if s.cfg.RejectUnknownStatementOptions {
+				return nil, status.Errorf(codes.InvalidArgument, "statement option %q is not allowed", key)
+			}
+			continue
+		}
+
+		if options == nil {
+			options = make(map[string]string)
+		}
+		options[key] = values[0]
+	}
+	return options, nil
+}
+
+// applyStatementOptions sets the client's statement options on stmt
+func applyStatementOptions(stmt adbc.Statement, options map[string]string) error {
+	for key, value := range options {
+		if err := stmt.SetOption(key, value); err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to set statement option %q: %v", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statementHandle is a query registered by GetFlightInfoStatement and
+// waiting to be fetched with DoGetStatement
+type statementHandle struct {
+	handle   string
+	query    string
+	maxRows  int64 // 0 means no cap
+	pageSize int64 // 0 means the results are not paged
+	created  time.Time
+	use      *list.Element // position in queryLRU, for LRU eviction
+
+	// batching is what GetFlightInfoStatement was asked for
+	batching batching
+
+	// transactionID binds the statement to an open transaction
+	transactionID string
+
+	// schema is the result schema GetFlightInfoStatement advertised
+	schema *arrow.Schema
+
+	// substrait is the plan run instead of query, if any
+	substrait []byte
+}
+
+// storeStatement registers stmt under its handle. When MaxStatementHandles
+// is reached the least recently used handle is evicted to make room.
+func (s *DummyFlightSQLServer) storeStatement(stmt statementHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropStatement(stmt.handle)
+	if limit := s.cfg.MaxStatementHandles; limit > 0 {
+		for len(s.queries) >= limit && s.queryLRU.Len() > 0 {
+			s.dropStatement(s.queryLRU.Front().Value.(string))
+		}
+	}
+
+	stmt.created = time.Now()
+	stmt.use = s.queryLRU.PushBack(stmt.handle)
+	s.queries[stmt.handle] = stmt
+}
+
+// dropStatement removes handle from the registered statements. The caller
+// must hold s.mu.
+func (s *DummyFlightSQLServer) dropStatement(handle string) {
+	if stmt, ok := s.queries[handle]; ok && stmt.use != nil {
+		s.queryLRU.Remove(stmt.use)
+	}
+	delete(s.queries, handle)
+}
+
+// lookupStatement returns the statement registered under handle and marks
+// it as recently used
+func (s *DummyFlightSQLServer) lookupStatement(handle string) (statementHandle, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stmt, ok := s.queries[handle]
+	if ok && stmt.use != nil {
+		s.queryLRU.MoveToBack(stmt.use)
+	}
+	return stmt, ok
+}
+
+// consumeStatement drops a handle once its results were handed to the
+// client, so it can't be fetched a second time
+func (s *DummyFlightSQLServer) consumeStatement(handle string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropStatement(handle)
+}
+
+// runningStatement is a DoGetStatement stream that KillStatement can stop
+type runningStatement struct {
+	killed chan struct{}
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (r *runningStatement) kill() {
+	r.once.Do(func() {
+		close(r.killed)
+		r.cancel()
+	})
+}
+
+// trackRunning registers a stream for handle. The returned context is
+// canceled and killed closed when the handle is killed; untrack must be
+// called once the stream is done.
+func (s *DummyFlightSQLServer) trackRunning(ctx context.Context, handle string) (context.Context, <-chan struct{}, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &runningStatement{killed: make(chan struct{}), cancel: cancel}
+
+	s.mu.Lock()
+	if s.running[handle] == nil {
+		s.running[handle] = make(map[*runningStatement]struct{})
+	}
+	s.running[handle][r] = struct{}{}
+	s.reportRunning()
+	s.mu.Unlock()
+
+	untrack := func() {
+		s.mu.Lock()
+		delete(s.running[handle], r)
+		if len(s.running[handle]) == 0 {
+			delete(s.running, handle)
+		}
+		s.reportRunning()
+		s.mu.Unlock()
+		cancel()
+	}
+	return ctx, r.killed, untrack
+}
+
+// killStatement removes the statement handle in the action body and stops
+// every stream still reading its results. The streams fail with Canceled.
+func (s *DummyFlightSQLServer) killStatement(ctx context.Context, body []byte) ([][]byte, error) {
+	handle := strings.TrimSpace(string(body))
+	if handle == "" {
+		return nil, status.Error(codes.InvalidArgument, "expected the statement handle to kill in the action body")
+	}
+
+	stored, stopped := s.stopStatement(handle)
+	if !stored && stopped == 0 {
+		return nil, status.Errorf(codes.NotFound, "unknown statement handle: %s", handle)
+	}
+	return [][]byte{[]byte(fmt.Sprintf("statement %s killed, %d running stream(s) stopped", handle, stopped))}, nil
+}
+
+// stopStatement drops handle and kills the streams reading it. It reports
+// whether the handle was registered and how many streams were stopped.
+func (s *DummyFlightSQLServer) stopStatement(handle string) (stored bool, stopped int) {
+	s.mu.Lock()
+	_, stored = s.queries[handle]
+	s.dropStatement(handle)
+	running := s.running[handle]
+	delete(s.running, handle)
+	s.mu.Unlock()
+
+	for r := range running {
+		r.kill()
+	}
+	return stored, len(running)
+}
+
+// CancelFlightInfo stops the statements behind the FlightInfo's endpoints.
+// It reports CANCELLED when a stream was stopped or a handle dropped before
+// it was fetched, and NOT_CANCELLABLE for tickets of anything but
+// statements. Handles the server doesn't know, e.g. because their results
+// were delivered already, fail with NotFound.
+func (s *DummyFlightSQLServer) CancelFlightInfo(ctx context.Context, req *flight.CancelFlightInfoRequest) (flight.CancelFlightInfoResult, error) {
+	if req.GetInfo() == nil {
+		return flight.CancelFlightInfoResult{Status: flight.CancelStatusUnspecified},
+			status.Error(codes.InvalidArgument, "CancelFlightInfo requires the FlightInfo to cancel")
+	}
+
+	cancelStatus := flight.CancelStatusNotCancellable
+	var unknown []string
+	for _, endpoint := range req.GetInfo().GetEndpoint() {
+		ticket, err := flightsql.GetStatementQueryTicket(endpoint.GetTicket())
+		if err != nil {
+			continue
+		}
+		handle := string(ticket.GetStatementHandle())
+		// Stateless statements have no server-side state to stop
+		if isStatelessHandle([]byte(handle)) {
+			continue
+		}
+
+		if stored, stopped := s.stopStatement(handle); stored || stopped > 0 {
+			cancelStatus = flight.CancelStatusCancelled
+		} else {
+			unknown = append(unknown, handle)
+		}
+	}
+
+	if cancelStatus != flight.CancelStatusCancelled && len(unknown) > 0 {
+		return flight.CancelFlightInfoResult{Status: flight.CancelStatusUnspecified},
+			status.Errorf(codes.NotFound, "unknown statement handle: %s", strings.Join(unknown, ", "))
+	}
+	return flight.CancelFlightInfoResult{Status: cancelStatus}, nil
+}
+
+// activeStatements returns a snapshot of the registered statements, oldest first
+func (s *DummyFlightSQLServer) activeStatements() []statementHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stmts := make([]statementHandle, 0, len(s.queries))
+	for _, stmt := range s.queries {
+		stmts = append(stmts, stmt)
+	}
+
+	sort.Slice(stmts, func(i, j int) bool {
+		return stmts[i].created.Before(stmts[j].created)
+	})
+	return stmts
+}
+
+// activePrepared returns a snapshot of the prepared statements, oldest first
+func (s *DummyFlightSQLServer) activePrepared() []preparedStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prepared := make([]preparedStatement, 0, len(s.prepared))
+	for _, p := range s.prepared {
+		prepared = append(prepared, preparedStatement{handle: p.handle, query: p.query, created: p.created})
+	}
+
+	sort.Slice(prepared, func(i, j int) bool {
+		return prepared[i].created.Before(prepared[j].created)
+	})
+	return prepared
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// substraitQuery stands in for the query text of Substrait statements in
+// logs, audit records and statement listings
+const substraitQuery = "<substrait plan>"
+
+// substraitError turns the backend's failure to run a Substrait plan into
+// Unimplemented when the backend can't run plans at all, e.g. a DuckDB build
+// without the substrait extension. Other errors are returned as they are.
+func substraitError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if isNotImplemented(err) {
+		return status.Errorf(codes.Unimplemented, "the backend does not support Substrait plans: %v", err)
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "substrait") &&
+		(strings.Contains(msg, "does not exist") || strings.Contains(msg, "not found") || strings.Contains(msg, "extension")) {
+		return status.Errorf(codes.Unimplemented,
+			"the backend can't run Substrait plans, its substrait extension is missing; "+
+				"on DuckDB run INSTALL substrait and LOAD substrait, or use a build that bundles it (%v)", err)
+	}
+	return err
+}
+
+// checkSubstraitPlan rejects plans the server can't run as requested
+func (s *DummyFlightSQLServer) checkSubstraitPlan(cmd flightsql.StatementSubstraitPlan) error {
+	if len(cmd.GetPlan().Plan) == 0 {
+		return status.Error(codes.InvalidArgument, "the Substrait plan is empty")
+	}
+	if len(cmd.GetTransactionId()) > 0 {
+		return status.Error(codes.Unimplemented, "Substrait plans are not supported in transactions")
+	}
+	// Redaction goes by the tables a query names, which a plan doesn't
+	if len(s.cfg.RedactColumns) > 0 {
+		return status.Error(codes.FailedPrecondition, "Substrait plans are disabled while RedactColumns is set")
+	}
+	return nil
+}
+
+// GetFlightInfoSubstraitPlan registers the plan for fetching with
+// DoGetStatement. The plan only runs on DoGet, so the schema is left
+// unknown.
+func (s *DummyFlightSQLServer) GetFlightInfoSubstraitPlan(ctx context.Context, cmd flightsql.StatementSubstraitPlan, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if err := s.checkSubstraitPlan(cmd); err != nil {
+		return nil, err
+	}
+
+	handleBytes := make([]byte, 16)
+	if _, err := rand.Read(handleBytes); err != nil {
+		return nil, err
+	}
+	handle := hex.EncodeToString(handleBytes)
+	s.storeStatement(statementHandle{handle: handle, query: substraitQuery, substrait: cmd.GetPlan().Plan})
+
+	ticket, err := flightsql.CreateStatementQueryTicket([]byte(handle))
+	if err != nil {
+		return nil, err
+	}
+	return &flight.FlightInfo{
+		Endpoint: []*flight.FlightEndpoint{{
+			Ticket: &flight.Ticket{Ticket: ticket},
+		}},
+		FlightDescriptor: desc,
+	}, nil
+}
+
+// DoPutCommandSubstraitPlan runs the plan and returns the affected rows
+func (s *DummyFlightSQLServer) DoPutCommandSubstraitPlan(ctx context.Context, cmd flightsql.StatementSubstraitPlan) (int64, error) {
+	if err := s.checkSubstraitPlan(cmd); err != nil {
+		return 0, err
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSubstraitPlan(cmd.GetPlan().Plan); err != nil {
+		return 0, substraitError(err)
+	}
+	affected, err := stmt.ExecuteUpdate(ctx)
+	if s.cache != nil {
+		// The plan may have changed any cached result
+		s.cache.invalidate()
+	}
+	if err != nil {
+		return 0, substraitError(err)
+	}
+	return affected, nil
+}
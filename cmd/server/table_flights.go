@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// tableRef names a table served as a flight of its own
+type tableRef struct {
+	catalog, schema, table string
+}
+
+// tableRefFromPath resolves a PATH descriptor of the form [table],
+// [schema, table] or [catalog, schema, table]
+func tableRefFromPath(path []string) (tableRef, error) {
+	switch len(path) {
+	case 1:
+		return tableRef{table: path[0]}, nil
+	case 2:
+		return tableRef{schema: path[0], table: path[1]}, nil
+	case 3:
+		return tableRef{catalog: path[0], schema: path[1], table: path[2]}, nil
+	}
+	return tableRef{}, status.Errorf(codes.InvalidArgument, "table path must be [catalog, schema, table], [schema, table] or [table], got %d elements", len(path))
+}
+
+// path returns the descriptor path of the table, leaving out empty parts
+// before the table name
+func (t tableRef) path() []string {
+	var path []string
+	if t.catalog != "" {
+		path = append(path, t.catalog, t.schema)
+	} else if t.schema != "" {
+		path = append(path, t.schema)
+	}
+	return append(path, t.table)
+}
+
+// tableQuery selects every row of the table, which for a virtual table is
+// its defining query
+func (s *DummyFlightSQLServer) tableQuery(t tableRef) string {
+	if query, ok := s.virtualDefinition(t); ok {
+		return query
+	}
+	return "SELECT * FROM " + s.qualifiedName(t.catalog, s.backendSchemaName(t.schema), t.table)
+}
+
+// tableFlightInfo describes the full read of a table, with a ticket that
+// DoGet turns into SELECT * on the table
+func (s *DummyFlightSQLServer) tableFlightInfo(ctx context.Context, table tableRef) (*flight.FlightInfo, error) {
+	schema, err := s.tableSchema(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, err := encodeTableTicket(table)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight.FlightInfo{
+		Endpoint: []*flight.FlightEndpoint{{
+			Ticket: &flight.Ticket{Ticket: ticket},
+		}},
+		FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: table.path()},
+		Schema:           flight.SerializeSchema(schema, s.Alloc),
+		TotalRecords:     -1,
+		TotalBytes:       -1,
+	}, nil
+}
+
+// tableSchema returns the schema the rows of the table are streamed with,
+// which DoGet produces through the statement path
+func (s *DummyFlightSQLServer) tableSchema(ctx context.Context, table tableRef) (*arrow.Schema, error) {
+	query, err := s.rewriteQuery(ctx, s.tableQuery(table))
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.probeSchema(ctx, query)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "unknown table %s: %v", strings.Join(table.path(), "."), err)
+	}
+	return schema, nil
+}
+
+// listTables returns every table the metadata filter lets the client see,
+// virtual tables last
+func (s *DummyFlightSQLServer) listTables(ctx context.Context, conn adbc.Connection) ([]tableRef, error) {
+	reader, err := s.getObjects(ctx, conn, adbc.ObjectDepthTables, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	filter := s.metadataFilter()
+	var tables []tableRef
+	for reader.Next() {
+		rec := reader.RecordBatch()
+
+		schemasCol := rec.Column(1).(*array.List)
+		schemaValues := schemasCol.ListValues().(*array.Struct)
+		tablesCol := schemaValues.Field(1).(*array.List)
+		nameCols, err := asStringColumns(rec.Column(0), schemaValues.Field(0),
+			tablesCol.ListValues().(*array.Struct).Field(0))
+		if err != nil {
+			return nil, err
+		}
+		catalogNameCol, schemaNameCol, tableNameCol := nameCols[0], nameCols[1], nameCols[2]
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+			schemaStart, schemaEnd := schemasCol.ValueOffsets(i)
+			for j := schemaStart; j < schemaEnd; j++ {
+				tableStart, tableEnd := tablesCol.ValueOffsets(int(j))
+				for k := tableStart; k < tableEnd; k++ {
+					table := tableRef{
+						catalog: catalogNameCol.Value(i),
+						schema:  s.reportedSchemaName(schemaNameCol.Value(int(j))),
+						table:   tableNameCol.Value(int(k)),
+					}
+					if !filter.AllowCatalog(ctx, table.catalog) || !filter.AllowSchema(ctx, table.catalog, table.schema) ||
+						!filter.AllowTable(ctx, table.catalog, table.schema, table.table) {
+						continue
+					}
+					tables = append(tables, table)
+				}
+			}
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range s.virtualTables(ctx, nil, nil, nil, nil) {
+		tables = append(tables, tableRef{catalog: s.cfg.VirtualCatalog, schema: s.cfg.VirtualSchema, table: name})
+	}
+	return tables, nil
+}
+
+// GetFlightInfo serves PATH descriptors naming a table as a full read of
+// it, and passes commands on to Flight SQL
+func (f *flightService) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	if desc.GetType() != flight.DescriptorPATH {
+		return f.FlightServer.GetFlightInfo(ctx, desc)
+	}
+
+	table, err := tableRefFromPath(desc.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	return f.srv.tableFlightInfo(ctx, table)
+}
+
+// ListFlights returns a flight per table, for clients that copy whole
+// tables. The criteria are ignored.
+func (f *flightService) ListFlights(criteria *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	ctx := stream.Context()
+	conn, err := f.srv.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	// Probing the schemas takes connections of its own
+	tables, err := f.srv.listTables(ctx, conn)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		info, err := f.srv.tableFlightInfo(ctx, table)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DoGet streams every row of the table named by a table ticket. Statement
+// results are written here as well when their bodies are compressed; other
+// tickets are passed on to Flight SQL.
+func (f *flightService) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	compression, compress := f.srv.ipcCompression(stream.Context())
+	var opts []ipc.Option
+	if compress {
+		opts = append(opts, compression)
+	}
+
+	if !isTableTicket(ticket.GetTicket()) {
+		if compress {
+			if handled, err := f.doGetCompressed(ticket, stream, opts); handled {
+				return err
+			}
+		}
+		return f.FlightServer.DoGet(ticket, stream)
+	}
+
+	table, err := decodeTableTicket(ticket.GetTicket())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Run as a statement so that rewriting, limits, redaction and auditing
+	// apply as for any other query
+	schema, ch, err := f.srv.executeStatement(stream.Context(), f.srv.tableQuery(table))
+	if err != nil {
+		return err
+	}
+	return writeChunks(stream, schema, ch, opts...)
+}
+
+// doGetCompressed serves statement and prepared statement tickets with
+// compressed record batch bodies, which the Flight SQL router can't write.
+// It reports false for other tickets.
+func (f *flightService) doGetCompressed(ticket *flight.Ticket, stream flight.FlightService_DoGetServer, opts []ipc.Option) (bool, error) {
+	var anycmd anypb.Any
+	if err := proto.Unmarshal(ticket.GetTicket(), &anycmd); err != nil {
+		return false, nil
+	}
+	cmd, err := anycmd.UnmarshalNew()
+	if err != nil {
+		return false, nil
+	}
+
+	var (
+		schema *arrow.Schema
+		ch     <-chan flight.StreamChunk
+	)
+	switch cmd := cmd.(type) {
+	case *pb.TicketStatementQuery:
+		schema, ch, err = f.srv.DoGetStatement(stream.Context(), cmd)
+	case *pb.CommandPreparedStatementQuery:
+		schema, ch, err = f.srv.DoGetPreparedStatement(stream.Context(), cmd)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, writeChunks(stream, schema, ch, opts...)
+}
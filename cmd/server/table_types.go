@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// canonicalTableTypes maps backend specific table type strings to the
+// canonical values reported to Flight SQL clients
+var canonicalTableTypes = map[string]string{
+	"table":           "TABLE",
+	"TABLE":           "TABLE",
+	"BASE TABLE":      "TABLE",
+	"view":            "VIEW",
+	"VIEW":            "VIEW",
+	"SYSTEM TABLE":    "SYSTEM TABLE",
+	"system table":    "SYSTEM TABLE",
+	"LOCAL TEMPORARY": "LOCAL TEMPORARY",
+	"temporary table": "LOCAL TEMPORARY",
+}
+
+// normalizeTableType returns the canonical Flight SQL value for a backend
+// table type. Unknown types are upper-cased.
+func normalizeTableType(tableType string) string {
+	if canonical, ok := canonicalTableTypes[tableType]; ok {
+		return canonical
+	}
+	return strings.ToUpper(tableType)
+}
+
+// expandTableTypes turns a table type filter from a client into the set of
+// backend specific values that normalize to the requested types, so that
+// filtering by a canonical value matches every backend spelling of it.
+func expandTableTypes(tableTypes []string) []string {
+	if tableTypes == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var expanded []string
+	add := func(tableType string) {
+		if !seen[tableType] {
+			seen[tableType] = true
+			expanded = append(expanded, tableType)
+		}
+	}
+
+	for _, tableType := range tableTypes {
+		add(tableType)
+		canonical := normalizeTableType(tableType)
+		for raw, c := range canonicalTableTypes {
+			if c == canonical {
+				add(raw)
+			}
+		}
+	}
+
+	return expanded
+}
+
+// checkTableTypes rejects table type filters that the backend does not
+// report. A filter matches a backend type either verbatim or through its
+// canonical value, so both spellings pass whether or not normalization is on.
+func checkTableTypes(ctx context.Context, conn adbc.Connection, tableTypes []string) error {
+	if len(tableTypes) == 0 {
+		return nil
+	}
+
+	reader, err := conn.GetTableTypes(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	known := make(map[string]bool)
+	for reader.Next() {
+		col, err := asStringColumn(reader.RecordBatch().Column(0))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < col.Len(); i++ {
+			known[col.Value(i)] = true
+			known[normalizeTableType(col.Value(i))] = true
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, tableType := range tableTypes {
+		if !known[tableType] && !known[normalizeTableType(tableType)] {
+			unknown = append(unknown, tableType)
+		}
+	}
+	if len(unknown) > 0 {
+		return status.Errorf(codes.InvalidArgument, "unknown table types: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
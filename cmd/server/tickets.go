@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	pb "github.com/apache/arrow-go/v18/arrow/flight/gen/flight"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// statelessHandlePrefix marks statement handles that embed their query
+// instead of referring to server-side state
+var statelessHandlePrefix = []byte("sq1:")
+
+// isStatelessHandle reports whether the handle was produced by
+// encodeStatelessHandle
+func isStatelessHandle(handle []byte) bool {
+	return bytes.HasPrefix(handle, statelessHandlePrefix)
+}
+
+// encodeStatelessHandle builds a statement handle carrying the compressed
+// query, signed with key so that it can't be forged by clients. The layout
+// is prefix | HMAC-SHA256(payload) | deflate(query).
+func encodeStatelessHandle(key []byte, query string) ([]byte, error) {
+	var payload bytes.Buffer
+	w, err := flate.NewWriter(&payload, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, query); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload.Bytes())
+
+	handle := make([]byte, 0, len(statelessHandlePrefix)+sha256.Size+payload.Len())
+	handle = append(handle, statelessHandlePrefix...)
+	handle = mac.Sum(handle)
+	handle = append(handle, payload.Bytes()...)
+	return handle, nil
+}
+
+// decodeStatelessHandle verifies the signature of a stateless handle and
+// returns the query embedded in it
+func decodeStatelessHandle(key []byte, handle []byte) (string, error) {
+	if !isStatelessHandle(handle) || len(handle) < len(statelessHandlePrefix)+sha256.Size {
+		return "", fmt.Errorf("malformed stateless statement handle")
+	}
+
+	signed := handle[len(statelessHandlePrefix):]
+	signature, payload := signed[:sha256.Size], signed[sha256.Size:]
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", fmt.Errorf("invalid stateless statement handle signature")
+	}
+
+	query, err := io.ReadAll(flate.NewReader(bytes.NewReader(payload)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode stateless statement handle: %w", err)
+	}
+	return string(query), nil
+}
+
+// createCatalogsTicket returns a ticket carrying a CommandGetCatalogs marker,
+// independent of whatever the client put in the descriptor
+func createCatalogsTicket() ([]byte, error) {
+	cmd, err := anypb.New(&pb.CommandGetCatalogs{})
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(cmd)
+}
+
+// tableTicketPrefix marks tickets that name a table to read in full
+var tableTicketPrefix = []byte("tbl1:")
+
+// isTableTicket reports whether the ticket was produced by encodeTableTicket
+func isTableTicket(ticket []byte) bool {
+	return bytes.HasPrefix(ticket, tableTicketPrefix)
+}
+
+// encodeTableTicket builds a ticket carrying the catalog, schema and name of
+// a table as a JSON array
+func encodeTableTicket(table tableRef) ([]byte, error) {
+	path, err := json.Marshal([]string{table.catalog, table.schema, table.table})
+	if err != nil {
+		return nil, err
+	}
+	return append(slices.Clone(tableTicketPrefix), path...), nil
+}
+
+// decodeTableTicket returns the table named by a table ticket
+func decodeTableTicket(ticket []byte) (tableRef, error) {
+	var path []string
+	if !isTableTicket(ticket) || json.Unmarshal(ticket[len(tableTicketPrefix):], &path) != nil || len(path) != 3 {
+		return tableRef{}, fmt.Errorf("malformed table ticket")
+	}
+	return tableRef{catalog: path[0], schema: path[1], table: path[2]}, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// checkDefaultTimezone validates DefaultTimezone
+func checkDefaultTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown default timezone %q: %w", name, err)
+	}
+	return nil
+}
+
+// timestampZoner stamps DefaultTimezone on the timezone-naive timestamp
+// columns of a result. Naive values are wall clock times in that timezone,
+// so they are converted to the UTC instants zoned timestamps hold.
+type timestampZoner struct {
+	alloc   memory.Allocator
+	loc     *time.Location
+	schema  *arrow.Schema // result schema with the zoned columns
+	columns map[int]*arrow.TimestampType
+}
+
+// newTimestampZoner returns the zoner for results with the columns of
+// schema, or nil without DefaultTimezone or naive timestamp columns
+func (s *DummyFlightSQLServer) newTimestampZoner(schema *arrow.Schema) *timestampZoner {
+	if s.cfg.DefaultTimezone == "" {
+		return nil
+	}
+
+	columns := make(map[int]*arrow.TimestampType)
+	fields := schema.Fields()
+	for i, field := range fields {
+		if ts, ok := field.Type.(*arrow.TimestampType); ok && ts.TimeZone == "" {
+			zoned := &arrow.TimestampType{Unit: ts.Unit, TimeZone: s.cfg.DefaultTimezone}
+			columns[i] = zoned
+			fields[i].Type = zoned
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	// Validated with the configuration
+	loc, err := time.LoadLocation(s.cfg.DefaultTimezone)
+	if err != nil {
+		return nil
+	}
+	metadata := schema.Metadata()
+	return &timestampZoner{alloc: s.Alloc, loc: loc, schema: arrow.NewSchema(fields, &metadata), columns: columns}
+}
+
+// apply returns rec with the naive timestamp columns zoned, taking over the
+// caller's reference to rec
+func (z *timestampZoner) apply(rec arrow.RecordBatch) arrow.RecordBatch {
+	defer rec.Release()
+
+	cols := make([]arrow.Array, rec.NumCols())
+	for i, col := range rec.Columns() {
+		if zoned, ok := z.columns[i]; ok {
+			cols[i] = z.zone(col.(*array.Timestamp), zoned)
+			defer cols[i].Release()
+		} else {
+			cols[i] = col
+		}
+	}
+	return array.NewRecordBatch(z.schema, cols, rec.NumRows())
+}
+
+// zone converts the wall clock times of col to instants of the zoned type
+func (z *timestampZoner) zone(col *array.Timestamp, zoned *arrow.TimestampType) arrow.Array {
+	if z.loc == time.UTC {
+		// The values already are the UTC instants, only the type changes
+		data := array.NewData(zoned, col.Len(), col.Data().Buffers(), nil, col.NullN(), col.Data().Offset())
+		defer data.Release()
+		return array.MakeFromData(data)
+	}
+
+	bldr := array.NewTimestampBuilder(z.alloc, zoned)
+	defer bldr.Release()
+	bldr.Reserve(col.Len())
+
+	// The naive type reads values as UTC, which keeps their wall clock
+	toTime, err := col.DataType().(*arrow.TimestampType).GetToTimeFunc()
+	if err != nil {
+		panic(err) // every timestamp unit has one
+	}
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			bldr.AppendNull()
+			continue
+		}
+		wall := toTime(col.Value(i))
+		instant := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), z.loc)
+		value, err := arrow.TimestampFromTime(instant, zoned.Unit)
+		if err != nil {
+			// Out of range for the unit once shifted, keep the wall clock value
+			value = col.Value(i)
+		}
+		bldr.Append(value)
+	}
+	return bldr.NewArray()
+}
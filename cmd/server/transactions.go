@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transaction is a backend connection with autocommit disabled, kept open
+// between BeginTransaction and EndTransaction
+type transaction struct {
+	id      string
+	conn    adbc.Connection
+	created time.Time
+	owner   string // client that began it, see callerIdentity
+
+	mu     sync.Mutex
+	ended  bool
+	reads  map[int]context.CancelFunc
+	nextID int
+	wg     sync.WaitGroup // in-flight reads
+
+	savepoints []string // open savepoint ids, innermost last
+}
+
+// startRead registers a read on the transaction. The returned context is
+// canceled when the transaction ends, and done must be called once the read
+// no longer uses the connection.
+func (t *transaction) startRead(ctx context.Context) (readCtx context.Context, done func(), err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ended {
+		return nil, nil, status.Error(codes.Aborted, "transaction has ended")
+	}
+
+	readCtx, cancel := context.WithCancel(ctx)
+	id := t.nextID
+	t.nextID++
+	t.reads[id] = cancel
+	t.wg.Add(1)
+
+	var once sync.Once
+	done = func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.reads, id)
+			t.mu.Unlock()
+			cancel()
+			t.wg.Done()
+		})
+	}
+	return readCtx, done, nil
+}
+
+// end cancels the in-flight reads and waits until they released the
+// connection, so it can be committed or rolled back safely
+func (t *transaction) end() {
+	t.mu.Lock()
+	t.ended = true
+	for _, cancel := range t.reads {
+		cancel()
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+}
+
+func (s *DummyFlightSQLServer) BeginTransaction(ctx context.Context, req flightsql.ActionBeginTransactionRequest) ([]byte, error) {
+	// Turning autocommit off would leak into later users of a pooled
+	// connection, so transactions get their own
+	conn, err := s.newConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, ok := conn.(adbc.PostInitOptions)
+	if !ok {
+		conn.Close()
+		return nil, status.Error(codes.Unimplemented, "backend does not support transactions")
+	}
+	if err := opts.SetOption(adbc.OptionKeyAutoCommit, adbc.OptionValueDisabled); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	txn := &transaction{
+		id:      hex.EncodeToString(idBytes),
+		conn:    conn,
+		created: time.Now(),
+		owner:   callerIdentity(ctx),
+		reads:   make(map[int]context.CancelFunc),
+	}
+
+	s.mu.Lock()
+	s.transactions[txn.id] = txn
+	s.mu.Unlock()
+
+	return []byte(txn.id), nil
+}
+
+func (s *DummyFlightSQLServer) EndTransaction(ctx context.Context, req flightsql.ActionEndTransactionRequest) error {
+	id := string(req.GetTransactionId())
+
+	// Checked before the transaction is taken out of the registry, so a
+	// malformed request does not silently roll it back
+	action := req.GetAction()
+	if action != flightsql.EndTransactionCommit && action != flightsql.EndTransactionRollback {
+		return status.Errorf(codes.InvalidArgument, "unsupported end transaction action: %s", action)
+	}
+
+	s.mu.Lock()
+	txn, ok := s.transactions[id]
+	delete(s.transactions, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return status.Errorf(codes.NotFound, "unknown transaction: %s", id)
+	}
+	return s.finishTransaction(ctx, txn, action)
+}
+
+// finishTransaction commits or rolls back a transaction already taken out
+// of the registry and closes its connection
+func (s *DummyFlightSQLServer) finishTransaction(ctx context.Context, txn *transaction, action flightsql.EndTransactionRequestType) error {
+	defer txn.conn.Close()
+
+	// Reads still streaming on the connection would race with the commit
+	// or rollback
+	txn.end()
+
+	txn.mu.Lock()
+	savepoints := txn.savepoints
+	txn.savepoints = nil
+	txn.mu.Unlock()
+	s.forgetSavepoints(savepoints)
+
+	// The connection is closed either way, which discards whatever a failed
+	// commit left behind
+	if action == flightsql.EndTransactionCommit {
+		if err := txn.conn.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+	if err := txn.conn.Rollback(ctx); err != nil {
+		return fmt.Errorf("failed to roll back transaction: %w", err)
+	}
+	return nil
+}
+
+// closeTransactions rolls back every open transaction, returning how many
+// there were
+func (s *DummyFlightSQLServer) closeTransactions() int {
+	s.mu.Lock()
+	transactions := s.transactions
+	s.transactions = make(map[string]*transaction)
+	s.mu.Unlock()
+
+	for _, txn := range transactions {
+		if err := s.finishTransaction(context.Background(), txn, flightsql.EndTransactionRollback); err != nil {
+			s.log().Warn("failed to roll back transaction on close", "transaction", txn.id, "error", err)
+		}
+	}
+	return len(transactions)
+}
+
+// activeTransactions returns the open transactions, oldest first
+func (s *DummyFlightSQLServer) activeTransactions() []*transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transactions := make([]*transaction, 0, len(s.transactions))
+	for _, txn := range s.transactions {
+		transactions = append(transactions, txn)
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].created.Before(transactions[j].created)
+	})
+	return transactions
+}
+
+// lookupTransaction returns the open transaction with the given id
+func (s *DummyFlightSQLServer) lookupTransaction(id []byte) (*transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn, ok := s.transactions[string(id)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown transaction: %s", id)
+	}
+	return txn, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unknownRowCount is the update count reported when the backend can't tell
+// how many rows a statement changed
+const unknownRowCount = -1
+
+// isDMLQuery reports whether query changes rows, so that the backend's
+// affected row count describes it. SQLite keeps reporting the count of the
+// last INSERT, UPDATE or DELETE for any other statement.
+func isDMLQuery(query string) bool {
+	switch leadingKeyword(query) {
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT":
+		return true
+	}
+	return false
+}
+
+// hasNoResultSet reports whether query is known to return no rows: DDL,
+// transaction control, and DML without a RETURNING clause
+func hasNoResultSet(query string) bool {
+	switch leadingKeyword(query) {
+	case "CREATE", "DROP", "ALTER", "TRUNCATE", "ATTACH", "DETACH", "BEGIN", "COMMIT", "ROLLBACK",
+		"SAVEPOINT", "RELEASE", "VACUUM", "REINDEX":
+		return true
+	}
+	return isDMLQuery(query) && !strings.Contains(strings.ToUpper(query), "RETURNING")
+}
+
+// errNoResultSet steers a client that sent query through the query path to
+// the update path, which is the only one that runs statements without rows
+func errNoResultSet(query string) error {
+	return status.Errorf(codes.InvalidArgument,
+		"%s statement has no result set; execute it as an update (CommandStatementUpdate) instead of a query",
+		leadingKeyword(query))
+}
+
+// DoPutCommandStatementUpdate executes a statement without a result set.
+// The count is sent back in a DoPutUpdateResult, with -1 when the backend
+// can't report it, e.g. for DDL.
+func (s *DummyFlightSQLServer) DoPutCommandStatementUpdate(ctx context.Context, cmd flightsql.StatementUpdate) (int64, error) {
+	start := time.Now()
+	query, err := s.rewriteQuery(ctx, cmd.GetQuery())
+	if err != nil {
+		s.audit(ctx, "DoPutCommandStatementUpdate", cmd.GetQuery(), start, 0, err)
+		return 0, err
+	}
+
+	affected, err := s.executeUpdate(ctx, query, cmd.GetTransactionId())
+	err = backendError(err)
+	s.audit(ctx, "DoPutCommandStatementUpdate", query, start, affected, err)
+	return affected, err
+}
+
+// executeUpdate runs query on the transaction's connection, or on a fresh
+// one without a transaction id, and returns the affected row count
+func (s *DummyFlightSQLServer) executeUpdate(ctx context.Context, query string, transactionID []byte) (int64, error) {
+	s.logFor(ctx).Debug("executing update", "query", query)
+
+	var conn adbc.Connection
+	if len(transactionID) > 0 {
+		txn, err := s.lookupTransaction(transactionID)
+		if err != nil {
+			return 0, err
+		}
+		var done func()
+		if ctx, done, err = txn.startRead(ctx); err != nil {
+			return 0, err
+		}
+		defer done()
+		conn = txn.conn
+	} else {
+		var err error
+		if conn, err = s.openConnection(ctx); err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+	}
+
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return 0, err
+	}
+	var affected int64
+	execute := func() (err error) {
+		affected, err = stmt.ExecuteUpdate(ctx)
+		return err
+	}
+	// A failed statement may have aborted the transaction, so only
+	// autocommitted statements are retried
+	if len(transactionID) == 0 {
+		err = s.retryTransient(ctx, "DoPutCommandStatementUpdate", execute)
+	} else {
+		err = execute()
+	}
+	if s.cache != nil {
+		// The statement may have changed any cached result
+		s.cache.invalidate()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if affected < 0 || !isDMLQuery(query) {
+		return unknownRowCount, nil
+	}
+	return affected, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validateQuerySchema is the result schema of the ValidateQuery action. The
+// result schema is set for valid queries, the diagnostic columns for invalid
+// ones, as far as the backend reports them.
+var validateQuerySchema = arrow.NewSchema([]arrow.Field{
+	{Name: "valid", Type: arrow.FixedWidthTypes.Boolean},
+	{Name: "schema", Type: arrow.BinaryTypes.Binary, Nullable: true},
+	{Name: "error_message", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "sql_state", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "vendor_code", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+}, nil)
+
+// validateQuery checks that the query in the body parses and its columns
+// resolve by preparing it, and returns its result schema. An invalid query
+// is reported in the result rather than failing the action, so clients can
+// tell it apart from the server being unable to check.
+func (s *DummyFlightSQLServer) validateQuery(ctx context.Context, body []byte) ([][]byte, error) {
+	query := strings.TrimSpace(string(body))
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "expected the query to validate in the action body")
+	}
+	query, err := s.rewriteQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.openConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	schema, invalid := s.preparedSchema(ctx, conn, query)
+
+	bldr := array.NewRecordBuilder(s.Alloc, validateQuerySchema)
+	defer bldr.Release()
+
+	if invalid == nil {
+		serialized, err := s.serializeSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		bldr.Field(0).(*array.BooleanBuilder).Append(true)
+		bldr.Field(1).(*array.BinaryBuilder).Append(serialized)
+		bldr.Field(2).AppendNull()
+		bldr.Field(3).AppendNull()
+		bldr.Field(4).AppendNull()
+	} else {
+		bldr.Field(0).(*array.BooleanBuilder).Append(false)
+		bldr.Field(1).AppendNull()
+		adbcErr, ok := asADBCError(invalid)
+		if !ok {
+			bldr.Field(2).(*array.StringBuilder).Append(invalid.Error())
+			bldr.Field(3).AppendNull()
+			bldr.Field(4).AppendNull()
+		} else {
+			bldr.Field(2).(*array.StringBuilder).Append(adbcErr.Msg)
+			if sqlState := backendSQLState(adbcErr); sqlState != "" {
+				bldr.Field(3).(*array.StringBuilder).Append(sqlState)
+			} else {
+				bldr.Field(3).AppendNull()
+			}
+			if adbcErr.VendorCode != 0 {
+				bldr.Field(4).(*array.Int32Builder).Append(adbcErr.VendorCode)
+			} else {
+				bldr.Field(4).AppendNull()
+			}
+		}
+	}
+
+	rec := bldr.NewRecordBatch()
+	defer rec.Release()
+
+	result, err := serializeRecord(rec)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{result}, nil
+}
+
+// preparedSchema prepares query and returns its result schema without
+// running it, or the backend's error when the query is invalid. Drivers
+// that can't report the schema of a prepared statement are asked for the
+// result of the query filtered to no rows, statements without rows have an
+// empty schema.
+func (s *DummyFlightSQLServer) preparedSchema(ctx context.Context, conn adbc.Connection, query string) (*arrow.Schema, error) {
+	stmt, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := stmt.SetSqlQuery(query); err != nil {
+		return nil, err
+	}
+	if err := stmt.Prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	if executeSchema, ok := stmt.(adbc.StatementExecuteSchema); ok {
+		schema, err := executeSchema.ExecuteSchema(ctx)
+		if err == nil {
+			return schema, nil
+		}
+		if !isNotImplemented(err) {
+			return nil, err
+		}
+	}
+
+	if !isReadQuery(query) {
+		return arrow.NewSchema(nil, nil), nil
+	}
+	probe, err := conn.NewStatement()
+	if err != nil {
+		return nil, err
+	}
+	defer probe.Close()
+
+	if err := probe.SetSqlQuery(schemaQuery(query)); err != nil {
+		return nil, err
+	}
+	reader, _, err := probe.ExecuteQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+	return reader.Schema(), nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/flight/flightsql"
+)
+
+// virtualTableType is the table type virtual tables are listed with, as
+// they behave like views
+const virtualTableType = "VIEW"
+
+// checkVirtualTables validates the virtual table settings
+func checkVirtualTables(schema string, tables map[string]string) error {
+	if len(tables) > 0 && schema == "" {
+		return fmt.Errorf("virtual tables require a virtual schema name")
+	}
+	for name, query := range tables {
+		if name == "" || strings.TrimSpace(query) == "" {
+			return fmt.Errorf("virtual table %q needs a name and a defining query", name)
+		}
+	}
+	return nil
+}
+
+// virtualDefinition returns the query defining the table when it names a
+// virtual table. The catalog may be left out.
+func (s *DummyFlightSQLServer) virtualDefinition(t tableRef) (string, bool) {
+	if t.schema != s.cfg.VirtualSchema || (t.catalog != "" && t.catalog != s.cfg.VirtualCatalog) {
+		return "", false
+	}
+	query, ok := s.cfg.VirtualTables[t.table]
+	return query, ok
+}
+
+// virtualSchemaMatches reports whether the virtual schema passes a client's
+// catalog and schema filters, and the metadata filter
+func (s *DummyFlightSQLServer) virtualSchemaMatches(ctx context.Context, catalog, schemaPattern *string) bool {
+	if len(s.cfg.VirtualTables) == 0 {
+		return false
+	}
+	if catalog != nil && *catalog != s.cfg.VirtualCatalog {
+		return false
+	}
+	if schemaPattern != nil && !compileLikePattern(*schemaPattern).Match(s.cfg.VirtualSchema) {
+		return false
+	}
+	filter := s.metadataFilter()
+	return filter.AllowCatalog(ctx, s.cfg.VirtualCatalog) && filter.AllowSchema(ctx, s.cfg.VirtualCatalog, s.cfg.VirtualSchema)
+}
+
+// virtualTables returns the names of the virtual tables passing a client's
+// filters, sorted
+func (s *DummyFlightSQLServer) virtualTables(ctx context.Context, catalog, schemaPattern, tablePattern *string, tableTypes []string) []string {
+	if !s.virtualSchemaMatches(ctx, catalog, schemaPattern) {
+		return nil
+	}
+	if len(tableTypes) > 0 && !slices.ContainsFunc(tableTypes, func(t string) bool { return strings.EqualFold(t, virtualTableType) }) {
+		return nil
+	}
+
+	var matcher *likePattern
+	if tablePattern != nil {
+		matcher = compileLikePattern(*tablePattern)
+	}
+	filter := s.metadataFilter()
+
+	var names []string
+	for name := range s.cfg.VirtualTables {
+		if matcher != nil && !matcher.Match(name) {
+			continue
+		}
+		if !filter.AllowTable(ctx, s.cfg.VirtualCatalog, s.cfg.VirtualSchema, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// appendVirtualCatalog appends the virtual catalog name, which is null when
+// none is configured
+func (s *DummyFlightSQLServer) appendVirtualCatalog(b *array.StringBuilder) {
+	if s.cfg.VirtualCatalog == "" {
+		b.AppendNull()
+	} else {
+		b.Append(s.cfg.VirtualCatalog)
+	}
+}
+
+// virtualSchemasBatch returns the DoGetDBSchemas row of the virtual schema,
+// or nil when the filters exclude it
+func (s *DummyFlightSQLServer) virtualSchemasBatch(ctx context.Context, cmd flightsql.GetDBSchemas, schema *arrow.Schema) arrow.RecordBatch {
+	if !s.virtualSchemaMatches(ctx, cmd.GetCatalog(), cmd.GetDBSchemaFilterPattern()) {
+		return nil
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, schema)
+	defer bldr.Release()
+
+	s.appendVirtualCatalog(bldr.Field(0).(*array.StringBuilder))
+	bldr.Field(1).(*array.StringBuilder).Append(s.cfg.VirtualSchema)
+	return bldr.NewRecordBatch()
+}
+
+// virtualTablesBatch returns the DoGetTables rows of the virtual tables, or
+// nil when the filters exclude them all. Their schemas are probed from the
+// defining queries.
+func (s *DummyFlightSQLServer) virtualTablesBatch(ctx context.Context, cmd flightsql.GetTables, schema *arrow.Schema) (arrow.RecordBatch, error) {
+	names := s.virtualTables(ctx, cmd.GetCatalog(), cmd.GetDBSchemaFilterPattern(), cmd.GetTableNameFilterPattern(), cmd.GetTableTypes())
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	bldr := array.NewRecordBuilder(s.Alloc, schema)
+	defer bldr.Release()
+
+	for _, name := range names {
+		s.appendVirtualCatalog(bldr.Field(0).(*array.StringBuilder))
+		bldr.Field(1).(*array.StringBuilder).Append(s.cfg.VirtualSchema)
+		bldr.Field(2).(*array.StringBuilder).Append(name)
+		bldr.Field(3).(*array.StringBuilder).Append(virtualTableType)
+
+		if cmd.GetIncludeSchema() {
+			tableSchema, err := s.probeSchema(ctx, s.cfg.VirtualTables[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe virtual table %s: %w", name, err)
+			}
+			bldr.Field(4).(*array.BinaryBuilder).Append(flight.SerializeSchema(tableSchema, s.Alloc))
+		}
+	}
+	return bldr.NewRecordBatch(), nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "context"
+
+// MetadataFilter decides which catalogs, schemas and tables a client may see
+// in metadata listings. The context carries the incoming request metadata
+// and, with mutual TLS, the client certificate (see clientIdentity). A schema
+// is only listed if its catalog is allowed, and a table only if its schema is.
+type MetadataFilter interface {
+	AllowCatalog(ctx context.Context, catalog string) bool
+	AllowSchema(ctx context.Context, catalog, schema string) bool
+	AllowTable(ctx context.Context, catalog, schema, table string) bool
+}
+
+// allowAllFilter lists every object
+type allowAllFilter struct{}
+
+func (allowAllFilter) AllowCatalog(context.Context, string) bool               { return true }
+func (allowAllFilter) AllowSchema(context.Context, string, string) bool        { return true }
+func (allowAllFilter) AllowTable(context.Context, string, string, string) bool { return true }
+
+// metadataFilter returns the configured filter, allowing everything by default
+func (s *DummyFlightSQLServer) metadataFilter() MetadataFilter {
+	if s.cfg.MetadataFilter == nil {
+		return allowAllFilter{}
+	}
+	return s.cfg.MetadataFilter
+}
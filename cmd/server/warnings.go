@@ -0,0 +1,18 @@
+package main
+
+import "github.com/apache/arrow-adbc/go/adbc"
+
+// warningReporter is implemented by backend statements that can report
+// non-fatal warnings raised while executing. ADBC has no standard way to
+// surface warnings, so drivers or wrappers opt in through this interface.
+type warningReporter interface {
+	Warnings() []string
+}
+
+// statementWarnings returns the warnings reported by stmt, if it reports any
+func statementWarnings(stmt adbc.Statement) []string {
+	if reporter, ok := stmt.(warningReporter); ok {
+		return reporter.Warnings()
+	}
+	return nil
+}